@@ -2,7 +2,13 @@ package main
 
 import (
 	"bbs/internal/infra"
+	"bbs/internal/middleware"
+	"bbs/internal/repository"
 	"bbs/internal/route"
+	"bbs/internal/service"
+	"bbs/internal/worker"
+	"context"
+	"log"
 	"os"
 
 	"github.com/gin-gonic/gin"
@@ -11,14 +17,45 @@ import (
 func main() {
 	infra.Init()
 	db := infra.SetUpDB()
+	st := infra.SetUpStorage()
+
+	// 署名鍵のキャッシュを共有するため、TokenServiceは1つだけ作成して各ルートとワーカーに渡す
+	tokenService := service.NewTokenService(repository.NewSigningKeyRepository(db))
+
+	// 他のサービスも同様に1つだけ作成して共有する
+	jobService := service.NewJobService(repository.NewJobRepository(db))
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(db), jobService)
+	attachmentService := service.NewAttachmentService(repository.NewAttachmentRepository(db), st)
+	contentCheckService := service.NewContentCheckService(repository.NewPostRepository(db))
+	unitOfWork := repository.NewUnitOfWork(db)
+	threadService := service.NewThreadService(repository.NewThreadRepository(db), repository.NewRevisionRepository(db), repository.NewSubscriptionRepository(db), repository.NewBookmarkRepository(db), repository.NewPollRepository(db), attachmentService, contentCheckService, webhookService, unitOfWork)
+	commentService := service.NewCommentService(repository.NewCommentRepository(db), repository.NewThreadRepository(db), repository.NewRevisionRepository(db), repository.NewSubscriptionRepository(db), repository.NewBookmarkRepository(db), repository.NewAuthRepository(db), attachmentService, contentCheckService, webhookService, unitOfWork)
+
+	// WORKER_EMBEDDEDがfalseの場合はcmd/workerを別に起動する
+	if os.Getenv("WORKER_EMBEDDED") != "false" {
+		w := worker.New(repository.NewJobRepository(db), 0)
+		if err := worker.SetUp(context.Background(), w, db, threadService, tokenService); err != nil {
+			log.Fatalln("failed to set up worker:", err)
+		}
+		go w.Run(context.Background())
+	}
 
 	r := gin.Default()
 
 	route.SetCorsHeader(r)
+	r.Use(middleware.QueryTimeoutMiddleware(middleware.QueryTimeout()))
 
-	route.SetThreadRoute(r, db)
-	route.SetAuthRoute(r, db)
-	route.SetCommentRoute(r, db)
+	route.SetThreadRoute(r, db, threadService, tokenService)
+	route.SetAuthRoute(r, db, tokenService)
+	route.SetCommentRoute(r, db, commentService, tokenService)
+	route.SetAttachmentRoute(r, db, attachmentService, tokenService)
+	route.SetReportRoute(r, db, threadService, commentService, tokenService)
+	route.SetSanctionRoute(r, db, tokenService)
+	route.SetSubscriptionRoute(r, db, threadService, tokenService)
+	route.SetBookmarkRoute(r, db, threadService, commentService, tokenService)
+	route.SetPollRoute(r, db, threadService, tokenService)
+	route.SetWebhookRoute(r, db, webhookService, tokenService)
+	route.SetJobRoute(r, db, jobService, tokenService)
 
 	allowHost := os.Getenv("ALLOW_HOST")
 	port := os.Getenv("PORT")
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bbs/internal/infra"
+	"bbs/internal/repository"
+	"bbs/internal/service"
+	"bbs/internal/worker"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// APIサーバーとは別にジョブだけを実行する。APIサーバーはWORKER_EMBEDDED=falseで起動する
+func main() {
+	infra.Init()
+	db := infra.SetUpDB()
+	st := infra.SetUpStorage()
+
+	jobService := service.NewJobService(repository.NewJobRepository(db))
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(db), jobService)
+	attachmentService := service.NewAttachmentService(repository.NewAttachmentRepository(db), st)
+	contentCheckService := service.NewContentCheckService(repository.NewPostRepository(db))
+	threadService := service.NewThreadService(repository.NewThreadRepository(db), repository.NewRevisionRepository(db), repository.NewSubscriptionRepository(db), repository.NewBookmarkRepository(db), repository.NewPollRepository(db), attachmentService, contentCheckService, webhookService, repository.NewUnitOfWork(db))
+	tokenService := service.NewTokenService(repository.NewSigningKeyRepository(db))
+
+	w := worker.New(repository.NewJobRepository(db), 0)
+	if err := worker.SetUp(context.Background(), w, db, threadService, tokenService); err != nil {
+		log.Fatalln("failed to set up worker:", err)
+	}
+
+	// 停止のシグナルを受け取った場合は実行中のジョブが終わるのを待って終了する
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Println("worker started")
+	w.Run(ctx)
+	log.Println("worker stopped")
+}
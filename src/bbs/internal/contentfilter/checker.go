@@ -0,0 +1,114 @@
+package contentfilter
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	reasonBannedWord    = "banned word"
+	reasonTooManyLinks  = "too many links"
+	reasonDuplicatePost = "duplicate post"
+)
+
+// 禁止語を含む投稿を扱う。語は正規化して比較するため、全角・半角やひらがな・カタカナの違いでは回避できない
+type WordChecker struct {
+	words  [][]rune
+	action string
+}
+
+func NewWordChecker(words []string, action string) *WordChecker {
+	checker := &WordChecker{action: action}
+	for _, word := range words {
+		normalizedWord := []rune(normalizeString(strings.TrimSpace(word)))
+		if len(normalizedWord) > 0 {
+			checker.words = append(checker.words, normalizedWord)
+		}
+	}
+	return checker
+}
+
+func (c *WordChecker) Check(ctx context.Context, post *Post) error {
+	title, titleMatched := c.mask(post.Title)
+	body, bodyMatched := c.mask(post.Body)
+	if !titleMatched && !bodyMatched {
+		return nil
+	}
+
+	if c.action == ActionMask {
+		post.Title = title
+		post.Body = body
+		return nil
+	}
+	return apply(post, c.action, reasonBannedWord)
+}
+
+// 禁止語の部分を伏せ字にした文字列と、禁止語を含んでいたかを返す
+func (c *WordChecker) mask(text string) (string, bool) {
+	normalizedText := normalize(text)
+	runes := []rune(text)
+	matched := false
+
+	for _, word := range c.words {
+		for _, span := range normalizedText.findAll(word) {
+			matched = true
+			for i := span[0]; i <= span[1]; i++ {
+				runes[i] = '*'
+			}
+		}
+	}
+	return string(runes), matched
+}
+
+var linkPattern = regexp.MustCompile(`https?://|www\.`)
+
+// 本文中のリンクの数を制限する
+type LinkChecker struct {
+	max    int
+	action string
+}
+
+func NewLinkChecker(max int, action string) *LinkChecker {
+	return &LinkChecker{max: max, action: action}
+}
+
+func (c *LinkChecker) Check(ctx context.Context, post *Post) error {
+	// 全角で書かれたURLも自動リンクされうるため正規化してから数える
+	count := len(linkPattern.FindAllString(normalizeString(post.Body), -1))
+	if count <= c.max {
+		return nil
+	}
+	return apply(post, c.action, reasonTooManyLinks)
+}
+
+type DuplicateCounter interface {
+	CountRecentByUser(ctx context.Context, userId uint, body string, since time.Time) (int64, error)
+}
+
+// 同じユーザーが一定時間内に同じ本文を投稿するのを防ぐ
+type DuplicateChecker struct {
+	counter DuplicateCounter
+	window  time.Duration
+	action  string
+}
+
+func NewDuplicateChecker(counter DuplicateCounter, window time.Duration, action string) *DuplicateChecker {
+	return &DuplicateChecker{counter: counter, window: window, action: action}
+}
+
+func (c *DuplicateChecker) Check(ctx context.Context, post *Post) error {
+	// 編集では投稿自身が同じ本文を持つため確認しない
+	if post.Edit {
+		return nil
+	}
+	count, err := c.counter.CountRecentByUser(ctx, post.UserID, post.Body, time.Now().Add(-c.window))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	return apply(post, c.action, reasonDuplicatePost)
+}
@@ -0,0 +1,141 @@
+package contentfilter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type WordRule struct {
+	Words  []string `json:"words"`
+	Action string   `json:"action"`
+}
+
+type LinkRule struct {
+	Max    int    `json:"max"`
+	Action string `json:"action"`
+}
+
+type DuplicateRule struct {
+	WindowSeconds int    `json:"windowSeconds"`
+	Action        string `json:"action"`
+}
+
+// 設定ファイルの内容。省略したルールは適用しない
+type Config struct {
+	BannedWords []WordRule     `json:"bannedWords"`
+	Links       *LinkRule      `json:"links"`
+	Duplicate   *DuplicateRule `json:"duplicate"`
+}
+
+func ParseConfig(data []byte) (*Config, error) {
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (c *Config) validate() error {
+	for _, rule := range c.BannedWords {
+		if rule.Action != ActionReject && rule.Action != ActionMask && rule.Action != ActionFlag {
+			return fmt.Errorf("invalid banned word action: %q", rule.Action)
+		}
+	}
+	// 伏せ字にできるのは禁止語のみ
+	if c.Links != nil {
+		if c.Links.Action != ActionReject && c.Links.Action != ActionFlag {
+			return fmt.Errorf("invalid link action: %q", c.Links.Action)
+		}
+		if c.Links.Max < 0 {
+			return errors.New("link max must not be negative")
+		}
+	}
+	if c.Duplicate != nil {
+		if c.Duplicate.Action != ActionReject && c.Duplicate.Action != ActionFlag {
+			return fmt.Errorf("invalid duplicate action: %q", c.Duplicate.Action)
+		}
+		if c.Duplicate.WindowSeconds <= 0 {
+			return errors.New("duplicate window must be positive")
+		}
+	}
+	return nil
+}
+
+func (c *Config) Pipeline(counter DuplicateCounter) *Pipeline {
+	var checkers []Checker
+	for _, rule := range c.BannedWords {
+		checkers = append(checkers, NewWordChecker(rule.Words, rule.Action))
+	}
+	if c.Links != nil {
+		checkers = append(checkers, NewLinkChecker(c.Links.Max, c.Links.Action))
+	}
+	if c.Duplicate != nil {
+		checkers = append(checkers, NewDuplicateChecker(counter, time.Duration(c.Duplicate.WindowSeconds)*time.Second, c.Duplicate.Action))
+	}
+	return NewPipeline(checkers...)
+}
+
+// 設定ファイルが更新されていればルールを読み込み直す
+type Loader struct {
+	counter  DuplicateCounter
+	mu       sync.Mutex
+	path     string
+	modTime  time.Time
+	pipeline *Pipeline
+}
+
+func NewLoader(counter DuplicateCounter) *Loader {
+	return &Loader{counter: counter}
+}
+
+// ファイルが存在しない場合はルールなしとして扱う。
+// 読み込みに失敗した場合は、以前に読み込んだルールがあればそれを使い続ける
+func (l *Loader) Pipeline(path string) (*Pipeline, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		l.path = path
+		l.modTime = time.Time{}
+		l.pipeline = NewPipeline()
+		return l.pipeline, nil
+	}
+	if err != nil {
+		return l.fallback(path, err)
+	}
+
+	if l.pipeline != nil && l.path == path && l.modTime.Equal(info.ModTime()) {
+		return l.pipeline, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return l.fallback(path, err)
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return l.fallback(path, err)
+	}
+
+	l.path = path
+	l.modTime = info.ModTime()
+	l.pipeline = config.Pipeline(l.counter)
+	return l.pipeline, nil
+}
+
+func (l *Loader) fallback(path string, err error) (*Pipeline, error) {
+	if l.pipeline == nil || l.path != path {
+		return nil, err
+	}
+	log.Printf("failed to reload content filter config %s: %v", path, err)
+	return l.pipeline, nil
+}
@@ -0,0 +1,62 @@
+package contentfilter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ルールに該当したときの扱い
+const (
+	ActionReject = "reject"
+	ActionMask   = "mask"
+	ActionFlag   = "flag"
+)
+
+var ErrRejected = errors.New("content is rejected")
+
+// チェック対象の投稿。Checkerは伏せ字にする場合はTitleとBodyを書き換え、要確認にする場合はFlagsに理由を追加する。
+// Editは既存の投稿を編集する場合にtrue
+type Post struct {
+	UserID uint
+	Title  string
+	Body   string
+	Edit   bool
+	Flags  []string
+}
+
+func (p *Post) Flagged() bool {
+	return len(p.Flags) > 0
+}
+
+type Checker interface {
+	Check(ctx context.Context, post *Post) error
+}
+
+type Pipeline struct {
+	checkers []Checker
+}
+
+func NewPipeline(checkers ...Checker) *Pipeline {
+	return &Pipeline{checkers: checkers}
+}
+
+func (p *Pipeline) Check(ctx context.Context, post *Post) error {
+	for _, checker := range p.checkers {
+		if err := checker.Check(ctx, post); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ActionMaskはルールごとに伏せ字の処理が必要なため、呼び出し側で扱う
+func apply(post *Post, action string, reason string) error {
+	switch action {
+	case ActionReject:
+		return fmt.Errorf("%w: %s", ErrRejected, reason)
+	case ActionFlag:
+		post.Flags = append(post.Flags, reason)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package contentfilter
+
+import (
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 正規化後の文字と、その文字が元の文字列の何文字目から何文字目までに対応するか
+type normalized struct {
+	runes  []rune
+	starts []int
+	ends   []int
+}
+
+// 全角・半角の英数字、半角カナ、ひらがな・カタカナ、大文字・小文字の違いを吸収する
+func normalize(text string) normalized {
+	src := []rune(text)
+	result := normalized{}
+
+	for i := 0; i < len(src); i++ {
+		// 半角カナの濁点・半濁点は直前の文字と合わせて変換しないと1文字にまとまらない
+		start := i
+		for i+1 < len(src) && isHalfwidthSoundMark(src[i+1]) {
+			i++
+		}
+
+		for _, r := range norm.NFKC.String(string(src[start : i+1])) {
+			result.runes = append(result.runes, foldRune(r))
+			result.starts = append(result.starts, start)
+			result.ends = append(result.ends, i)
+		}
+	}
+
+	return result
+}
+
+func normalizeString(text string) string {
+	return string(normalize(text).runes)
+}
+
+func isHalfwidthSoundMark(r rune) bool {
+	return r == 'ﾞ' || r == 'ﾟ'
+}
+
+func foldRune(r rune) rune {
+	// ひらがなはカタカナにそろえる
+	if r >= 'ぁ' && r <= 'ゖ' {
+		return r + ('ァ' - 'ぁ')
+	}
+	return unicode.ToLower(r)
+}
+
+// 正規化済みの語が現れる箇所を、元の文字列での開始位置と終了位置(どちらも含む)で返す
+func (n normalized) findAll(word []rune) [][2]int {
+	if len(word) == 0 {
+		return nil
+	}
+
+	var spans [][2]int
+	for start := 0; start+len(word) <= len(n.runes); start++ {
+		if !equalRunes(n.runes[start:start+len(word)], word) {
+			continue
+		}
+		end := start + len(word) - 1
+		spans = append(spans, [2]int{n.starts[start], n.ends[end]})
+	}
+	return spans
+}
+
+func equalRunes(a []rune, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
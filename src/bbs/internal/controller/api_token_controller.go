@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APITokenController struct {
+	service service.IAPITokenService
+}
+
+func NewAPITokenController(service service.IAPITokenService) IAPITokenController {
+	return &APITokenController{service: service}
+}
+
+func (c *APITokenController) Create(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	var input dto.CreateAPITokenInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	output, err := c.service.Create(ctx.Request.Context(), input, userId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": output})
+}
+
+func (c *APITokenController) FindMine(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	tokens, err := c.service.FindByUserId(ctx.Request.Context(), userId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": tokens})
+}
+
+func (c *APITokenController) Revoke(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	tokenId, err := strconv.ParseUint(ctx.Param("tokenId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token id"})
+		return
+	}
+
+	err = c.service.Revoke(ctx.Request.Context(), uint(tokenId), userId)
+	if err != nil {
+		if err.Error() == "api token not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
@@ -0,0 +1,172 @@
+package controller_test
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type CreateAPITokenResponse struct {
+	Data struct {
+		model.APIToken
+		Token string `json:"token"`
+	} `json:"data"`
+}
+
+type APITokenListResponse struct {
+	Data []model.APIToken `json:"data"`
+}
+
+var _ = Describe("APITokenController", func() {
+	BeforeEach(func() {
+		defaultBeforeEachFunc()
+	})
+
+	AfterEach(func() {
+		defaultAfterEachFunc()
+	})
+
+	Describe("個人アクセストークンの作成", func() {
+		It("トークンを返し、ハッシュ化して保存する", func() {
+			body, _ := json.Marshal(dto.CreateAPITokenInput{Name: "ci", Scopes: []string{model.ScopeRead}, ExpiresInDays: 30})
+			w := requestAPI(http.MethodPost, "/me/tokens", token, body)
+
+			Expect(w.Code).To(Equal(http.StatusCreated))
+			var res CreateAPITokenResponse
+			json.Unmarshal(w.Body.Bytes(), &res)
+			Expect(res.Data.Token).To(HavePrefix("bbs_pat_"))
+			Expect(strings.HasPrefix(res.Data.Token, res.Data.Prefix)).To(BeTrue())
+			Expect(res.Data.Scopes).To(Equal([]string{model.ScopeRead}))
+			Expect(res.Data.ExpiresAt).NotTo(BeNil())
+
+			var saved model.APIToken
+			db.First(&saved, res.Data.ID)
+			Expect(saved.TokenHash).NotTo(BeEmpty())
+			Expect(saved.TokenHash).NotTo(Equal(res.Data.Token))
+		})
+
+		It("不明なスコープの場合は400エラーを返す", func() {
+			body, _ := json.Marshal(dto.CreateAPITokenInput{Name: "ci", Scopes: []string{"admin"}})
+			w := requestAPI(http.MethodPost, "/me/tokens", token, body)
+
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("個人アクセストークンでの認証", func() {
+		It("readスコープで参照できるが、更新はできない", func() {
+			apiToken := createAPIToken(model.ScopeRead)
+
+			w := requestAPI(http.MethodGet, "/me/threads", apiToken, nil)
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			body, _ := json.Marshal(dto.CreateThreadInput{Title: "ビルド結果", Body: "成功"})
+			w = requestAPI(http.MethodPost, "/threads", apiToken, body)
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("write:commentsスコープでコメントを投稿できるが、スレッドは作成できない", func() {
+			thread := createTestThread(db, user.ID, 1)[0]
+			apiToken := createAPIToken(model.ScopeWriteComments)
+
+			body, _ := json.Marshal(dto.CreateComment{Body: "ビルドが成功しました"})
+			w := requestAPI(http.MethodPost, fmt.Sprintf("/threads/%d/comments", thread.ID), apiToken, body)
+			Expect(w.Code).To(Equal(http.StatusCreated))
+
+			body, _ = json.Marshal(dto.CreateThreadInput{Title: "ビルド結果", Body: "成功"})
+			w = requestAPI(http.MethodPost, "/threads", apiToken, body)
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("write:threadsスコープでスレッドを作成できる", func() {
+			apiToken := createAPIToken(model.ScopeWriteThreads)
+
+			body, _ := json.Marshal(dto.CreateThreadInput{Title: "ビルド結果", Body: "成功"})
+			w := requestAPI(http.MethodPost, "/threads", apiToken, body)
+
+			Expect(w.Code).To(Equal(http.StatusCreated))
+		})
+
+		It("トークンの管理には使えない", func() {
+			apiToken := createAPIToken(model.ScopeRead, model.ScopeWriteThreads, model.ScopeWriteComments)
+
+			body, _ := json.Marshal(dto.CreateAPITokenInput{Name: "other", Scopes: []string{model.ScopeRead}})
+			w := requestAPI(http.MethodPost, "/me/tokens", apiToken, body)
+
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("最終使用日時を記録する", func() {
+			apiToken := createAPIToken(model.ScopeRead)
+
+			requestAPI(http.MethodGet, "/me/threads", apiToken, nil)
+
+			w := requestAPI(http.MethodGet, "/me/tokens", token, nil)
+			var res APITokenListResponse
+			json.Unmarshal(w.Body.Bytes(), &res)
+			Expect(res.Data).To(HaveLen(1))
+			Expect(res.Data[0].LastUsedAt).NotTo(BeNil())
+		})
+
+		It("期限切れのトークンの場合は401エラーを返す", func() {
+			apiToken := createAPIToken(model.ScopeRead)
+			db.Model(&model.APIToken{}).Where("user_id = ?", user.ID).Update("expires_at", time.Now().Add(-time.Minute))
+
+			w := requestAPI(http.MethodGet, "/me/threads", apiToken, nil)
+
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("存在しないトークンの場合は401エラーを返す", func() {
+			w := requestAPI(http.MethodGet, "/me/threads", "bbs_pat_invalid", nil)
+
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	Describe("個人アクセストークンの取り消し", func() {
+		It("取り消したトークンは使えず、一覧にも含めない", func() {
+			apiToken := createAPIToken(model.ScopeRead)
+			var saved model.APIToken
+			db.First(&saved, "user_id = ?", user.ID)
+
+			w := requestAPI(http.MethodDelete, fmt.Sprintf("/me/tokens/%d", saved.ID), token, nil)
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			w = requestAPI(http.MethodGet, "/me/threads", apiToken, nil)
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+
+			w = requestAPI(http.MethodGet, "/me/tokens", token, nil)
+			var res APITokenListResponse
+			json.Unmarshal(w.Body.Bytes(), &res)
+			Expect(res.Data).To(BeEmpty())
+		})
+
+		It("他のユーザーのトークンの場合は404エラーを返す", func() {
+			createAPIToken(model.ScopeRead)
+			var saved model.APIToken
+			db.First(&saved, "user_id = ?", user.ID)
+
+			w := requestAPI(http.MethodDelete, fmt.Sprintf("/me/tokens/%d", saved.ID), getOtherUserAuthToken(), nil)
+
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+})
+
+func createAPIToken(scopes ...string) string {
+	body, _ := json.Marshal(dto.CreateAPITokenInput{Name: "bot", Scopes: scopes})
+	w := requestAPI(http.MethodPost, "/me/tokens", token, body)
+	Expect(w.Code).To(Equal(http.StatusCreated))
+
+	var res CreateAPITokenResponse
+	json.Unmarshal(w.Body.Bytes(), &res)
+	return res.Data.Token
+}
@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"bbs/internal/model"
+	"bbs/internal/service"
+	"bbs/internal/storage"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AttachmentController struct {
+	service service.IAttachmentService
+}
+
+func NewAttachmentController(service service.IAttachmentService) IAttachmentController {
+	return &AttachmentController{service: service}
+}
+
+func (c *AttachmentController) Upload(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	defer file.Close()
+
+	attachment, err := c.service.Upload(ctx.Request.Context(), fileHeader.Filename, file, userId)
+	if err != nil {
+		if err.Error() == "file is too large" {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "file type is not allowed" {
+			ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": attachment})
+}
+
+func (c *AttachmentController) Download(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("attachmentId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	attachment, file, err := c.service.Open(ctx.Request.Context(), uint(id), viewerId(ctx))
+	if err != nil {
+		if err.Error() == "attachment not found" || errors.Is(err, storage.ErrNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "attachment not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	defer file.Close()
+
+	// 画像以外はブラウザで開かずダウンロードさせる
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "inline"
+	}
+
+	ctx.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, file, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+func (c *AttachmentController) Thumbnail(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("attachmentId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	attachment, file, err := c.service.OpenThumbnail(ctx.Request.Context(), uint(id), viewerId(ctx))
+	if err != nil {
+		if err.Error() == "attachment not found" || err.Error() == "thumbnail not found" || errors.Is(err, storage.ErrNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	defer file.Close()
+
+	ctx.DataFromReader(http.StatusOK, -1, service.ThumbnailContentType(attachment.ContentType), file, map[string]string{
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+func (c *AttachmentController) Delete(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	id, err := strconv.ParseUint(ctx.Param("attachmentId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	err = c.service.Delete(ctx.Request.Context(), uint(id), userId)
+	if err != nil {
+		if err.Error() == "user is not attachment owner" {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if err.Error() == "attachment not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
@@ -0,0 +1,287 @@
+package controller_test
+
+import (
+	"bbs/internal/model"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type AttachmentResponse struct {
+	Attachment   model.Attachment `json:"data"`
+	ErrorMessage string           `json:"error"`
+}
+
+type CreateThreadWithAttachmentsRequest struct {
+	Title         string `json:"title"`
+	Body          string `json:"body"`
+	AttachmentIDs []uint `json:"attachmentIds"`
+}
+
+var _ = Describe("AttachmentController", func() {
+	BeforeEach(func() {
+		defaultBeforeEachFunc()
+	})
+
+	AfterEach(func() {
+		defaultAfterEachFunc()
+	})
+
+	Describe("添付ファイルアップロード", func() {
+		Context("画像をアップロードした場合", func() {
+			It("ステータスコード201とサムネイル付きの添付ファイルを返す", func() {
+				w := uploadAttachment("image.png", createTestPNG(640, 480), token)
+
+				res := getAttachmentResponse(w)
+
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				Expect(res.Attachment.ID).NotTo(BeZero())
+				Expect(res.Attachment.FileName).To(Equal("image.png"))
+				Expect(res.Attachment.ContentType).To(Equal("image/png"))
+				Expect(res.Attachment.HasThumbnail).To(BeTrue())
+			})
+
+			It("アップロードした内容とサムネイルを取得できる", func() {
+				content := createTestPNG(640, 480)
+				attachment := getAttachmentResponse(uploadAttachment("image.png", content, token)).Attachment
+
+				url := "/attachments/" + strconv.Itoa(int(attachment.ID))
+				w := requestAPI(http.MethodGet, url, token, nil)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				Expect(w.Body.Bytes()).To(Equal(content))
+
+				w = requestAPI(http.MethodGet, url+"/thumbnail", token, nil)
+
+				thumbnail, err := png.Decode(w.Body)
+				Expect(err).To(BeNil())
+				Expect(thumbnail.Bounds().Dx()).To(Equal(320))
+				Expect(thumbnail.Bounds().Dy()).To(Equal(240))
+			})
+		})
+
+		Context("テキストファイルをアップロードした場合", func() {
+			It("サムネイルは作成されず、ダウンロードとして返す", func() {
+				attachment := getAttachmentResponse(uploadAttachment("build.log", []byte("build succeeded\n"), token)).Attachment
+
+				Expect(attachment.ContentType).To(Equal("text/plain"))
+				Expect(attachment.HasThumbnail).To(BeFalse())
+
+				url := "/attachments/" + strconv.Itoa(int(attachment.ID))
+				w := requestAPI(http.MethodGet, url, token, nil)
+
+				Expect(w.Header().Get("Content-Disposition")).To(Equal(`attachment; filename=build.log`))
+			})
+		})
+
+		Context("寸法が極端に大きい画像の場合", func() {
+			It("デコードせずにサムネイルなしで保存する", func() {
+				w := uploadAttachment("huge.png", createHugeDimensionPNG(100000, 100000), token)
+
+				res := getAttachmentResponse(w)
+
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				Expect(res.Attachment.ContentType).To(Equal("image/png"))
+				Expect(res.Attachment.HasThumbnail).To(BeFalse())
+			})
+		})
+
+		Context("許可されていない形式の場合", func() {
+			It("ステータスコード415を返す", func() {
+				w := uploadAttachment("image.png", []byte{0x7f, 'E', 'L', 'F', 0x02, 0x01, 0x01, 0x00}, token)
+
+				Expect(w.Code).To(Equal(http.StatusUnsupportedMediaType))
+			})
+		})
+
+		Context("サイズが上限を超える場合", func() {
+			It("ステータスコード413を返す", func() {
+				DeferCleanup(os.Setenv, "ATTACHMENT_MAX_SIZE", os.Getenv("ATTACHMENT_MAX_SIZE"))
+				os.Setenv("ATTACHMENT_MAX_SIZE", "10")
+
+				w := uploadAttachment("build.log", []byte("build succeeded\n"), token)
+
+				Expect(w.Code).To(Equal(http.StatusRequestEntityTooLarge))
+			})
+		})
+
+		Context("認証トークンがない場合", func() {
+			It("ステータスコード401を返す", func() {
+				w := uploadAttachment("build.log", []byte("build succeeded\n"), "")
+
+				Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+	})
+
+	Describe("スレッドへの添付", func() {
+		Context("アップロード済みの添付ファイルを指定した場合", func() {
+			It("スレッドに紐づいた添付ファイルを返す", func() {
+				attachment := getAttachmentResponse(uploadAttachment("build.log", []byte("build succeeded\n"), token)).Attachment
+
+				request := getCreateThreadWithAttachmentsRequestBodyBites([]uint{attachment.ID})
+				w := requestAPI(http.MethodPost, "/threads", token, request)
+
+				res := getThreadCreateResponseBody(w)
+
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				Expect(len(res.Thread.Attachments)).To(Equal(1))
+				Expect(res.Thread.Attachments[0].ID).To(Equal(attachment.ID))
+			})
+		})
+
+		Context("他のユーザーの添付ファイルを指定した場合", func() {
+			It("ステータスコード400を返す", func() {
+				attachment := getAttachmentResponse(uploadAttachment("build.log", []byte("build succeeded\n"), getOtherUserAuthToken())).Attachment
+
+				request := getCreateThreadWithAttachmentsRequestBodyBites([]uint{attachment.ID})
+				w := requestAPI(http.MethodPost, "/threads", token, request)
+
+				Expect(w.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("スレッドを削除した場合", func() {
+			It("添付ファイルも削除される", func() {
+				attachment := getAttachmentResponse(uploadAttachment("build.log", []byte("build succeeded\n"), token)).Attachment
+
+				request := getCreateThreadWithAttachmentsRequestBodyBites([]uint{attachment.ID})
+				thread := getThreadCreateResponseBody(requestAPI(http.MethodPost, "/threads", token, request)).Thread
+
+				requestAPI(http.MethodDelete, "/threads/"+strconv.Itoa(int(thread.ID)), token, nil)
+
+				w := requestAPI(http.MethodGet, "/attachments/"+strconv.Itoa(int(attachment.ID)), "", nil)
+
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("添付ファイル取得", func() {
+		Context("投稿に紐づいていない添付ファイルの場合", func() {
+			It("アップロードした本人以外にはステータスコード404を返す", func() {
+				attachment := getAttachmentResponse(uploadAttachment("build.log", []byte("build succeeded\n"), token)).Attachment
+
+				url := "/attachments/" + strconv.Itoa(int(attachment.ID))
+
+				Expect(requestAPI(http.MethodGet, url, token, nil).Code).To(Equal(http.StatusOK))
+				Expect(requestAPI(http.MethodGet, url, getOtherUserAuthToken(), nil).Code).To(Equal(http.StatusNotFound))
+				Expect(requestAPI(http.MethodGet, url, "", nil).Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("非表示のスレッドに添付されている場合", func() {
+			It("ステータスコード404を返す", func() {
+				attachment := getAttachmentResponse(uploadAttachment("build.log", []byte("build succeeded\n"), token)).Attachment
+
+				request := getCreateThreadWithAttachmentsRequestBodyBites([]uint{attachment.ID})
+				thread := getThreadCreateResponseBody(requestAPI(http.MethodPost, "/threads", token, request)).Thread
+
+				url := "/attachments/" + strconv.Itoa(int(attachment.ID))
+				Expect(requestAPI(http.MethodGet, url, "", nil).Code).To(Equal(http.StatusOK))
+
+				db.Model(&model.Thread{}).Where("id = ?", thread.ID).Update("hidden", true)
+
+				Expect(requestAPI(http.MethodGet, url, "", nil).Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("非表示のコメントに添付されている場合", func() {
+			It("ステータスコード404を返す", func() {
+				thread := createTestThread(db, user.ID, 1)[0]
+				attachment := getAttachmentResponse(uploadAttachment("build.log", []byte("build succeeded\n"), token)).Attachment
+
+				request, _ := json.Marshal(map[string]interface{}{"body": "添付", "attachmentIds": []uint{attachment.ID}})
+				w := requestAPI(http.MethodPost, "/threads/"+strconv.Itoa(int(thread.ID))+"/comments", token, request)
+				Expect(w.Code).To(Equal(http.StatusCreated))
+
+				url := "/attachments/" + strconv.Itoa(int(attachment.ID))
+				Expect(requestAPI(http.MethodGet, url, "", nil).Code).To(Equal(http.StatusOK))
+
+				db.Model(&model.Comment{}).Where("thread_id = ?", thread.ID).Update("hidden", true)
+
+				Expect(requestAPI(http.MethodGet, url, "", nil).Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("添付ファイル削除", func() {
+		Context("所有者ではない場合", func() {
+			It("ステータスコード401を返す", func() {
+				attachment := getAttachmentResponse(uploadAttachment("build.log", []byte("build succeeded\n"), token)).Attachment
+
+				url := "/attachments/" + strconv.Itoa(int(attachment.ID))
+				w := requestAPI(http.MethodDelete, url, getOtherUserAuthToken(), nil)
+
+				Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+	})
+})
+
+func uploadAttachment(fileName string, content []byte, authToken string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", fileName)
+	part.Write(content)
+	writer.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/attachments", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+authToken)
+	}
+
+	r.ServeHTTP(w, req)
+
+	return w
+}
+
+func createTestPNG(width int, height int) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)))
+
+	return buf.Bytes()
+}
+
+// 1x1のPNGのIHDRの寸法だけを書き換え、ファイルは小さいまま巨大な画像を装う
+func createHugeDimensionPNG(width uint32, height uint32) []byte {
+	data := createTestPNG(1, 1)
+	// シグネチャ8バイト、チャンク長4バイト、チャンク種別4バイトの後にIHDRのデータが続く
+	binary.BigEndian.PutUint32(data[16:20], width)
+	binary.BigEndian.PutUint32(data[20:24], height)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+
+	return data
+}
+
+func getAttachmentResponse(w *httptest.ResponseRecorder) AttachmentResponse {
+	var res AttachmentResponse
+	decoder := json.NewDecoder(bytes.NewReader(w.Body.Bytes()))
+	decoder.Decode(&res)
+
+	return res
+}
+
+func getCreateThreadWithAttachmentsRequestBodyBites(attachmentIds []uint) []byte {
+	request := CreateThreadWithAttachmentsRequest{
+		Title:         "テスト",
+		Body:          "テストテスト",
+		AttachmentIDs: attachmentIds,
+	}
+	requestBytes, _ := json.Marshal(request)
+
+	return requestBytes
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"bbs/internal/dto"
 	"bbs/internal/service"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -23,7 +24,7 @@ func (c *AuthController) Signup(ctx *gin.Context) {
 		return
 	}
 
-	err := c.service.Signup(input.Name, input.Email, input.Password)
+	err := c.service.Signup(ctx.Request.Context(), input.Name, input.Email, input.Password)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
 		return
@@ -38,13 +39,30 @@ func (c *AuthController) Login(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	token, err := c.service.Login(input.Email, input.Password)
+	output, err := c.service.Login(ctx.Request.Context(), input.Email, input.Password)
 	if err != nil {
 		if err.Error() == "user not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, service.ErrUserBanned) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
-	ctx.JSON(http.StatusOK, gin.H{"token": token})
+	ctx.JSON(http.StatusOK, output)
+}
+
+// JWKSの形式に合わせ、dataで包まずにそのまま返す
+func (c *AuthController) JWKS(ctx *gin.Context) {
+	jwks, err := c.service.JWKS(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, jwks)
 }
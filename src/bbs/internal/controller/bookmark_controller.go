@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BookmarkController struct {
+	service service.IBookmarkService
+}
+
+func NewBookmarkController(service service.IBookmarkService) IBookmarkController {
+	return &BookmarkController{service: service}
+}
+
+// スレッドとコメントのどちらのブックマークにも使う。ブックマーク済みの場合はメモを更新する
+func (c *BookmarkController) Save(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	threadId, commentId, ok := parsePostTarget(ctx)
+	if !ok {
+		return
+	}
+
+	// メモは省略できる
+	var input dto.SaveBookmarkInput
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	bookmark, err := c.service.Save(ctx.Request.Context(), input, threadId, commentId, userId)
+	if err != nil {
+		if err.Error() == "thread not found" || err.Error() == "comment not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": bookmark})
+}
+
+func (c *BookmarkController) Delete(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	threadId, commentId, ok := parsePostTarget(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.Delete(ctx.Request.Context(), threadId, commentId, userId); err != nil {
+		if err.Error() == "bookmark not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (c *BookmarkController) FindMine(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	limit, offset, err := parsePagination(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bookmarkList, err := c.service.FindByUserId(ctx.Request.Context(), userId, limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	for _, bookmark := range bookmarkList.Bookmarks {
+		if bookmark.Thread != nil {
+			service.AnonymizeThread(bookmark.Thread, currentUser(ctx))
+		}
+		if bookmark.Comment != nil {
+			service.AnonymizeComment(bookmark.Comment, currentUser(ctx))
+		}
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": bookmarkList})
+}
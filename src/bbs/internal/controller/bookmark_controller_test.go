@@ -0,0 +1,165 @@
+package controller_test
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type BookmarkListResponse struct {
+	Data dto.BookmarkListOutput `json:"data"`
+}
+
+var _ = Describe("BookmarkController", func() {
+	BeforeEach(func() {
+		defaultBeforeEachFunc()
+	})
+
+	AfterEach(func() {
+		defaultAfterEachFunc()
+	})
+
+	Describe("ブックマーク", func() {
+		Context("スレッドとコメントをブックマークした場合", func() {
+			It("メモとともに新しい順に一覧に表示される", func() {
+				comment := createTestComment(db, user.ID, 1)[0]
+
+				w := requestAPI(http.MethodPut, threadBookmarkURL(comment.ThreadID), token, getBookmarkRequestBodyBites("あとで読む"))
+				Expect(w.Code).To(Equal(http.StatusOK))
+				w = requestAPI(http.MethodPut, commentBookmarkURL(comment.ThreadID, comment.ID), token, nil)
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				list := getBookmarkListResponse(requestAPI(http.MethodGet, "/me/bookmarks", token, nil))
+
+				Expect(list.Data.Total).To(Equal(int64(2)))
+				Expect(*list.Data.Bookmarks[0].CommentID).To(Equal(comment.ID))
+				Expect(list.Data.Bookmarks[0].Comment.Body).To(Equal(comment.Body))
+				Expect(list.Data.Bookmarks[1].CommentID).To(BeNil())
+				Expect(list.Data.Bookmarks[1].Note).To(Equal("あとで読む"))
+				Expect(list.Data.Bookmarks[1].Thread.ID).To(Equal(comment.ThreadID))
+			})
+
+			It("再度ブックマークするとメモが更新される", func() {
+				thread := createTestThread(db, user.ID, 1)[0]
+				requestAPI(http.MethodPut, threadBookmarkURL(thread.ID), token, getBookmarkRequestBodyBites("メモ1"))
+				requestAPI(http.MethodPut, threadBookmarkURL(thread.ID), token, getBookmarkRequestBodyBites("メモ2"))
+
+				list := getBookmarkListResponse(requestAPI(http.MethodGet, "/me/bookmarks", token, nil))
+
+				Expect(list.Data.Total).To(Equal(int64(1)))
+				Expect(list.Data.Bookmarks[0].Note).To(Equal("メモ2"))
+			})
+
+			It("同じ対象へのブックマークは一意制約により重複しない", func() {
+				comment := createTestComment(db, user.ID, 1)[0]
+				requestAPI(http.MethodPut, threadBookmarkURL(comment.ThreadID), token, getBookmarkRequestBodyBites("スレッド"))
+				requestAPI(http.MethodPut, commentBookmarkURL(comment.ThreadID, comment.ID), token, getBookmarkRequestBodyBites("コメント1"))
+				requestAPI(http.MethodPut, commentBookmarkURL(comment.ThreadID, comment.ID), token, getBookmarkRequestBodyBites("コメント2"))
+
+				var count int64
+				db.Model(&model.Bookmark{}).Where("user_id = ?", user.ID).Count(&count)
+				Expect(count).To(Equal(int64(2)))
+
+				db.SavePoint("duplicate")
+				err := db.Create(&model.Bookmark{UserID: user.ID, ThreadID: comment.ThreadID, Note: "重複"}).Error
+				db.RollbackTo("duplicate")
+				Expect(err).NotTo(BeNil())
+			})
+
+			It("スレッドとコメントの取得結果にブックマーク済みであることが含まれる", func() {
+				comment := createTestComment(db, user.ID, 2)[0]
+				requestAPI(http.MethodPut, threadBookmarkURL(comment.ThreadID), token, nil)
+				requestAPI(http.MethodPut, commentBookmarkURL(comment.ThreadID, comment.ID), token, nil)
+
+				thread := getThreadDetailResponseBody(requestAPI(http.MethodGet, "/threads/"+strconv.Itoa(int(comment.ThreadID)), token, nil))
+				Expect(thread.Thread.Bookmarked).To(BeTrue())
+
+				url := "/threads/" + strconv.Itoa(int(comment.ThreadID)) + "/comments"
+				w := requestAPI(http.MethodGet, url, token, nil)
+				comments := getCommentListResponse(w.Body.Bytes()).Data.Comments
+				Expect(comments[0].Bookmarked).To(BeTrue())
+				Expect(comments[1].Bookmarked).To(BeFalse())
+
+				w = requestAPI(http.MethodGet, url, getOtherUserAuthToken(), nil)
+				comments = getCommentListResponse(w.Body.Bytes()).Data.Comments
+				Expect(comments[0].Bookmarked).To(BeFalse())
+			})
+		})
+
+		Context("ブックマークした対象が削除された場合", func() {
+			It("内容を含まない削除済みの項目として一覧に残る", func() {
+				thread := createTestThread(db, user.ID, 1)[0]
+				requestAPI(http.MethodPut, threadBookmarkURL(thread.ID), token, getBookmarkRequestBodyBites("メモ"))
+				requestAPI(http.MethodDelete, "/threads/"+strconv.Itoa(int(thread.ID)), token, nil)
+
+				list := getBookmarkListResponse(requestAPI(http.MethodGet, "/me/bookmarks", token, nil))
+
+				Expect(list.Data.Total).To(Equal(int64(1)))
+				Expect(list.Data.Bookmarks[0].Deleted).To(BeTrue())
+				Expect(list.Data.Bookmarks[0].Thread).To(BeNil())
+				Expect(list.Data.Bookmarks[0].Note).To(Equal("メモ"))
+
+				w := requestAPI(http.MethodDelete, threadBookmarkURL(thread.ID), token, nil)
+				Expect(w.Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("存在しないコメントの場合", func() {
+			It("ステータスコード404を返す", func() {
+				thread := createTestThread(db, user.ID, 1)[0]
+
+				w := requestAPI(http.MethodPut, commentBookmarkURL(thread.ID, 999), token, nil)
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("閲覧できないスレッドの場合", func() {
+			It("ステータスコード404を返す", func() {
+				otherUser := createTestUser(r, db, "other", "other@example.com")
+				thread := createTestThread(db, otherUser.ID, 1)[0]
+				db.Model(&thread).Update("visibility", model.VisibilityPrivate)
+
+				w := requestAPI(http.MethodPut, threadBookmarkURL(thread.ID), token, nil)
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("ブックマークしていない場合", func() {
+			It("削除するとステータスコード404を返す", func() {
+				thread := createTestThread(db, user.ID, 1)[0]
+
+				w := requestAPI(http.MethodDelete, threadBookmarkURL(thread.ID), token, nil)
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+})
+
+func threadBookmarkURL(threadId uint) string {
+	return "/threads/" + strconv.Itoa(int(threadId)) + "/bookmark"
+}
+
+func commentBookmarkURL(threadId uint, commentId uint) string {
+	return "/threads/" + strconv.Itoa(int(threadId)) + "/comments/" + strconv.Itoa(int(commentId)) + "/bookmark"
+}
+
+func getBookmarkRequestBodyBites(note string) []byte {
+	requestBytes, _ := json.Marshal(dto.SaveBookmarkInput{Note: note})
+
+	return requestBytes
+}
+
+func getBookmarkListResponse(w *httptest.ResponseRecorder) BookmarkListResponse {
+	var res BookmarkListResponse
+	decoder := json.NewDecoder(bytes.NewReader(w.Body.Bytes()))
+	decoder.Decode(&res)
+
+	return res
+}
@@ -1,9 +1,11 @@
 package controller
 
 import (
+	"bbs/internal/contentfilter"
 	"bbs/internal/dto"
 	"bbs/internal/model"
 	"bbs/internal/service"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -39,16 +41,29 @@ func (c *CommentController) Create(ctx *gin.Context) {
 		return
 	}
 
-	newComment, err := c.service.Create(input, uint(threadId), userId)
+	newComment, err := c.service.Create(ctx.Request.Context(), input, uint(threadId), userId)
 	if err != nil {
 		if err.Error() == "thread not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if err.Error() == "thread is locked" || err.Error() == "thread is archived" {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "attachment not found" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, contentfilter.ErrRejected) {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
 		return
 	}
 
+	service.AnonymizeComment(newComment, currentUser(ctx))
 	ctx.JSON(http.StatusCreated, gin.H{"data": newComment})
 }
 
@@ -79,19 +94,29 @@ func (c *CommentController) Update(ctx *gin.Context) {
 		return
 	}
 
-	updateComment, err := c.service.Update(input, uint(commentId), uint(threadId), userId)
+	updateComment, err := c.service.Update(ctx.Request.Context(), input, uint(commentId), uint(threadId), userId)
 	if err != nil {
 		if err.Error() == "user is not comment owner" {
 			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
 		}
-		if err.Error() == "comment not found" {
+		if err.Error() == "thread is archived" {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "comment not found" || err.Error() == "thread not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, contentfilter.ErrRejected) {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
 		return
 	}
 
+	service.AnonymizeComment(updateComment, currentUser(ctx))
 	ctx.JSON(http.StatusOK, gin.H{"data": updateComment})
 }
 
@@ -116,8 +141,16 @@ func (c *CommentController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	err = c.service.Delete(uint(commentId), uint(threadId), userId)
+	err = c.service.Delete(ctx.Request.Context(), uint(commentId), uint(threadId), userId)
 	if err != nil {
+		if err.Error() == "user is not comment owner" {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if err.Error() == "comment not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
 		return
 	}
@@ -126,6 +159,62 @@ func (c *CommentController) Delete(ctx *gin.Context) {
 }
 
 func (c *CommentController) FindByThreadId(ctx *gin.Context) {
+	threadId, err := strconv.ParseUint(ctx.Param("threadId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread id"})
+		return
+	}
+
+	// スレッド内のコメントは古い順に表示する
+	query, err := parseCommentListQuery(ctx, dto.OrderAsc)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	commentList, err := c.service.FindByThreadId(ctx.Request.Context(), uint(threadId), query, viewerId(ctx))
+	if err != nil {
+		if err.Error() == "thread not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	service.AnonymizeComments(commentList.Comments, currentUser(ctx))
+	ctx.JSON(http.StatusOK, gin.H{"data": commentList})
+}
+
+func (c *CommentController) FindByUserId(ctx *gin.Context) {
+	userId, err := strconv.ParseUint(ctx.Param("userId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	// ユーザーごとのコメントは新しい順に表示する
+	query, err := parseCommentListQuery(ctx, dto.OrderDesc)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	commentList, err := c.service.FindByUserId(ctx.Request.Context(), uint(userId), query, viewerId(ctx))
+	if err != nil {
+		if err.Error() == "user not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	service.AnonymizeComments(commentList.Comments, currentUser(ctx))
+	ctx.JSON(http.StatusOK, gin.H{"data": commentList})
+}
+
+func (c *CommentController) FindMine(ctx *gin.Context) {
 	user, exists := ctx.Get("user")
 	if !exists {
 		ctx.AbortWithStatus(http.StatusUnauthorized)
@@ -134,44 +223,141 @@ func (c *CommentController) FindByThreadId(ctx *gin.Context) {
 
 	userId := user.(*model.User).ID
 
+	query, err := parseCommentListQuery(ctx, dto.OrderDesc)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	commentList, err := c.service.FindByUserId(ctx.Request.Context(), userId, query, userId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	service.AnonymizeComments(commentList.Comments, currentUser(ctx))
+	ctx.JSON(http.StatusOK, gin.H{"data": commentList})
+}
+
+func (c *CommentController) FindById(ctx *gin.Context) {
 	threadId, err := strconv.ParseUint(ctx.Param("threadId"), 10, 64)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread id"})
 		return
 	}
 
-	comments, err := c.service.FindByThreadId(uint(threadId), userId)
+	id, err := strconv.ParseUint(ctx.Param("commentId"), 10, 64)
 	if err != nil {
-		if err.Error() == "comment not found" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	comment, err := c.service.FindById(ctx.Request.Context(), uint(id), uint(threadId), viewerId(ctx))
+	if err != nil {
+		if err.Error() == "thread not found" || err.Error() == "comment not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
 		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"data": comments})
+	service.AnonymizeComment(comment, currentUser(ctx))
+	ctx.JSON(http.StatusOK, gin.H{"data": comment})
 }
 
-func (c *CommentController) FindById(ctx *gin.Context) {
+func (c *CommentController) FindRevisions(ctx *gin.Context) {
 	threadId, err := strconv.ParseUint(ctx.Param("threadId"), 10, 64)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread id"})
 		return
 	}
 
-	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	commentId, err := strconv.ParseUint(ctx.Param("commentId"), 10, 64)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment id"})
+		return
 	}
 
-	comment, err := c.service.FindById(uint(id), uint(threadId))
+	revisions, err := c.service.FindRevisions(ctx.Request.Context(), uint(commentId), uint(threadId), viewerId(ctx))
 	if err != nil {
-		if err.Error() == "comment not found" {
+		if err.Error() == "thread not found" || err.Error() == "comment not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
 	}
 
+	service.AnonymizeRevisions(*revisions, currentUser(ctx))
+	ctx.JSON(http.StatusOK, gin.H{"data": revisions})
+}
+
+func (c *CommentController) RevertRevision(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	threadId, err := strconv.ParseUint(ctx.Param("threadId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread id"})
+		return
+	}
+
+	commentId, err := strconv.ParseUint(ctx.Param("commentId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment id"})
+		return
+	}
+
+	revisionId, err := strconv.ParseUint(ctx.Param("revisionId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision id"})
+		return
+	}
+
+	comment, err := c.service.RevertRevision(ctx.Request.Context(), uint(commentId), uint(threadId), uint(revisionId), userId)
+	if err != nil {
+		if err.Error() == "comment not found" || err.Error() == "revision not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	service.AnonymizeComment(comment, currentUser(ctx))
 	ctx.JSON(http.StatusOK, gin.H{"data": comment})
 }
+
+// クエリのpage・limitに加え、order(ascまたはdesc)とauthor(ユーザーID)を読み取る
+func parseCommentListQuery(ctx *gin.Context, defaultOrder string) (dto.CommentListQuery, error) {
+	limit, offset, err := parsePagination(ctx)
+	if err != nil {
+		return dto.CommentListQuery{}, err
+	}
+
+	query := dto.CommentListQuery{Limit: limit, Offset: offset, Order: defaultOrder}
+
+	if order := ctx.Query("order"); order != "" {
+		if order != dto.OrderAsc && order != dto.OrderDesc {
+			return dto.CommentListQuery{}, errors.New("invalid order")
+		}
+		query.Order = order
+	}
+
+	if author := ctx.Query("author"); author != "" {
+		authorId, err := strconv.ParseUint(author, 10, 64)
+		if err != nil {
+			return dto.CommentListQuery{}, errors.New("invalid author")
+		}
+		id := uint(authorId)
+		query.AuthorID = &id
+	}
+
+	return query, nil
+}
@@ -1,14 +1,17 @@
 package controller_test
 
 import (
+	"bbs/internal/dto"
 	"bbs/internal/model"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
 )
 
 type CommentCreateRequest struct {
@@ -16,7 +19,12 @@ type CommentCreateRequest struct {
 }
 
 type CommentCreateResponse struct {
-	Comment model.Comment `json:"data"`
+	Comment      model.Comment `json:"data"`
+	ErrorMessage string        `json:"error"`
+}
+
+type CommentListResponse struct {
+	Data dto.CommentListOutput `json:"data"`
 }
 
 type CommentUpdateRequest struct {
@@ -132,6 +140,120 @@ var _ = Describe("CommentController", func() {
 			})
 		})
 
+		Context("本文に言及とアンカーが含まれる場合", func() {
+			It("言及したユーザーと参照したコメントが返る", func() {
+				testComment := createTestComment(db, user.ID, 1)[0]
+
+				body := ">>" + strconv.Itoa(int(testComment.ID)) + "\n@" + user.Name + " 返信です"
+				requestBytes := getCreateCommentRequestBodyBites(body)
+
+				url := "/threads/" + strconv.Itoa(int(testComment.ThreadID)) + "/comments"
+				w := requestAPI(http.MethodPost, url, token, requestBytes)
+
+				res := getCreateCommentResponse(w.Body.Bytes())
+
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				Expect(len(res.Comment.Mentions)).To(Equal(1))
+				Expect(res.Comment.Mentions[0].UserID).To(Equal(user.ID))
+				Expect(len(res.Comment.References)).To(Equal(1))
+				Expect(res.Comment.References[0].ReferencedCommentID).To(Equal(testComment.ID))
+				Expect(res.Comment.BodyHTML).NotTo(ContainSubstring("<blockquote>"))
+			})
+
+			It("同じ名前のユーザーが複数いる場合は言及として扱わない", func() {
+				createTestUser(r, db, "taro", "taro1@example.com")
+				createTestUser(r, db, "taro", "taro2@example.com")
+				testThread := createTestThread(db, user.ID, 1)[0]
+
+				body := "@taro @" + user.Name + " 返信です"
+				requestBytes := getCreateCommentRequestBodyBites(body)
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments"
+				w := requestAPI(http.MethodPost, url, token, requestBytes)
+
+				res := getCreateCommentResponse(w.Body.Bytes())
+
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				Expect(len(res.Comment.Mentions)).To(Equal(1))
+				Expect(res.Comment.Mentions[0].UserID).To(Equal(user.ID))
+			})
+
+			It("別のスレッドのコメントは参照として扱わない", func() {
+				otherComment := createTestComment(db, user.ID, 1)[0]
+				testThread := createTestThread(db, user.ID, 1)[0]
+
+				body := ">>" + strconv.Itoa(int(otherComment.ID))
+				requestBytes := getCreateCommentRequestBodyBites(body)
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments"
+				w := requestAPI(http.MethodPost, url, token, requestBytes)
+
+				res := getCreateCommentResponse(w.Body.Bytes())
+
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				Expect(len(res.Comment.References)).To(Equal(0))
+			})
+
+			It("言及の保存に失敗した場合はコメントもロールバックされる", func() {
+				db.Callback().Create().Before("gorm:create").Register("test:fail_mention", func(tx *gorm.DB) {
+					if tx.Statement.Table == "comment_mentions" {
+						tx.AddError(errors.New("mention insert failed"))
+					}
+				})
+				DeferCleanup(func() {
+					db.Callback().Create().Remove("test:fail_mention")
+				})
+				testThread := createTestThread(db, user.ID, 1)[0]
+
+				requestBytes := getCreateCommentRequestBodyBites("@" + user.Name + " 返信です")
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments"
+				w := requestAPI(http.MethodPost, url, token, requestBytes)
+				Expect(w.Code).To(Equal(http.StatusInternalServerError))
+
+				var commentCount int64
+				db.Model(&model.Comment{}).Where("thread_id = ?", testThread.ID).Count(&commentCount)
+				Expect(commentCount).To(BeZero())
+			})
+		})
+
+		Context("スレッドがロックされている場合", func() {
+			It("403エラーとthread is lockedが返る", func() {
+				testThreadNum := 1
+				testThread := createTestThread(db, user.ID, testThreadNum)[0]
+				db.Model(&testThread).Update("locked", true)
+
+				body := "コメント本文"
+				requestBytes := getCreateCommentRequestBodyBites(body)
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments"
+				w := requestAPI(http.MethodPost, url, token, requestBytes)
+
+				res := getCreateCommentResponse(w.Body.Bytes())
+
+				Expect(w.Code).To(Equal(http.StatusForbidden))
+				Expect(res.ErrorMessage).To(Equal("thread is locked"))
+			})
+		})
+
+		Context("スレッドがアーカイブされている場合", func() {
+			It("403エラーとthread is archivedが返る", func() {
+				testThreadNum := 1
+				testThread := createTestThread(db, user.ID, testThreadNum)[0]
+				db.Model(&testThread).Update("archived", true)
+
+				body := "コメント本文"
+				requestBytes := getCreateCommentRequestBodyBites(body)
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments"
+				w := requestAPI(http.MethodPost, url, token, requestBytes)
+
+				res := getCreateCommentResponse(w.Body.Bytes())
+
+				Expect(w.Code).To(Equal(http.StatusForbidden))
+				Expect(res.ErrorMessage).To(Equal("thread is archived"))
+			})
+		})
+
 		Context("スレッドが存在しない場合", func() {
 			It("404エラーが返る", func() {
 				body := "コメント本文"
@@ -143,9 +265,80 @@ var _ = Describe("CommentController", func() {
 				Expect(w.Code).To(Equal(http.StatusNotFound))
 			})
 		})
+
+		Context("スレッドが削除されている場合", func() {
+			It("404エラーが返り、コメントは作成されない", func() {
+				testThreadNum := 1
+				testThread := createTestThread(db, user.ID, testThreadNum)[0]
+				db.Delete(&testThread)
+
+				body := "コメント本文"
+				requestBytes := getCreateCommentRequestBodyBites(body)
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments"
+				w := requestAPI(http.MethodPost, url, token, requestBytes)
+
+				var count int64
+				db.Unscoped().Model(&model.Comment{}).Where("thread_id = ?", testThread.ID).Count(&count)
+
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+				Expect(count).To(Equal(int64(0)))
+			})
+		})
+	})
+
+	Describe("コメント削除", func() {
+		Context("自分のコメントを削除した場合", func() {
+			It("ステータスコード200を返し、コメントが取得できなくなる", func() {
+				testComment := createTestComment(db, user.ID, 1)[0]
+
+				url := "/threads/" + strconv.Itoa(int(testComment.ThreadID)) + "/comments/" + strconv.Itoa(int(testComment.ID))
+				w := requestAPI(http.MethodDelete, url, token, nil)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				Expect(requestAPI(http.MethodGet, url, token, nil).Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("他のユーザーのコメントを削除しようとした場合", func() {
+			It("ステータスコード401を返す", func() {
+				testComment := createTestComment(db, user.ID, 1)[0]
+
+				url := "/threads/" + strconv.Itoa(int(testComment.ThreadID)) + "/comments/" + strconv.Itoa(int(testComment.ID))
+				w := requestAPI(http.MethodDelete, url, getOtherUserAuthToken(), nil)
+
+				Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Context("コメントが存在しない場合", func() {
+			It("ステータスコード404を返す", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments/9999"
+				w := requestAPI(http.MethodDelete, url, token, nil)
+
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+			})
+		})
 	})
 
 	Describe("コメント更新", func() {
+		Context("スレッドが非表示になっている場合", func() {
+			It("ステータスコード404を返し、コメントを更新しない", func() {
+				testComment := createTestComment(db, user.ID, 1)[0]
+				db.Model(&model.Thread{}).Where("id = ?", testComment.ThreadID).Update("hidden", true)
+
+				url := "/threads/" + strconv.Itoa(int(testComment.ThreadID)) + "/comments/" + strconv.Itoa(int(testComment.ID))
+				w := requestAPI(http.MethodPut, url, token, getUpdateCommentRequestBodyBites("コメント本文更新"))
+
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+				var comment model.Comment
+				db.First(&comment, testComment.ID)
+				Expect(comment.Body).To(Equal(testComment.Body))
+			})
+		})
+
 		Context("リクエストが正常な場合", func() {
 			It("ステータスコード200が返る", func() {
 				testCommentNum := 1
@@ -270,6 +463,30 @@ var _ = Describe("CommentController", func() {
 			})
 		})
 
+		Context("コメントを更新した場合", func() {
+			It("更新前の内容が履歴として残る", func() {
+				testCommentNum := 1
+				testComment := createTestComment(db, user.ID, testCommentNum)[0]
+
+				body := "コメント本文更新"
+				requestBytes := getUpdateCommentRequestBodyBites(body)
+
+				url := "/threads/" + strconv.Itoa(int(testComment.ThreadID)) + "/comments/" + strconv.Itoa(int(testComment.ID))
+				w := requestAPI(http.MethodPut, url, token, requestBytes)
+
+				res := getUpdateCommentResponse(w.Body.Bytes())
+				Expect(res.Comment.Edited).To(BeTrue())
+
+				w = requestAPI(http.MethodGet, url+"/revisions", token, nil)
+				revisions := getRevisionListResponseBody(w).Revisions
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				Expect(len(revisions)).To(Equal(1))
+				Expect(revisions[0].Body).To(Equal(testComment.Body))
+				Expect(revisions[0].EditedBy).To(Equal(user.ID))
+			})
+		})
+
 		Context("コメントの所有者と更新者が異なる", func() {
 			It("401エラーが返る", func() {
 				testCommentNum := 1
@@ -287,6 +504,174 @@ var _ = Describe("CommentController", func() {
 			})
 		})
 	})
+
+	Describe("コメント一覧", func() {
+		Context("複数のユーザーがコメントした場合", func() {
+			It("スレッド内のすべてのコメントを古い順に返す", func() {
+				otherUser := createTestUser(r, db, "other", "other@example.com")
+				testThread := createTestThread(db, user.ID, 1)[0]
+				db.Create(&model.Comment{Body: "1", ThreadID: testThread.ID, UserID: user.ID})
+				db.Create(&model.Comment{Body: "2", ThreadID: testThread.ID, UserID: otherUser.ID})
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments"
+				w := requestAPI(http.MethodGet, url, "", nil)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				res := getCommentListResponse(w.Body.Bytes())
+				Expect(res.Data.Total).To(Equal(int64(2)))
+				Expect(res.Data.Comments[0].Body).To(Equal("1"))
+				Expect(res.Data.Comments[1].Body).To(Equal("2"))
+			})
+		})
+
+		Context("ページと並び順を指定した場合", func() {
+			It("指定したページを指定した順で返す", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+				for _, body := range []string{"1", "2", "3"} {
+					db.Create(&model.Comment{Body: body, ThreadID: testThread.ID, UserID: user.ID})
+				}
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments"
+				res := getCommentListResponse(requestAPI(http.MethodGet, url+"?limit=2&page=2", "", nil).Body.Bytes())
+				Expect(res.Data.Total).To(Equal(int64(3)))
+				Expect(res.Data.Comments).To(HaveLen(1))
+				Expect(res.Data.Comments[0].Body).To(Equal("3"))
+
+				res = getCommentListResponse(requestAPI(http.MethodGet, url+"?order=desc", "", nil).Body.Bytes())
+				Expect(res.Data.Comments[0].Body).To(Equal("3"))
+
+				w := requestAPI(http.MethodGet, url+"?order=random", "", nil)
+				Expect(w.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("投稿者を指定した場合", func() {
+			It("そのユーザーのコメントのみ返す", func() {
+				otherUser := createTestUser(r, db, "other", "other@example.com")
+				testThread := createTestThread(db, user.ID, 1)[0]
+				db.Create(&model.Comment{Body: "1", ThreadID: testThread.ID, UserID: user.ID})
+				db.Create(&model.Comment{Body: "2", ThreadID: testThread.ID, UserID: otherUser.ID})
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments?author=" + strconv.Itoa(int(otherUser.ID))
+				res := getCommentListResponse(requestAPI(http.MethodGet, url, "", nil).Body.Bytes())
+
+				Expect(res.Data.Total).To(Equal(int64(1)))
+				Expect(res.Data.Comments[0].UserID).To(Equal(otherUser.ID))
+			})
+		})
+	})
+
+	Describe("ユーザーごとのコメント一覧", func() {
+		Context("複数のスレッドにコメントした場合", func() {
+			It("閲覧できるスレッドへのコメントを新しい順に返す", func() {
+				threads := createTestThread(db, user.ID, 2)
+				privateThread := model.Thread{Title: "非公開", Body: "本文", UserID: user.ID, Visibility: model.VisibilityPrivate}
+				db.Create(&privateThread)
+				db.Create(&model.Comment{Body: "1", ThreadID: threads[0].ID, UserID: user.ID})
+				db.Create(&model.Comment{Body: "2", ThreadID: threads[1].ID, UserID: user.ID})
+				db.Create(&model.Comment{Body: "非公開", ThreadID: privateThread.ID, UserID: user.ID})
+
+				url := "/users/" + strconv.Itoa(int(user.ID)) + "/comments"
+				res := getCommentListResponse(requestAPI(http.MethodGet, url, "", nil).Body.Bytes())
+				Expect(res.Data.Total).To(Equal(int64(2)))
+				Expect(res.Data.Comments[0].Body).To(Equal("2"))
+
+				res = getCommentListResponse(requestAPI(http.MethodGet, url, token, nil).Body.Bytes())
+				Expect(res.Data.Total).To(Equal(int64(3)))
+			})
+
+			It("匿名のコメントは本人以外には返さない", func() {
+				anonymousThread := model.Thread{Title: "匿名", Body: "本文", UserID: user.ID, Anonymous: true}
+				db.Create(&anonymousThread)
+				db.Create(&model.Comment{Body: "匿名", ThreadID: anonymousThread.ID, UserID: user.ID, Anonymous: true})
+
+				url := "/users/" + strconv.Itoa(int(user.ID)) + "/comments"
+				res := getCommentListResponse(requestAPI(http.MethodGet, url, getOtherUserAuthToken(), nil).Body.Bytes())
+				Expect(res.Data.Total).To(Equal(int64(0)))
+
+				res = getCommentListResponse(requestAPI(http.MethodGet, url, token, nil).Body.Bytes())
+				Expect(res.Data.Total).To(Equal(int64(1)))
+			})
+		})
+
+		Context("ユーザーが存在しない場合", func() {
+			It("ステータスコード404を返す", func() {
+				w := requestAPI(http.MethodGet, "/users/0/comments", "", nil)
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("自分のコメント一覧", func() {
+		Context("ログインしている場合", func() {
+			It("自分のコメントのみ返す", func() {
+				otherUser := createTestUser(r, db, "other", "other@example.com")
+				testThread := createTestThread(db, user.ID, 1)[0]
+				db.Create(&model.Comment{Body: "1", ThreadID: testThread.ID, UserID: user.ID})
+				db.Create(&model.Comment{Body: "2", ThreadID: testThread.ID, UserID: otherUser.ID})
+
+				w := requestAPI(http.MethodGet, "/me/comments", token, nil)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				res := getCommentListResponse(w.Body.Bytes())
+				Expect(res.Data.Total).To(Equal(int64(1)))
+				Expect(res.Data.Comments[0].Body).To(Equal("1"))
+			})
+		})
+	})
+
+	Describe("匿名スレッドへのコメント", func() {
+		Context("匿名スレッドにコメントした場合", func() {
+			It("ユーザーIDの代わりにスレッドごとの投稿者IDが返る", func() {
+				testThread := model.Thread{Title: "匿名スレッド", Body: "本文", UserID: user.ID, Anonymous: true}
+				db.Create(&testThread)
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments"
+
+				first := getCreateCommentResponse(requestAPI(http.MethodPost, url, token, getCreateCommentRequestBodyBites("1つ目")).Body.Bytes()).Comment
+				second := getCreateCommentResponse(requestAPI(http.MethodPost, url, token, getCreateCommentRequestBodyBites("2つ目")).Body.Bytes()).Comment
+				other := getCreateCommentResponse(requestAPI(http.MethodPost, url, getOtherUserAuthToken(), getCreateCommentRequestBodyBites("3つ目")).Body.Bytes()).Comment
+
+				Expect(first.Anonymous).To(BeTrue())
+				Expect(first.UserID).To(Equal(uint(0)))
+				Expect(first.PosterID).NotTo(BeEmpty())
+				Expect(second.PosterID).To(Equal(first.PosterID))
+				Expect(other.PosterID).NotTo(Equal(first.PosterID))
+
+				var saved model.Comment
+				db.First(&saved, first.ID)
+				Expect(saved.UserID).To(Equal(user.ID))
+			})
+
+			It("スレッドが異なれば投稿者IDも異なる", func() {
+				var posterIds []string
+				for i := 0; i < 2; i++ {
+					testThread := model.Thread{Title: "匿名スレッド", Body: "本文", UserID: user.ID, Anonymous: true}
+					db.Create(&testThread)
+					url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments"
+					w := requestAPI(http.MethodPost, url, token, getCreateCommentRequestBodyBites("コメント"))
+					posterIds = append(posterIds, getCreateCommentResponse(w.Body.Bytes()).Comment.PosterID)
+				}
+
+				Expect(posterIds[0]).NotTo(Equal(posterIds[1]))
+			})
+		})
+
+		Context("モデレーターが閲覧した場合", func() {
+			It("実際の投稿者のユーザーIDが返る", func() {
+				testThread := model.Thread{Title: "匿名スレッド", Body: "本文", UserID: user.ID, Anonymous: true}
+				db.Create(&testThread)
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments"
+				created := getCreateCommentResponse(requestAPI(http.MethodPost, url, token, getCreateCommentRequestBodyBites("コメント")).Body.Bytes()).Comment
+
+				w := requestAPI(http.MethodGet, url+"/"+strconv.Itoa(int(created.ID)), getModeratorAuthToken(), nil)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				res := getCreateCommentResponse(w.Body.Bytes())
+				Expect(res.Comment.UserID).To(Equal(user.ID))
+				Expect(res.Comment.PosterID).To(Equal(created.PosterID))
+			})
+		})
+	})
 })
 
 func getCreateCommentRequestBodyBites(body string) []byte {
@@ -315,6 +700,13 @@ func getCreateCommentResponse(responseBody []byte) CommentCreateResponse {
 	return res
 }
 
+func getCommentListResponse(responseBody []byte) CommentListResponse {
+	var res CommentListResponse
+	json.Unmarshal(responseBody, &res)
+
+	return res
+}
+
 func getUpdateCommentResponse(responseBody []byte) CommentUpdateResponse {
 	var res CommentUpdateResponse
 	decoder := json.NewDecoder(bytes.NewReader(responseBody))
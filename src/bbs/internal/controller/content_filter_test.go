@@ -0,0 +1,199 @@
+package controller_test
+
+import (
+	"bbs/internal/model"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ContentFilter", func() {
+	BeforeEach(func() {
+		defaultBeforeEachFunc()
+	})
+
+	AfterEach(func() {
+		defaultAfterEachFunc()
+	})
+
+	Describe("禁止語", func() {
+		Context("拒否する語を含む場合", func() {
+			It("全角やひらがなで書かれていてもステータスコード422を返す", func() {
+				setContentFilterConfig(`{"bannedWords": [{"words": ["スパム", "casino"], "action": "reject"}]}`)
+
+				w := requestAPI(http.MethodPost, "/threads", token, getCreateThreadRequestBodyBites("お知らせ", "ｃａｓｉｎｏはこちら"))
+				Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+
+				w = requestAPI(http.MethodPost, "/threads", token, getCreateThreadRequestBodyBites("すぱむ", "本文"))
+				Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+
+				var count int64
+				db.Model(&model.Thread{}).Count(&count)
+				Expect(count).To(Equal(int64(0)))
+			})
+		})
+
+		Context("伏せ字にする語を含む場合", func() {
+			It("該当部分を伏せ字にして保存する", func() {
+				setContentFilterConfig(`{"bannedWords": [{"words": ["ばか"], "action": "mask"}]}`)
+
+				testThread := createTestThread(db, user.ID, 1)[0]
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments"
+				w := requestAPI(http.MethodPost, url, token, getCreateCommentRequestBodyBites("ﾊﾞｶなことを言うな"))
+
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				res := getCreateCommentResponse(w.Body.Bytes())
+				Expect(res.Comment.Body).To(Equal("***なことを言うな"))
+			})
+		})
+
+		Context("要確認とする語を含む場合", func() {
+			It("投稿したうえでモデレーションキューに載せる", func() {
+				setContentFilterConfig(`{"bannedWords": [{"words": ["副業"], "action": "flag"}]}`)
+
+				w := requestAPI(http.MethodPost, "/threads", token, getCreateThreadRequestBodyBites("副業の話", "本文"))
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				res := getThreadCreateResponseBody(w)
+
+				var report model.Report
+				Expect(db.Where("thread_id = ?", res.Thread.ID).First(&report).Error).To(BeNil())
+				Expect(report.ReporterID).To(Equal(model.SystemReporterID))
+				Expect(report.CommentID).To(BeNil())
+				Expect(report.Reason).To(Equal("banned word"))
+			})
+		})
+	})
+
+	Describe("編集", func() {
+		Context("拒否する語を含む内容に編集した場合", func() {
+			It("ステータスコード422を返し、内容を変更しない", func() {
+				setContentFilterConfig(`{"bannedWords": [{"words": ["casino"], "action": "reject"}]}`)
+
+				testThread := createTestThread(db, user.ID, 1)[0]
+				w := requestAPI(http.MethodPut, "/threads/"+strconv.Itoa(int(testThread.ID)), token, getUpdateThreadRequestBodyBites("", "ｃａｓｉｎｏはこちら"))
+				Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+
+				testComment := createTestComment(db, user.ID, 1)[0]
+				url := "/threads/" + strconv.Itoa(int(testComment.ThreadID)) + "/comments/" + strconv.Itoa(int(testComment.ID))
+				w = requestAPI(http.MethodPut, url, token, getUpdateCommentRequestBodyBites("casinoはこちら"))
+				Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+
+				var thread model.Thread
+				db.First(&thread, testThread.ID)
+				Expect(thread.Body).To(Equal(testThread.Body))
+				var comment model.Comment
+				db.First(&comment, testComment.ID)
+				Expect(comment.Body).To(Equal(testComment.Body))
+			})
+		})
+
+		Context("伏せ字にする語を含む内容に編集した場合", func() {
+			It("該当部分を伏せ字にして保存する", func() {
+				setContentFilterConfig(`{"bannedWords": [{"words": ["ばか"], "action": "mask"}]}`)
+
+				testComment := createTestComment(db, user.ID, 1)[0]
+				url := "/threads/" + strconv.Itoa(int(testComment.ThreadID)) + "/comments/" + strconv.Itoa(int(testComment.ID))
+				w := requestAPI(http.MethodPut, url, token, getUpdateCommentRequestBodyBites("ﾊﾞｶなことを言うな"))
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				var comment model.Comment
+				db.First(&comment, testComment.ID)
+				Expect(comment.Body).To(Equal("***なことを言うな"))
+			})
+		})
+
+		Context("要確認とする語を含む内容に編集した場合", func() {
+			It("編集したうえでモデレーションキューに載せる", func() {
+				setContentFilterConfig(`{"bannedWords": [{"words": ["副業"], "action": "flag"}]}`)
+
+				testComment := createTestComment(db, user.ID, 1)[0]
+				url := "/threads/" + strconv.Itoa(int(testComment.ThreadID)) + "/comments/" + strconv.Itoa(int(testComment.ID))
+				w := requestAPI(http.MethodPut, url, token, getUpdateCommentRequestBodyBites("副業の話"))
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				var report model.Report
+				Expect(db.Where("comment_id = ?", testComment.ID).First(&report).Error).To(BeNil())
+				Expect(report.ReporterID).To(Equal(model.SystemReporterID))
+				Expect(report.Reason).To(Equal("banned word"))
+			})
+		})
+
+		Context("連続投稿を拒否する設定の場合", func() {
+			It("作成直後でも編集できる", func() {
+				setContentFilterConfig(`{"duplicate": {"windowSeconds": 60, "action": "reject"}}`)
+
+				w := requestAPI(http.MethodPost, "/threads", token, getCreateThreadRequestBodyBites("タイトル", "本文"))
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				res := getThreadCreateResponseBody(w)
+
+				w = requestAPI(http.MethodPut, "/threads/"+strconv.Itoa(int(res.Thread.ID)), token, getUpdateThreadRequestBodyBites("新しいタイトル", "本文"))
+				Expect(w.Code).To(Equal(http.StatusOK))
+			})
+		})
+	})
+
+	Describe("リンク数の制限", func() {
+		Context("リンクが上限を超える場合", func() {
+			It("ステータスコード422を返す", func() {
+				setContentFilterConfig(`{"links": {"max": 1, "action": "reject"}}`)
+
+				testThread := createTestThread(db, user.ID, 1)[0]
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments"
+				w := requestAPI(http.MethodPost, url, token, getCreateCommentRequestBodyBites("https://example.com と ｈｔｔｐｓ://example.org"))
+
+				Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+				res := getCreateCommentResponse(w.Body.Bytes())
+				Expect(res.ErrorMessage).To(Equal("content is rejected: too many links"))
+			})
+		})
+	})
+
+	Describe("連続投稿", func() {
+		Context("一定時間内に同じ本文を投稿した場合", func() {
+			It("ステータスコード422を返す", func() {
+				setContentFilterConfig(`{"duplicate": {"windowSeconds": 60, "action": "reject"}}`)
+
+				testThread := createTestThread(db, user.ID, 1)[0]
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments"
+				w := requestAPI(http.MethodPost, url, token, getCreateCommentRequestBodyBites("同じ内容"))
+				Expect(w.Code).To(Equal(http.StatusCreated))
+
+				w = requestAPI(http.MethodPost, url, token, getCreateCommentRequestBodyBites("同じ内容"))
+				Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+	})
+
+	Describe("設定の再読み込み", func() {
+		Context("設定ファイルが更新された場合", func() {
+			It("新しいルールが適用される", func() {
+				path := setContentFilterConfig(`{"bannedWords": [{"words": ["foo"], "action": "reject"}]}`)
+
+				w := requestAPI(http.MethodPost, "/threads", token, getCreateThreadRequestBodyBites("bar", "本文"))
+				Expect(w.Code).To(Equal(http.StatusCreated))
+
+				Expect(os.WriteFile(path, []byte(`{"bannedWords": [{"words": ["bar"], "action": "reject"}]}`), 0o644)).To(BeNil())
+				modTime := time.Now().Add(time.Second)
+				Expect(os.Chtimes(path, modTime, modTime)).To(BeNil())
+
+				w = requestAPI(http.MethodPost, "/threads", token, getCreateThreadRequestBodyBites("bar", "本文"))
+				Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+	})
+})
+
+func setContentFilterConfig(config string) string {
+	path := filepath.Join(GinkgoT().TempDir(), "content_filter.json")
+	Expect(os.WriteFile(path, []byte(config), 0o644)).To(BeNil())
+
+	DeferCleanup(os.Setenv, "CONTENT_FILTER_CONFIG", os.Getenv("CONTENT_FILTER_CONFIG"))
+	os.Setenv("CONTENT_FILTER_CONFIG", path)
+
+	return path
+}
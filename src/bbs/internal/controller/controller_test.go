@@ -2,8 +2,12 @@ package controller_test
 
 import (
 	"bbs/internal/infra"
+	"bbs/internal/middleware"
 	"bbs/internal/model"
+	"bbs/internal/repository"
 	"bbs/internal/route"
+	"bbs/internal/service"
+	"bbs/internal/storage"
 	"bytes"
 	"encoding/json"
 	"net/http"
@@ -41,6 +45,12 @@ var db *gorm.DB
 
 var tmpDB *gorm.DB
 
+var st storage.Storage
+
+var tokenService service.ITokenService
+
+var threadService service.IThreadService
+
 var user *model.User
 
 var token string
@@ -59,6 +69,11 @@ func TestBbs(t *testing.T) {
 var _ = BeforeSuite(func() {
 	infra.TestInit(getEnvTestPath())
 	db = infra.SetUpDB()
+	Expect(infra.Migrate(db)).To(Succeed())
+	storageDir, err := os.MkdirTemp("", "bbs-storage")
+	Expect(err).To(BeNil())
+	st = storage.NewLocalStorage(storageDir)
+	DeferCleanup(os.RemoveAll, storageDir)
 	gin.SetMode(gin.TestMode)
 })
 
@@ -80,9 +95,26 @@ func setTransaction() {
 
 func setGinRoute() {
 	r = gin.New()
-	route.SetThreadRoute(r, db)
-	route.SetCommentRoute(r, db)
-	route.SetAuthRoute(r, db)
+	r.Use(middleware.QueryTimeoutMiddleware(middleware.QueryTimeout()))
+	tokenService = service.NewTokenService(repository.NewSigningKeyRepository(db))
+	jobService := service.NewJobService(repository.NewJobRepository(db))
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(db), jobService)
+	attachmentService := service.NewAttachmentService(repository.NewAttachmentRepository(db), st)
+	contentCheckService := service.NewContentCheckService(repository.NewPostRepository(db))
+	unitOfWork := repository.NewUnitOfWork(db)
+	threadService = service.NewThreadService(repository.NewThreadRepository(db), repository.NewRevisionRepository(db), repository.NewSubscriptionRepository(db), repository.NewBookmarkRepository(db), repository.NewPollRepository(db), attachmentService, contentCheckService, webhookService, unitOfWork)
+	commentService := service.NewCommentService(repository.NewCommentRepository(db), repository.NewThreadRepository(db), repository.NewRevisionRepository(db), repository.NewSubscriptionRepository(db), repository.NewBookmarkRepository(db), repository.NewAuthRepository(db), attachmentService, contentCheckService, webhookService, unitOfWork)
+	route.SetThreadRoute(r, db, threadService, tokenService)
+	route.SetCommentRoute(r, db, commentService, tokenService)
+	route.SetAuthRoute(r, db, tokenService)
+	route.SetAttachmentRoute(r, db, attachmentService, tokenService)
+	route.SetReportRoute(r, db, threadService, commentService, tokenService)
+	route.SetSanctionRoute(r, db, tokenService)
+	route.SetSubscriptionRoute(r, db, threadService, tokenService)
+	route.SetBookmarkRoute(r, db, threadService, commentService, tokenService)
+	route.SetPollRoute(r, db, threadService, tokenService)
+	route.SetWebhookRoute(r, db, webhookService, tokenService)
+	route.SetJobRoute(r, db, jobService, tokenService)
 }
 
 func setUserWithToken() {
@@ -198,3 +230,21 @@ func getOtherUserAuthToken() string {
 
 	return otherUserToken
 }
+
+func getModeratorAuthToken() string {
+	name := "moderator"
+	email := "moderator@example.com"
+	moderator := createTestUser(r, db, name, email)
+	db.Model(moderator).Update("role", model.RoleModerator)
+
+	return createTestUserToken(r, moderator.Email)
+}
+
+func getAdminAuthToken() string {
+	name := "admin"
+	email := "admin@example.com"
+	admin := createTestUser(r, db, name, email)
+	db.Model(admin).Update("role", model.RoleAdmin)
+
+	return createTestUserToken(r, admin.Email)
+}
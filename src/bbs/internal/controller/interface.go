@@ -7,6 +7,39 @@ import (
 type IAuthController interface {
 	Signup(ctx *gin.Context)
 	Login(ctx *gin.Context)
+	JWKS(ctx *gin.Context)
+}
+
+type ITwoFactorController interface {
+	Enroll(ctx *gin.Context)
+	Enable(ctx *gin.Context)
+	Disable(ctx *gin.Context)
+	FindStatus(ctx *gin.Context)
+	Login(ctx *gin.Context)
+}
+
+type IAPITokenController interface {
+	Create(ctx *gin.Context)
+	FindMine(ctx *gin.Context)
+	Revoke(ctx *gin.Context)
+}
+
+type IWebhookController interface {
+	Create(ctx *gin.Context)
+	FindAll(ctx *gin.Context)
+	Delete(ctx *gin.Context)
+	FindDeliveries(ctx *gin.Context)
+	Redeliver(ctx *gin.Context)
+}
+
+type IJobController interface {
+	FindAll(ctx *gin.Context)
+	Retry(ctx *gin.Context)
+}
+
+type IOIDCController interface {
+	Authorize(ctx *gin.Context)
+	Callback(ctx *gin.Context)
 }
 
 type ICommentController interface {
@@ -14,13 +47,59 @@ type ICommentController interface {
 	Update(ctx *gin.Context)
 	Delete(ctx *gin.Context)
 	FindByThreadId(ctx *gin.Context)
+	FindByUserId(ctx *gin.Context)
+	FindMine(ctx *gin.Context)
 	FindById(ctx *gin.Context)
+	FindRevisions(ctx *gin.Context)
+	RevertRevision(ctx *gin.Context)
 }
 
 type IThreadController interface {
 	Create(ctx *gin.Context)
 	Update(ctx *gin.Context)
+	UpdateState(ctx *gin.Context)
+	UpdateVisibility(ctx *gin.Context)
 	Delete(ctx *gin.Context)
 	FindAll(ctx *gin.Context)
+	FindMine(ctx *gin.Context)
+	FindParticipating(ctx *gin.Context)
 	FindById(ctx *gin.Context)
+	FindRevisions(ctx *gin.Context)
+	RevertRevision(ctx *gin.Context)
+}
+
+type IAttachmentController interface {
+	Upload(ctx *gin.Context)
+	Download(ctx *gin.Context)
+	Thumbnail(ctx *gin.Context)
+	Delete(ctx *gin.Context)
+}
+
+type IReportController interface {
+	Create(ctx *gin.Context)
+	FindQueue(ctx *gin.Context)
+	Resolve(ctx *gin.Context)
+}
+
+type ISanctionController interface {
+	Create(ctx *gin.Context)
+	FindByUserId(ctx *gin.Context)
+	Revoke(ctx *gin.Context)
+}
+
+type ISubscriptionController interface {
+	Subscribe(ctx *gin.Context)
+	Unsubscribe(ctx *gin.Context)
+	FindMine(ctx *gin.Context)
+}
+
+type IBookmarkController interface {
+	Save(ctx *gin.Context)
+	Delete(ctx *gin.Context)
+	FindMine(ctx *gin.Context)
+}
+
+type IPollController interface {
+	Vote(ctx *gin.Context)
+	Unvote(ctx *gin.Context)
 }
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"bbs/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JobController struct {
+	service service.IJobService
+}
+
+func NewJobController(service service.IJobService) IJobController {
+	return &JobController{service: service}
+}
+
+// statusで絞り込める(例: status=deadで失敗したジョブ)
+func (c *JobController) FindAll(ctx *gin.Context) {
+	limit, offset, err := parsePagination(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	jobs, err := c.service.FindAll(ctx.Request.Context(), ctx.Query("status"), limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": jobs})
+}
+
+// 失敗したジョブを再実行する
+func (c *JobController) Retry(ctx *gin.Context) {
+	jobId, err := strconv.ParseUint(ctx.Param("jobId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job id"})
+		return
+	}
+
+	job, err := c.service.Retry(ctx.Request.Context(), uint(jobId))
+	if err != nil {
+		if err.Error() == "job not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"data": job})
+}
@@ -0,0 +1,220 @@
+package controller_test
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"bbs/internal/service"
+	"bbs/internal/worker"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type JobListResponse struct {
+	Data dto.JobListOutput `json:"data"`
+}
+
+type JobResponse struct {
+	Data model.Job `json:"data"`
+}
+
+const testJobType = "test.job"
+
+var _ = Describe("JobController", func() {
+	var w *worker.Worker
+	var jobService service.IJobService
+
+	BeforeEach(func() {
+		defaultBeforeEachFunc()
+		w = worker.New(repository.NewJobRepository(db), 1)
+		jobService = service.NewJobService(repository.NewJobRepository(db))
+	})
+
+	AfterEach(func() {
+		defaultAfterEachFunc()
+	})
+
+	Describe("ジョブの実行", func() {
+		It("登録したジョブをペイロードとともにハンドラーに渡し、成功として記録する", func() {
+			var received map[string]int
+			w.Handle(testJobType, func(ctx context.Context, job *model.Job) error {
+				return json.Unmarshal([]byte(job.Payload), &received)
+			})
+			job, err := jobService.Enqueue(context.Background(), testJobType, map[string]int{"threadId": 1})
+			Expect(err).To(BeNil())
+
+			processed, err := w.RunOnce(context.Background())
+
+			Expect(err).To(BeNil())
+			Expect(processed).To(BeTrue())
+			Expect(received["threadId"]).To(Equal(1))
+			Expect(findJob(job.ID).Status).To(Equal(model.JobStatusSucceeded))
+
+			processed, _ = w.RunOnce(context.Background())
+			Expect(processed).To(BeFalse())
+		})
+
+		It("実行予定時刻になるまでは実行しない", func() {
+			w.Handle(testJobType, func(ctx context.Context, job *model.Job) error { return nil })
+			jobService.EnqueueAt(context.Background(), testJobType, nil, time.Now().Add(time.Hour))
+
+			processed, err := w.RunOnce(context.Background())
+
+			Expect(err).To(BeNil())
+			Expect(processed).To(BeFalse())
+		})
+
+		It("失敗した場合は間隔を空けて再実行し、上限に達した場合は失敗として残す", func() {
+			w.Handle(testJobType, func(ctx context.Context, job *model.Job) error { return errors.New("temporary error") })
+			job, _ := jobService.Enqueue(context.Background(), testJobType, nil)
+
+			w.RunOnce(context.Background())
+
+			retried := findJob(job.ID)
+			Expect(retried.Status).To(Equal(model.JobStatusPending))
+			Expect(retried.Attempts).To(Equal(1))
+			Expect(retried.LastError).To(Equal("temporary error"))
+			Expect(retried.RunAt.After(time.Now())).To(BeTrue())
+
+			for i := 1; i < retried.MaxAttempts; i++ {
+				db.Model(&model.Job{}).Where("id = ?", job.ID).Update("run_at", time.Now().Add(-time.Second))
+				processed, _ := w.RunOnce(context.Background())
+				Expect(processed).To(BeTrue())
+			}
+
+			dead := findJob(job.ID)
+			Expect(dead.Status).To(Equal(model.JobStatusDead))
+			Expect(dead.Attempts).To(Equal(dead.MaxAttempts))
+		})
+
+		It("ハンドラーがpanicした場合も失敗として扱う", func() {
+			w.Handle(testJobType, func(ctx context.Context, job *model.Job) error { panic("unexpected") })
+			job, _ := jobService.Enqueue(context.Background(), testJobType, nil)
+
+			_, err := w.RunOnce(context.Background())
+
+			Expect(err).To(BeNil())
+			Expect(findJob(job.ID).LastError).To(ContainSubstring("unexpected"))
+		})
+
+		It("実行中のまま残ったジョブを再実行できるようにする", func() {
+			job, _ := jobService.Enqueue(context.Background(), testJobType, nil)
+			db.Model(&model.Job{}).Where("id = ?", job.ID).Updates(map[string]interface{}{"status": model.JobStatusRunning, "locked_at": time.Now().Add(-time.Hour), "locked_by": "stopped-worker"})
+
+			Expect(w.Tick(context.Background(), time.Now())).To(BeNil())
+
+			Expect(findJob(job.ID).Status).To(Equal(model.JobStatusPending))
+		})
+	})
+
+	Describe("定期実行", func() {
+		It("予定時刻を過ぎた場合に1回だけジョブを登録する", func() {
+			Expect(w.Schedule(context.Background(), "test-schedule", "@every 1m", testJobType)).To(BeNil())
+			now := time.Now()
+
+			// 次回の予定は登録時に作成する
+			var schedules int64
+			db.Model(&model.JobSchedule{}).Where("name = ?", "test-schedule").Count(&schedules)
+			Expect(schedules).To(Equal(int64(1)))
+
+			Expect(w.Tick(context.Background(), now)).To(BeNil())
+			Expect(countJobs(testJobType)).To(BeZero())
+
+			Expect(w.Tick(context.Background(), now.Add(2*time.Minute))).To(BeNil())
+			Expect(w.Tick(context.Background(), now.Add(2*time.Minute))).To(BeNil())
+			Expect(countJobs(testJobType)).To(Equal(int64(1)))
+		})
+
+		It("不正な書式の場合はエラーを返す", func() {
+			Expect(w.Schedule(context.Background(), "test-schedule", "61 * * * *", testJobType)).NotTo(BeNil())
+		})
+	})
+
+	Describe("Webhookの配信", func() {
+		It("イベントの発生時に配信のジョブを登録し、ワーカーが送信する", func() {
+			receiver := newWebhookReceiver()
+			DeferCleanup(receiver.server.Close)
+			createWebhook(getAdminAuthToken(), receiver.server.URL, nil, model.WebhookEventThreadCreated)
+			Expect(worker.SetUp(context.Background(), w, db, threadService, tokenService)).To(BeNil())
+
+			body, _ := json.Marshal(dto.CreateThreadInput{Title: "テストタイトル", Body: "テスト本文"})
+			requestAPI(http.MethodPost, "/threads", token, body)
+			Expect(countJobs(model.JobTypeDeliverWebhooks)).To(Equal(int64(1)))
+
+			processed, err := w.RunOnce(context.Background())
+
+			Expect(err).To(BeNil())
+			Expect(processed).To(BeTrue())
+			Expect(receiver.requests()).To(HaveLen(1))
+		})
+	})
+
+	Describe("失敗したジョブの管理", func() {
+		var adminToken string
+		var job *model.Job
+
+		BeforeEach(func() {
+			adminToken = getAdminAuthToken()
+			job, _ = jobService.Enqueue(context.Background(), testJobType, nil)
+			db.Model(&model.Job{}).Where("id = ?", job.ID).Updates(map[string]interface{}{"status": model.JobStatusDead, "attempts": 5})
+		})
+
+		It("失敗したジョブを一覧で返す", func() {
+			jobService.Enqueue(context.Background(), testJobType, nil)
+
+			w := requestAPI(http.MethodGet, "/admin/jobs?status=dead", adminToken, nil)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			var res JobListResponse
+			json.Unmarshal(w.Body.Bytes(), &res)
+			Expect(res.Data.Total).To(Equal(int64(1)))
+			Expect(res.Data.Jobs[0].ID).To(Equal(job.ID))
+		})
+
+		It("再実行すると回数を数え直して実行待ちに戻す", func() {
+			res := requestAPI(http.MethodPost, fmt.Sprintf("/admin/jobs/%d/retry", job.ID), adminToken, nil)
+			Expect(res.Code).To(Equal(http.StatusAccepted))
+
+			w.Handle(testJobType, func(ctx context.Context, job *model.Job) error { return nil })
+			processed, _ := w.RunOnce(context.Background())
+
+			Expect(processed).To(BeTrue())
+			revived := findJob(job.ID)
+			Expect(revived.Status).To(Equal(model.JobStatusSucceeded))
+			Expect(revived.Attempts).To(Equal(1))
+		})
+
+		It("失敗していないジョブの場合は404エラーを返す", func() {
+			pending, _ := jobService.Enqueue(context.Background(), testJobType, nil)
+
+			res := requestAPI(http.MethodPost, fmt.Sprintf("/admin/jobs/%d/retry", pending.ID), adminToken, nil)
+
+			Expect(res.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("管理者以外の場合は403エラーを返す", func() {
+			res := requestAPI(http.MethodGet, "/admin/jobs", token, nil)
+
+			Expect(res.Code).To(Equal(http.StatusForbidden))
+		})
+	})
+})
+
+func findJob(id uint) model.Job {
+	var job model.Job
+	Expect(db.First(&job, id).Error).To(BeNil())
+	return job
+}
+
+func countJobs(jobType string) int64 {
+	var count int64
+	db.Model(&model.Job{}).Where("type = ?", jobType).Count(&count)
+	return count
+}
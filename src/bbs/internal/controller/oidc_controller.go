@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/service"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 認可リクエストを開始したブラウザにstateを紐づけるクッキー
+const (
+	oidcStateCookieName = "oidc_state"
+	oidcStateCookiePath = "/auth/oidc"
+)
+
+type OIDCController struct {
+	service service.IOIDCService
+}
+
+func NewOIDCController(service service.IOIDCService) IOIDCController {
+	return &OIDCController{service: service}
+}
+
+func (c *OIDCController) Authorize(ctx *gin.Context) {
+	output, err := c.service.Authorize(ctx.Request.Context(), ctx.Param("provider"))
+	if err != nil {
+		if err.Error() == "provider not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookieName, output.State, int(service.OIDCStateTTL.Seconds()), oidcStateCookiePath, "", secureCookie(), true)
+	ctx.JSON(http.StatusOK, gin.H{"data": output})
+}
+
+func (c *OIDCController) Callback(ctx *gin.Context) {
+	var input dto.OIDCCallbackInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 別のブラウザで開始された認可リクエストのstateは受け付けない(ログインCSRF対策)
+	stateCookie, err := ctx.Cookie(oidcStateCookieName)
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookieName, "", -1, oidcStateCookiePath, "", secureCookie(), true)
+	if err != nil || subtle.ConstantTimeCompare([]byte(stateCookie), []byte(input.State)) != 1 {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid state"})
+		return
+	}
+
+	output, err := c.service.Callback(ctx.Request.Context(), ctx.Param("provider"), input)
+	if err != nil {
+		if err.Error() == "provider not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "invalid state" || errors.Is(err, service.ErrOIDCAuthentication) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "email is not verified" || errors.Is(err, service.ErrUserBanned) {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, output)
+}
+
+// ローカルの開発環境とテスト以外ではHTTPSでのみクッキーを送る
+func secureCookie() bool {
+	env := os.Getenv("ENV")
+	return env != "develop" && env != "test"
+}
@@ -0,0 +1,218 @@
+package controller_test
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/oidc"
+	"bbs/internal/oidc/oidctest"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type OIDCAuthorizeResponse struct {
+	Data dto.OIDCAuthorizeOutput `json:"data"`
+}
+
+var _ = Describe("OIDCController", func() {
+	var provider *oidctest.Provider
+
+	BeforeEach(func() {
+		defaultBeforeEachFunc()
+		provider = oidctest.NewProvider()
+		DeferCleanup(provider.Close)
+		setOIDCConfig(provider.URL())
+		setGinRoute()
+	})
+
+	AfterEach(func() {
+		defaultAfterEachFunc()
+	})
+
+	Describe("OIDCでのログイン", func() {
+		Context("初めてログインした場合", func() {
+			It("ユーザーを登録し、認証に使えるトークンを返す", func() {
+				provider.SetUser(oidc.Claims{Subject: "new-user", Email: "new@example.com", EmailVerified: true, Name: "new"})
+
+				w := oidcLogin()
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				var res loginResponseBody
+				json.Unmarshal(w.Body.Bytes(), &res)
+				Expect(res.Token).NotTo(BeNil())
+
+				w = requestAPI(http.MethodGet, "/me/threads", *res.Token, nil)
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				var newUser model.User
+				Expect(db.First(&newUser, "email = ?", "new@example.com").Error).To(BeNil())
+				Expect(newUser.Name).To(Equal("new"))
+			})
+		})
+
+		Context("確認済みのメールアドレスが既存のユーザーと一致する場合", func() {
+			It("既存のユーザーに紐づけ、以降はメールアドレスが変わっても同じユーザーとしてログインする", func() {
+				provider.SetUser(oidc.Claims{Subject: "existing-user", Email: user.Email, EmailVerified: true})
+
+				w := oidcLogin()
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				var identity model.UserIdentity
+				Expect(db.First(&identity, "subject = ?", "existing-user").Error).To(BeNil())
+				Expect(identity.UserID).To(Equal(user.ID))
+
+				provider.SetUser(oidc.Claims{Subject: "existing-user", Email: "changed@example.com", EmailVerified: true})
+
+				w = oidcLogin()
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				var count int64
+				db.Model(&model.User{}).Where("email = ?", "changed@example.com").Count(&count)
+				Expect(count).To(BeZero())
+			})
+		})
+
+		Context("メールアドレスが確認されていない場合", func() {
+			It("ステータスコード403を返す", func() {
+				provider.SetUser(oidc.Claims{Subject: "unverified-user", Email: user.Email, EmailVerified: false})
+
+				w := oidcLogin()
+				Expect(w.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+
+		Context("同じstateを2回使った場合", func() {
+			It("ステータスコード401を返す", func() {
+				provider.SetUser(oidc.Claims{Subject: "new-user", Email: "new@example.com", EmailVerified: true})
+
+				code, state, stateCookie := authorizeWithProvider()
+				w := requestOIDCCallback(code, state, stateCookie)
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				w = requestOIDCCallback(code, state, stateCookie)
+				Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Context("stateのクッキーがない場合", func() {
+			It("ステータスコード401を返し、ログインしない", func() {
+				provider.SetUser(oidc.Claims{Subject: "new-user", Email: "new@example.com", EmailVerified: true})
+
+				code, state, _ := authorizeWithProvider()
+				w := requestOIDCCallback(code, state, nil)
+				Expect(w.Code).To(Equal(http.StatusUnauthorized))
+
+				var count int64
+				db.Model(&model.User{}).Where("email = ?", "new@example.com").Count(&count)
+				Expect(count).To(BeZero())
+			})
+		})
+
+		Context("別のブラウザで開始された認可リクエストのstateの場合", func() {
+			It("ステータスコード401を返す", func() {
+				provider.SetUser(oidc.Claims{Subject: "new-user", Email: "new@example.com", EmailVerified: true})
+
+				code, state, _ := authorizeWithProvider()
+				_, _, otherCookie := authorizeWithProvider()
+				w := requestOIDCCallback(code, state, otherCookie)
+				Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Context("認可リクエストを開始した場合", func() {
+			It("stateをHttpOnlyのクッキーに保存する", func() {
+				_, state, stateCookie := authorizeWithProvider()
+
+				Expect(stateCookie.Value).To(Equal(state))
+				Expect(stateCookie.HttpOnly).To(BeTrue())
+				Expect(stateCookie.SameSite).To(Equal(http.SameSiteLaxMode))
+			})
+		})
+
+		Context("設定されていないプロバイダの場合", func() {
+			It("ステータスコード404を返す", func() {
+				w := requestAPI(http.MethodGet, "/auth/oidc/unknown/authorize", "", nil)
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+})
+
+func setOIDCConfig(issuerURL string) {
+	config := map[string]interface{}{
+		"providers": []map[string]string{{
+			"name":        "mock",
+			"issuerUrl":   issuerURL,
+			"clientId":    "bbs",
+			"redirectUrl": "http://localhost:3000/auth/callback",
+		}},
+	}
+	data, _ := json.Marshal(config)
+	path := filepath.Join(GinkgoT().TempDir(), "oidc.json")
+	Expect(os.WriteFile(path, data, 0o644)).To(BeNil())
+
+	DeferCleanup(os.Setenv, "OIDC_CONFIG", os.Getenv("OIDC_CONFIG"))
+	os.Setenv("OIDC_CONFIG", path)
+}
+
+// 認可URLを開き、プロバイダからリダイレクトされたURLのcodeとstateと、ブラウザに保存されたstateのクッキーを返す
+func authorizeWithProvider() (string, string, *http.Cookie) {
+	w := requestAPI(http.MethodGet, "/auth/oidc/mock/authorize", "", nil)
+	Expect(w.Code).To(Equal(http.StatusOK))
+
+	var stateCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "oidc_state" {
+			stateCookie = cookie
+		}
+	}
+	Expect(stateCookie).NotTo(BeNil())
+
+	var res OIDCAuthorizeResponse
+	json.Unmarshal(w.Body.Bytes(), &res)
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(res.Data.AuthorizationURL)
+	Expect(err).To(BeNil())
+	resp.Body.Close()
+	Expect(resp.StatusCode).To(Equal(http.StatusFound))
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	Expect(err).To(BeNil())
+	Expect(location.Query().Get("state")).To(Equal(res.Data.State))
+
+	return location.Query().Get("code"), location.Query().Get("state"), stateCookie
+}
+
+func oidcLogin() *httptest.ResponseRecorder {
+	code, state, stateCookie := authorizeWithProvider()
+	return requestOIDCCallback(code, state, stateCookie)
+}
+
+func requestOIDCCallback(code string, state string, stateCookie *http.Cookie) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/auth/oidc/mock/callback", bytes.NewBuffer(getOIDCCallbackRequestBodyBites(code, state)))
+	req.Header.Set("Content-Type", contentType)
+	if stateCookie != nil {
+		req.AddCookie(&http.Cookie{Name: stateCookie.Name, Value: stateCookie.Value})
+	}
+	r.ServeHTTP(w, req)
+
+	return w
+}
+
+func getOIDCCallbackRequestBodyBites(code string, state string) []byte {
+	request := dto.OIDCCallbackInput{Code: code, State: state}
+	requestBytes, _ := json.Marshal(request)
+
+	return requestBytes
+}
@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultLimit = 10
+
+// クエリのpage(1始まり)とlimitから、リポジトリに渡すlimitとoffset(0始まりのページ番号)を返す
+func parsePagination(ctx *gin.Context) (int, int, error) {
+	limit := defaultLimit
+	if limitQuery := ctx.Query("limit"); limitQuery != "" {
+		limitInt, err := strconv.Atoi(limitQuery)
+		if err != nil {
+			return 0, 0, err
+		}
+		limit = limitInt
+	}
+
+	offset := 0
+	if pageQuery := ctx.Query("page"); pageQuery != "" {
+		pageInt, err := strconv.Atoi(pageQuery)
+		if err != nil {
+			return 0, 0, err
+		}
+		// offsetは0始まりでpageは1始まりなので1を引く
+		offset = pageInt - 1
+	}
+
+	return limit, offset, nil
+}
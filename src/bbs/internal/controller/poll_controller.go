@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PollController struct {
+	service service.IPollService
+}
+
+func NewPollController(service service.IPollService) IPollController {
+	return &PollController{service: service}
+}
+
+func (c *PollController) Vote(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	threadId, err := strconv.ParseUint(ctx.Param("threadId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread id"})
+		return
+	}
+
+	var input dto.VotePollInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	poll, err := c.service.Vote(ctx.Request.Context(), uint(threadId), input, userId)
+	if err != nil {
+		if err.Error() == "thread not found" || err.Error() == "poll not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "poll allows only one choice" || err.Error() == "invalid poll option" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "thread is locked" || err.Error() == "thread is archived" || err.Error() == "poll is closed" {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "already voted" {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": poll})
+}
+
+func (c *PollController) Unvote(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	threadId, err := strconv.ParseUint(ctx.Param("threadId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread id"})
+		return
+	}
+
+	poll, err := c.service.Unvote(ctx.Request.Context(), uint(threadId), userId)
+	if err != nil {
+		if err.Error() == "thread not found" || err.Error() == "poll not found" || err.Error() == "vote not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "thread is locked" || err.Error() == "thread is archived" || err.Error() == "poll is closed" {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": poll})
+}
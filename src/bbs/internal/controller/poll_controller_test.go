@@ -0,0 +1,195 @@
+package controller_test
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+)
+
+type PollResponse struct {
+	Poll         model.Poll `json:"data"`
+	ErrorMessage string     `json:"error"`
+}
+
+var _ = Describe("PollController", func() {
+	BeforeEach(func() {
+		defaultBeforeEachFunc()
+	})
+
+	AfterEach(func() {
+		defaultAfterEachFunc()
+	})
+
+	Describe("投票", func() {
+		Context("投票付きのスレッドを作成した場合", func() {
+			It("スレッドの詳細に選択肢が含まれる", func() {
+				thread := createTestPollThread(dto.CreatePollInput{Question: "昼食は?", Options: []string{"和食", "洋食", "中華"}})
+
+				Expect(thread.Poll).NotTo(BeNil())
+				Expect(thread.Poll.Question).To(Equal("昼食は?"))
+				Expect(len(thread.Poll.Options)).To(Equal(3))
+				Expect(thread.Poll.Options[1].Label).To(Equal("洋食"))
+			})
+
+			It("締め切りが過去の場合はステータスコード400を返す", func() {
+				closesAt := time.Now().Add(-time.Hour)
+				request := getCreatePollThreadRequestBodyBites(dto.CreatePollInput{Question: "昼食は?", Options: []string{"和食", "洋食"}, ClosesAt: &closesAt})
+
+				w := requestAPI(http.MethodPost, "/threads", token, request)
+				Expect(w.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("投票の作成に失敗した場合", func() {
+			It("スレッドと購読もロールバックされる", func() {
+				db.Callback().Create().Before("gorm:create").Register("test:fail_poll", func(tx *gorm.DB) {
+					if tx.Statement.Table == "polls" {
+						tx.AddError(errors.New("poll insert failed"))
+					}
+				})
+				DeferCleanup(func() {
+					db.Callback().Create().Remove("test:fail_poll")
+				})
+
+				request := getCreatePollThreadRequestBodyBites(dto.CreatePollInput{Question: "昼食は?", Options: []string{"和食", "洋食"}})
+				w := requestAPI(http.MethodPost, "/threads", token, request)
+				Expect(w.Code).To(Equal(http.StatusInternalServerError))
+
+				var threadCount, subscriptionCount int64
+				db.Model(&model.Thread{}).Where("user_id = ?", user.ID).Count(&threadCount)
+				db.Model(&model.Subscription{}).Where("user_id = ?", user.ID).Count(&subscriptionCount)
+				Expect(threadCount).To(BeZero())
+				Expect(subscriptionCount).To(BeZero())
+			})
+		})
+
+		Context("投票した場合", func() {
+			It("集計と自分の選択が返る", func() {
+				thread := createTestPollThread(dto.CreatePollInput{Question: "昼食は?", Options: []string{"和食", "洋食"}, PublicVotes: true})
+				option := thread.Poll.Options[0]
+
+				w := requestAPI(http.MethodPost, votesURL(thread.ID), token, getVoteRequestBodyBites(option.ID))
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				w = requestAPI(http.MethodPost, votesURL(thread.ID), getOtherUserAuthToken(), getVoteRequestBodyBites(option.ID))
+				res := getPollResponse(w)
+
+				Expect(res.Poll.TotalVoters).To(Equal(int64(2)))
+				Expect(res.Poll.Options[0].VoteCount).To(Equal(int64(2)))
+				Expect(res.Poll.Options[0].Voters).To(ContainElement(user.ID))
+				Expect(res.Poll.Options[1].VoteCount).To(BeZero())
+				Expect(res.Poll.MyChoices).To(Equal([]uint{option.ID}))
+			})
+
+			It("投票者を公開しない場合は投票者を返さない", func() {
+				thread := createTestPollThread(dto.CreatePollInput{Question: "昼食は?", Options: []string{"和食", "洋食"}})
+
+				res := getPollResponse(requestAPI(http.MethodPost, votesURL(thread.ID), token, getVoteRequestBodyBites(thread.Poll.Options[0].ID)))
+
+				Expect(res.Poll.Options[0].VoteCount).To(Equal(int64(1)))
+				Expect(res.Poll.Options[0].Voters).To(BeEmpty())
+			})
+
+			It("2回目の投票はステータスコード409を返し、取り消した後は投票し直せる", func() {
+				thread := createTestPollThread(dto.CreatePollInput{Question: "昼食は?", Options: []string{"和食", "洋食"}})
+				options := thread.Poll.Options
+				requestAPI(http.MethodPost, votesURL(thread.ID), token, getVoteRequestBodyBites(options[0].ID))
+
+				w := requestAPI(http.MethodPost, votesURL(thread.ID), token, getVoteRequestBodyBites(options[1].ID))
+				Expect(w.Code).To(Equal(http.StatusConflict))
+
+				w = requestAPI(http.MethodDelete, votesURL(thread.ID), token, nil)
+				Expect(w.Code).To(Equal(http.StatusOK))
+				Expect(getPollResponse(w).Poll.TotalVoters).To(BeZero())
+
+				w = requestAPI(http.MethodPost, votesURL(thread.ID), token, getVoteRequestBodyBites(options[1].ID))
+				Expect(w.Code).To(Equal(http.StatusOK))
+				Expect(getPollResponse(w).Poll.Options[1].VoteCount).To(Equal(int64(1)))
+			})
+
+			It("複数選択できない投票で複数の選択肢を選ぶとステータスコード400を返す", func() {
+				thread := createTestPollThread(dto.CreatePollInput{Question: "昼食は?", Options: []string{"和食", "洋食"}})
+
+				w := requestAPI(http.MethodPost, votesURL(thread.ID), token, getVoteRequestBodyBites(thread.Poll.Options[0].ID, thread.Poll.Options[1].ID))
+				Expect(w.Code).To(Equal(http.StatusBadRequest))
+			})
+
+			It("複数選択できる投票では複数の選択肢に投票できる", func() {
+				thread := createTestPollThread(dto.CreatePollInput{Question: "参加できる日は?", Options: []string{"月", "火", "水"}, MultipleChoice: true})
+
+				w := requestAPI(http.MethodPost, votesURL(thread.ID), token, getVoteRequestBodyBites(thread.Poll.Options[0].ID, thread.Poll.Options[2].ID))
+				res := getPollResponse(w)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				Expect(res.Poll.TotalVoters).To(Equal(int64(1)))
+				Expect(res.Poll.Options[0].VoteCount).To(Equal(int64(1)))
+				Expect(res.Poll.Options[2].VoteCount).To(Equal(int64(1)))
+			})
+		})
+
+		Context("締め切り後の場合", func() {
+			It("ステータスコード403を返す", func() {
+				thread := createTestPollThread(dto.CreatePollInput{Question: "昼食は?", Options: []string{"和食", "洋食"}})
+				db.Model(&model.Poll{}).Where("id = ?", thread.Poll.ID).Update("closes_at", time.Now().Add(-time.Minute))
+
+				w := requestAPI(http.MethodPost, votesURL(thread.ID), token, getVoteRequestBodyBites(thread.Poll.Options[0].ID))
+				Expect(w.Code).To(Equal(http.StatusForbidden))
+				Expect(getPollResponse(w).ErrorMessage).To(Equal("poll is closed"))
+			})
+		})
+
+		Context("投票のないスレッドの場合", func() {
+			It("ステータスコード404を返す", func() {
+				thread := createTestThread(db, user.ID, 1)[0]
+
+				w := requestAPI(http.MethodPost, votesURL(thread.ID), token, getVoteRequestBodyBites(1))
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+})
+
+func createTestPollThread(poll dto.CreatePollInput) model.Thread {
+	w := requestAPI(http.MethodPost, "/threads", token, getCreatePollThreadRequestBodyBites(poll))
+
+	return getThreadCreateResponseBody(w).Thread
+}
+
+func getCreatePollThreadRequestBodyBites(poll dto.CreatePollInput) []byte {
+	request := dto.CreateThreadInput{
+		Title: "テスト",
+		Body:  "テストテスト",
+		Poll:  &poll,
+	}
+	requestBytes, _ := json.Marshal(request)
+
+	return requestBytes
+}
+
+func votesURL(threadId uint) string {
+	return "/threads/" + strconv.Itoa(int(threadId)) + "/poll/votes"
+}
+
+func getVoteRequestBodyBites(optionIds ...uint) []byte {
+	requestBytes, _ := json.Marshal(dto.VotePollInput{OptionIDs: optionIds})
+
+	return requestBytes
+}
+
+func getPollResponse(w *httptest.ResponseRecorder) PollResponse {
+	var res PollResponse
+	decoder := json.NewDecoder(bytes.NewReader(w.Body.Bytes()))
+	decoder.Decode(&res)
+
+	return res
+}
@@ -0,0 +1,59 @@
+package controller_test
+
+import (
+	"bbs/internal/middleware"
+	"bbs/internal/route"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QueryTimeoutMiddleware", func() {
+	BeforeEach(func() {
+		defaultBeforeEachFunc()
+	})
+
+	AfterEach(func() {
+		defaultAfterEachFunc()
+	})
+
+	Context("期限内に問い合わせが終わる場合", func() {
+		It("ステータスコード200が返る", func() {
+			createTestThread(db, user.ID, 1)
+
+			w := requestAPI(http.MethodGet, "/threads", token, nil)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("問い合わせの前に期限が切れている場合", func() {
+		It("問い合わせが打ち切られ500エラーが返る", func() {
+			createTestThread(db, user.ID, 1)
+
+			r = gin.New()
+			r.Use(middleware.QueryTimeoutMiddleware(time.Nanosecond))
+			route.SetThreadRoute(r, db, threadService, tokenService)
+
+			w := requestAPI(http.MethodGet, "/threads", "", nil)
+
+			Expect(w.Code).To(Equal(http.StatusInternalServerError))
+		})
+	})
+
+	Context("期限が0の場合", func() {
+		It("期限を設定せずに処理する", func() {
+			r = gin.New()
+			r.Use(middleware.QueryTimeoutMiddleware(0))
+			route.SetThreadRoute(r, db, threadService, tokenService)
+
+			w := requestAPI(http.MethodGet, "/threads", "", nil)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+		})
+	})
+})
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportController struct {
+	service service.IReportService
+}
+
+func NewReportController(service service.IReportService) IReportController {
+	return &ReportController{service: service}
+}
+
+// スレッドとコメントのどちらの通報にも使う。commentIdがないURLの場合はスレッドへの通報
+func (c *ReportController) Create(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	threadId, commentId, ok := parsePostTarget(ctx)
+	if !ok {
+		return
+	}
+
+	var input dto.CreateReportInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := c.service.Create(ctx.Request.Context(), input, threadId, commentId, userId)
+	if err != nil {
+		if err.Error() == "thread not found" || err.Error() == "comment not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "already reported" {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": report})
+}
+
+func (c *ReportController) FindQueue(ctx *gin.Context) {
+	limit, offset, err := parsePagination(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	queue, err := c.service.FindQueue(ctx.Request.Context(), limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": queue})
+}
+
+func (c *ReportController) Resolve(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	threadId, commentId, ok := parsePostTarget(ctx)
+	if !ok {
+		return
+	}
+
+	var input dto.ResolveReportInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := c.service.Resolve(ctx.Request.Context(), input, threadId, commentId, userId)
+	if err != nil {
+		if err.Error() == "report not found" || err.Error() == "thread not found" || err.Error() == "comment not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// URLのthreadIdとcommentIdを読み取る。commentIdがないURLの場合はnilを返す
+func parsePostTarget(ctx *gin.Context) (uint, *uint, bool) {
+	threadId, err := strconv.ParseUint(ctx.Param("threadId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread id"})
+		return 0, nil, false
+	}
+
+	if ctx.Param("commentId") == "" {
+		return uint(threadId), nil, true
+	}
+
+	commentId, err := strconv.ParseUint(ctx.Param("commentId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment id"})
+		return 0, nil, false
+	}
+
+	id := uint(commentId)
+	return uint(threadId), &id, true
+}
@@ -0,0 +1,254 @@
+package controller_test
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+)
+
+type ReportCreateRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ReportResolveRequest struct {
+	Action string `json:"action"`
+}
+
+type ModerationQueueResponse struct {
+	Queue dto.ModerationQueueOutput `json:"data"`
+}
+
+var _ = Describe("ReportController", func() {
+	BeforeEach(func() {
+		defaultBeforeEachFunc()
+	})
+
+	AfterEach(func() {
+		defaultAfterEachFunc()
+	})
+
+	Describe("通報", func() {
+		Context("スレッドを通報した場合", func() {
+			It("ステータスコード201を返す", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/reports"
+				w := requestAPI(http.MethodPost, url, token, getReportCreateRequestBodyBites("スパム"))
+
+				Expect(w.Code).To(Equal(http.StatusCreated))
+			})
+
+			It("同じユーザーが再度通報した場合は409を返す", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/reports"
+				requestAPI(http.MethodPost, url, token, getReportCreateRequestBodyBites("スパム"))
+				w := requestAPI(http.MethodPost, url, token, getReportCreateRequestBodyBites("スパム"))
+
+				Expect(w.Code).To(Equal(http.StatusConflict))
+			})
+		})
+
+		Context("コメントが存在しない場合", func() {
+			It("ステータスコード404を返す", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/comments/0/reports"
+				w := requestAPI(http.MethodPost, url, token, getReportCreateRequestBodyBites("スパム"))
+
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("通報数がしきい値に達した場合", func() {
+			It("スレッドが自動で非表示になる", func() {
+				DeferCleanup(os.Setenv, "REPORT_HIDE_THRESHOLD", os.Getenv("REPORT_HIDE_THRESHOLD"))
+				os.Setenv("REPORT_HIDE_THRESHOLD", "2")
+
+				testThread := createTestThread(db, user.ID, 1)[0]
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID))
+				requestAPI(http.MethodPost, url+"/reports", token, getReportCreateRequestBodyBites("スパム"))
+				requestAPI(http.MethodPost, url+"/reports", getOtherUserAuthToken(), getReportCreateRequestBodyBites("荒らし"))
+
+				w := requestAPI(http.MethodGet, url, "", nil)
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+
+				body := getThreadListResponseBody(requestAPI(http.MethodGet, "/threads", "", nil))
+				Expect(body.Data.Total).To(Equal(int64(0)))
+			})
+		})
+	})
+
+	Describe("モデレーションキュー", func() {
+		Context("モデレーターの場合", func() {
+			It("対象ごとにまとめた通報を返す", func() {
+				testComment := createTestComment(db, user.ID, 1)[0]
+				moderatorToken := getModeratorAuthToken()
+
+				url := "/threads/" + strconv.Itoa(int(testComment.ThreadID)) + "/comments/" + strconv.Itoa(int(testComment.ID)) + "/reports"
+				requestAPI(http.MethodPost, url, token, getReportCreateRequestBodyBites("スパム"))
+				requestAPI(http.MethodPost, url, getOtherUserAuthToken(), getReportCreateRequestBodyBites("荒らし"))
+
+				w := requestAPI(http.MethodGet, "/moderation/reports", moderatorToken, nil)
+				res := getModerationQueueResponse(w)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				Expect(res.Queue.Total).To(Equal(int64(1)))
+				Expect(res.Queue.Items[0].ThreadID).To(Equal(testComment.ThreadID))
+				Expect(*res.Queue.Items[0].CommentID).To(Equal(testComment.ID))
+				Expect(res.Queue.Items[0].ReportCount).To(Equal(int64(2)))
+				Expect(res.Queue.Items[0].Comment.Body).To(Equal(testComment.Body))
+			})
+
+			It("スレッドとコメントへの通報をそれぞれの対象にまとめる", func() {
+				testComment := createTestComment(db, user.ID, 1)[0]
+				moderatorToken := getModeratorAuthToken()
+
+				threadUrl := "/threads/" + strconv.Itoa(int(testComment.ThreadID))
+				requestAPI(http.MethodPost, threadUrl+"/reports", getOtherUserAuthToken(), getReportCreateRequestBodyBites("スレッドへの通報"))
+				requestAPI(http.MethodPost, threadUrl+"/comments/"+strconv.Itoa(int(testComment.ID))+"/reports", token, getReportCreateRequestBodyBites("コメントへの通報"))
+
+				res := getModerationQueueResponse(requestAPI(http.MethodGet, "/moderation/reports", moderatorToken, nil))
+
+				Expect(res.Queue.Total).To(Equal(int64(2)))
+				commentItem, threadItem := res.Queue.Items[0], res.Queue.Items[1]
+				Expect(*commentItem.CommentID).To(Equal(testComment.ID))
+				Expect(commentItem.Comment.ID).To(Equal(testComment.ID))
+				Expect(len(commentItem.Reports)).To(Equal(1))
+				Expect(commentItem.Reports[0].Reason).To(Equal("コメントへの通報"))
+				Expect(threadItem.CommentID).To(BeNil())
+				Expect(threadItem.Comment).To(BeNil())
+				Expect(threadItem.Thread.ID).To(Equal(testComment.ThreadID))
+				Expect(len(threadItem.Reports)).To(Equal(1))
+				Expect(threadItem.Reports[0].Reason).To(Equal("スレッドへの通報"))
+			})
+		})
+
+		Context("モデレーターではない場合", func() {
+			It("ステータスコード403を返す", func() {
+				w := requestAPI(http.MethodGet, "/moderation/reports", token, nil)
+
+				Expect(w.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+	})
+
+	Describe("通報への対応", func() {
+		Context("コメントを非表示にした場合", func() {
+			It("コメントが取得できなくなりキューから消える", func() {
+				testComment := createTestComment(db, user.ID, 1)[0]
+				moderatorToken := getModeratorAuthToken()
+
+				threadUrl := "/threads/" + strconv.Itoa(int(testComment.ThreadID))
+				requestAPI(http.MethodPost, threadUrl+"/comments/"+strconv.Itoa(int(testComment.ID))+"/reports", token, getReportCreateRequestBodyBites("スパム"))
+
+				url := "/moderation/reports" + threadUrl + "/comments/" + strconv.Itoa(int(testComment.ID))
+				w := requestAPI(http.MethodPut, url, moderatorToken, getReportResolveRequestBodyBites("hide"))
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				var dbComment model.Comment
+				db.First(&dbComment, testComment.ID)
+				Expect(dbComment.Hidden).To(BeTrue())
+
+				res := getModerationQueueResponse(requestAPI(http.MethodGet, "/moderation/reports", moderatorToken, nil))
+				Expect(res.Queue.Total).To(Equal(int64(0)))
+			})
+		})
+
+		Context("スレッドを削除した場合", func() {
+			It("スレッドが削除される", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+				moderatorToken := getModeratorAuthToken()
+
+				threadUrl := "/threads/" + strconv.Itoa(int(testThread.ID))
+				requestAPI(http.MethodPost, threadUrl+"/reports", getOtherUserAuthToken(), getReportCreateRequestBodyBites("スパム"))
+
+				w := requestAPI(http.MethodPut, "/moderation/reports"+threadUrl, moderatorToken, getReportResolveRequestBodyBites("delete"))
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				var deletedThread model.Thread
+				result := db.First(&deletedThread, testThread.ID)
+				Expect(errors.Is(result.Error, gorm.ErrRecordNotFound)).To(BeTrue())
+			})
+		})
+
+		Context("自動で非表示になった通報を却下した場合", func() {
+			It("スレッドが再び表示される", func() {
+				DeferCleanup(os.Setenv, "REPORT_HIDE_THRESHOLD", os.Getenv("REPORT_HIDE_THRESHOLD"))
+				os.Setenv("REPORT_HIDE_THRESHOLD", "1")
+
+				testThread := createTestThread(db, user.ID, 1)[0]
+				moderatorToken := getModeratorAuthToken()
+
+				threadUrl := "/threads/" + strconv.Itoa(int(testThread.ID))
+				requestAPI(http.MethodPost, threadUrl+"/reports", token, getReportCreateRequestBodyBites("スパム"))
+				Expect(requestAPI(http.MethodGet, threadUrl, "", nil).Code).To(Equal(http.StatusNotFound))
+
+				requestAPI(http.MethodPut, "/moderation/reports"+threadUrl, moderatorToken, getReportResolveRequestBodyBites("dismiss"))
+
+				Expect(requestAPI(http.MethodGet, threadUrl, "", nil).Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("モデレーターが非表示にした対象への通報を却下した場合", func() {
+			It("非表示のまま変わらない", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+				moderatorToken := getModeratorAuthToken()
+
+				db.Model(&model.Thread{}).Where("id = ?", testThread.ID).Update("hidden", true)
+				db.Create(&model.Report{ReporterID: user.ID, ThreadID: testThread.ID, Reason: "スパム", Status: model.ReportStatusOpen})
+
+				threadUrl := "/threads/" + strconv.Itoa(int(testThread.ID))
+				w := requestAPI(http.MethodPut, "/moderation/reports"+threadUrl, moderatorToken, getReportResolveRequestBodyBites("dismiss"))
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				Expect(requestAPI(http.MethodGet, threadUrl, "", nil).Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("未対応の通報がない場合", func() {
+			It("ステータスコード404を返す", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+				moderatorToken := getModeratorAuthToken()
+
+				url := "/moderation/reports/threads/" + strconv.Itoa(int(testThread.ID))
+				w := requestAPI(http.MethodPut, url, moderatorToken, getReportResolveRequestBodyBites("dismiss"))
+
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+})
+
+func getReportCreateRequestBodyBites(reason string) []byte {
+	requestBytes, _ := json.Marshal(ReportCreateRequest{Reason: reason})
+
+	return requestBytes
+}
+
+func getReportResolveRequestBodyBites(action string) []byte {
+	requestBytes, _ := json.Marshal(ReportResolveRequest{Action: action})
+
+	return requestBytes
+}
+
+func getModerationQueueResponse(w *httptest.ResponseRecorder) ModerationQueueResponse {
+	var res ModerationQueueResponse
+	decoder := json.NewDecoder(bytes.NewReader(w.Body.Bytes()))
+	decoder.Decode(&res)
+
+	return res
+}
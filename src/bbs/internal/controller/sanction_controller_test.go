@@ -0,0 +1,168 @@
+package controller_test
+
+import (
+	"bbs/internal/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type SanctionCreateRequest struct {
+	Type          string `json:"type"`
+	Reason        string `json:"reason"`
+	DurationHours int    `json:"durationHours,omitempty"`
+}
+
+type SanctionResponse struct {
+	Sanction     model.Sanction `json:"data"`
+	ErrorMessage string         `json:"error"`
+}
+
+type SanctionListResponse struct {
+	Sanctions []model.Sanction `json:"data"`
+}
+
+var _ = Describe("SanctionController", func() {
+	BeforeEach(func() {
+		defaultBeforeEachFunc()
+	})
+
+	AfterEach(func() {
+		defaultAfterEachFunc()
+	})
+
+	Describe("利用停止", func() {
+		Context("管理者が停止した場合", func() {
+			It("停止中のユーザーは理由と期限を含む403を返される", func() {
+				adminToken := getAdminAuthToken()
+
+				w := requestAPI(http.MethodPost, sanctionsURL(user.ID), adminToken, getSanctionCreateRequestBodyBites(SanctionCreateRequest{Type: "suspension", Reason: "連続投稿", DurationHours: 24}))
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				res := getSanctionResponse(w)
+				Expect(res.Sanction.ExpiresAt).NotTo(BeNil())
+
+				w = requestAPI(http.MethodPost, "/threads", token, getCreateThreadRequestBodyBites("タイトル", "本文"))
+				Expect(w.Code).To(Equal(http.StatusForbidden))
+				Expect(getSanctionResponse(w).ErrorMessage).To(And(HavePrefix("user is suspended until "), HaveSuffix(": 連続投稿")))
+			})
+
+			It("ログインはできる", func() {
+				adminToken := getAdminAuthToken()
+				requestAPI(http.MethodPost, sanctionsURL(user.ID), adminToken, getSanctionCreateRequestBodyBites(SanctionCreateRequest{Type: "suspension", Reason: "連続投稿", DurationHours: 24}))
+
+				w := login(user.Email)
+				Expect(w.Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("期間が指定されていない場合", func() {
+			It("ステータスコード400を返す", func() {
+				adminToken := getAdminAuthToken()
+
+				w := requestAPI(http.MethodPost, sanctionsURL(user.ID), adminToken, getSanctionCreateRequestBodyBites(SanctionCreateRequest{Type: "suspension", Reason: "連続投稿"}))
+				Expect(w.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("停止期間が過ぎた場合", func() {
+			It("再び操作できる", func() {
+				expiresAt := time.Now().Add(-time.Minute)
+				db.Create(&model.Sanction{UserID: user.ID, Type: model.SanctionTypeSuspension, Reason: "連続投稿", ExpiresAt: &expiresAt})
+
+				w := requestAPI(http.MethodPost, "/threads", token, getCreateThreadRequestBodyBites("タイトル", "本文"))
+				Expect(w.Code).To(Equal(http.StatusCreated))
+			})
+		})
+	})
+
+	Describe("永久追放", func() {
+		Context("管理者が追放した場合", func() {
+			It("ログインできない", func() {
+				adminToken := getAdminAuthToken()
+
+				w := requestAPI(http.MethodPost, sanctionsURL(user.ID), adminToken, getSanctionCreateRequestBodyBites(SanctionCreateRequest{Type: "ban", Reason: "荒らし行為"}))
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				Expect(getSanctionResponse(w).Sanction.ExpiresAt).To(BeNil())
+
+				w = login(user.Email)
+				Expect(w.Code).To(Equal(http.StatusForbidden))
+				Expect(getSanctionResponse(w).ErrorMessage).To(Equal("user is banned: 荒らし行為"))
+
+				w = requestAPI(http.MethodPost, "/threads", token, getCreateThreadRequestBodyBites("タイトル", "本文"))
+				Expect(w.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+
+		Context("管理者を追放しようとした場合", func() {
+			It("ステータスコード403を返す", func() {
+				db.Model(user).Update("role", model.RoleAdmin)
+				adminToken := getAdminAuthToken()
+
+				w := requestAPI(http.MethodPost, sanctionsURL(user.ID), adminToken, getSanctionCreateRequestBodyBites(SanctionCreateRequest{Type: "ban", Reason: "荒らし行為"}))
+				Expect(w.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+	})
+
+	Describe("制裁の解除と履歴", func() {
+		Context("解除した場合", func() {
+			It("再び操作でき、履歴には解除済みとして残る", func() {
+				adminToken := getAdminAuthToken()
+				w := requestAPI(http.MethodPost, sanctionsURL(user.ID), adminToken, getSanctionCreateRequestBodyBites(SanctionCreateRequest{Type: "ban", Reason: "誤り"}))
+				sanction := getSanctionResponse(w).Sanction
+
+				w = requestAPI(http.MethodDelete, sanctionsURL(user.ID)+"/"+strconv.Itoa(int(sanction.ID)), adminToken, nil)
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				w = requestAPI(http.MethodPost, "/threads", token, getCreateThreadRequestBodyBites("タイトル", "本文"))
+				Expect(w.Code).To(Equal(http.StatusCreated))
+
+				w = requestAPI(http.MethodGet, sanctionsURL(user.ID), adminToken, nil)
+				Expect(w.Code).To(Equal(http.StatusOK))
+				var res SanctionListResponse
+				json.Unmarshal(w.Body.Bytes(), &res)
+				Expect(res.Sanctions).To(HaveLen(1))
+				Expect(res.Sanctions[0].RevokedAt).NotTo(BeNil())
+
+				w = requestAPI(http.MethodDelete, sanctionsURL(user.ID)+"/"+strconv.Itoa(int(sanction.ID)), adminToken, nil)
+				Expect(w.Code).To(Equal(http.StatusConflict))
+			})
+		})
+	})
+
+	Describe("権限", func() {
+		Context("管理者ではない場合", func() {
+			It("ステータスコード403を返す", func() {
+				moderatorToken := getModeratorAuthToken()
+
+				w := requestAPI(http.MethodGet, sanctionsURL(user.ID), moderatorToken, nil)
+				Expect(w.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+	})
+})
+
+func sanctionsURL(userId uint) string {
+	return "/admin/users/" + strconv.Itoa(int(userId)) + "/sanctions"
+}
+
+func getSanctionCreateRequestBodyBites(request SanctionCreateRequest) []byte {
+	requestBytes, _ := json.Marshal(request)
+	return requestBytes
+}
+
+func getSanctionResponse(w *httptest.ResponseRecorder) SanctionResponse {
+	var res SanctionResponse
+	json.Unmarshal(w.Body.Bytes(), &res)
+	return res
+}
+
+func login(email string) *httptest.ResponseRecorder {
+	requestBytes, _ := json.Marshal(loginRequest{Email: email, Password: password})
+	return requestAPI(http.MethodPost, "/auth/login", "", requestBytes)
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SubscriptionController struct {
+	service service.ISubscriptionService
+}
+
+func NewSubscriptionController(service service.ISubscriptionService) ISubscriptionController {
+	return &SubscriptionController{service: service}
+}
+
+// 購読済みのスレッドに対しては既読位置を更新する
+func (c *SubscriptionController) Subscribe(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	threadId, err := strconv.ParseUint(ctx.Param("threadId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread id"})
+		return
+	}
+
+	// リクエストボディは省略できる
+	var input dto.SubscribeInput
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	subscription, err := c.service.Subscribe(ctx.Request.Context(), uint(threadId), input, userId)
+	if err != nil {
+		if err.Error() == "thread not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": subscription})
+}
+
+func (c *SubscriptionController) Unsubscribe(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	threadId, err := strconv.ParseUint(ctx.Param("threadId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread id"})
+		return
+	}
+
+	if err := c.service.Unsubscribe(ctx.Request.Context(), uint(threadId), userId); err != nil {
+		if err.Error() == "subscription not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (c *SubscriptionController) FindMine(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	limit, offset, err := parsePagination(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscriptionList, err := c.service.FindByUserId(ctx.Request.Context(), userId, limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	for i := range subscriptionList.Subscriptions {
+		service.AnonymizeThread(&subscriptionList.Subscriptions[i].Thread, currentUser(ctx))
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": subscriptionList})
+}
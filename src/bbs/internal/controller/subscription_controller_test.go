@@ -0,0 +1,156 @@
+package controller_test
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type SubscriptionListResponse struct {
+	Data dto.SubscriptionListOutput `json:"data"`
+}
+
+var _ = Describe("SubscriptionController", func() {
+	BeforeEach(func() {
+		defaultBeforeEachFunc()
+	})
+
+	AfterEach(func() {
+		defaultAfterEachFunc()
+	})
+
+	Describe("スレッドの購読", func() {
+		Context("スレッドを購読した場合", func() {
+			It("購読一覧に未読のコメント数とともに表示される", func() {
+				otherUser := createTestUser(r, db, "other", "other@example.com")
+				thread := createTestThread(db, otherUser.ID, 1)[0]
+
+				w := requestAPI(http.MethodPost, subscriptionURL(thread.ID), token, nil)
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				db.Create(&model.Comment{Body: "1", ThreadID: thread.ID, UserID: otherUser.ID})
+				db.Create(&model.Comment{Body: "2", ThreadID: thread.ID, UserID: otherUser.ID})
+
+				list := getSubscriptionListResponse(requestAPI(http.MethodGet, "/me/subscriptions", token, nil))
+
+				Expect(list.Data.Total).To(Equal(int64(1)))
+				Expect(list.Data.Subscriptions[0].ThreadID).To(Equal(thread.ID))
+				Expect(list.Data.Subscriptions[0].UnreadCount).To(Equal(int64(2)))
+			})
+
+			It("複数のスレッドの未読数をスレッドごとに数える", func() {
+				otherUser := createTestUser(r, db, "other", "other@example.com")
+				threads := createTestThread(db, otherUser.ID, 2)
+				for _, thread := range threads {
+					requestAPI(http.MethodPost, subscriptionURL(thread.ID), token, nil)
+				}
+
+				db.Create(&model.Comment{Body: "1", ThreadID: threads[0].ID, UserID: otherUser.ID})
+				db.Create(&model.Comment{Body: "2", ThreadID: threads[0].ID, UserID: otherUser.ID})
+				db.Create(&model.Comment{Body: "3", ThreadID: threads[0].ID, UserID: otherUser.ID, Hidden: true})
+				db.Create(&model.Comment{Body: "4", ThreadID: threads[1].ID, UserID: otherUser.ID})
+
+				list := getSubscriptionListResponse(requestAPI(http.MethodGet, "/me/subscriptions", token, nil))
+
+				Expect(list.Data.Total).To(Equal(int64(2)))
+				unreadCounts := map[uint]int64{}
+				for _, subscription := range list.Data.Subscriptions {
+					Expect(subscription.Thread.ID).To(Equal(subscription.ThreadID))
+					unreadCounts[subscription.ThreadID] = subscription.UnreadCount
+				}
+				Expect(unreadCounts).To(Equal(map[uint]int64{threads[0].ID: 2, threads[1].ID: 1}))
+			})
+
+			It("再度購読すると既読になる", func() {
+				otherUser := createTestUser(r, db, "other", "other@example.com")
+				thread := createTestThread(db, otherUser.ID, 1)[0]
+				requestAPI(http.MethodPost, subscriptionURL(thread.ID), token, nil)
+				db.Create(&model.Comment{Body: "1", ThreadID: thread.ID, UserID: otherUser.ID})
+
+				w := requestAPI(http.MethodPost, subscriptionURL(thread.ID), token, nil)
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				list := getSubscriptionListResponse(requestAPI(http.MethodGet, "/me/subscriptions", token, nil))
+				Expect(list.Data.Total).To(Equal(int64(1)))
+				Expect(list.Data.Subscriptions[0].UnreadCount).To(BeZero())
+			})
+		})
+
+		Context("購読を解除した場合", func() {
+			It("購読一覧に表示されない", func() {
+				thread := createTestThread(db, user.ID, 1)[0]
+				requestAPI(http.MethodPost, subscriptionURL(thread.ID), token, nil)
+
+				w := requestAPI(http.MethodDelete, subscriptionURL(thread.ID), token, nil)
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				list := getSubscriptionListResponse(requestAPI(http.MethodGet, "/me/subscriptions", token, nil))
+				Expect(list.Data.Total).To(BeZero())
+
+				w = requestAPI(http.MethodDelete, subscriptionURL(thread.ID), token, nil)
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("閲覧できないスレッドの場合", func() {
+			It("ステータスコード404を返す", func() {
+				otherUser := createTestUser(r, db, "other", "other@example.com")
+				thread := createTestThread(db, otherUser.ID, 1)[0]
+				db.Model(&thread).Update("visibility", model.VisibilityPrivate)
+
+				w := requestAPI(http.MethodPost, subscriptionURL(thread.ID), token, nil)
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("スレッドを作成した場合", func() {
+			It("作成者は自動的に購読する", func() {
+				requestAPI(http.MethodPost, "/threads", token, getCreateThreadRequestBodyBites("テスト", "テストテスト"))
+
+				list := getSubscriptionListResponse(requestAPI(http.MethodGet, "/me/subscriptions", token, nil))
+				Expect(list.Data.Total).To(Equal(int64(1)))
+			})
+		})
+
+		Context("コメントした場合", func() {
+			It("自動的に購読し、自分のコメントは未読に含まれない", func() {
+				otherUser := createTestUser(r, db, "other", "other@example.com")
+				thread := createTestThread(db, otherUser.ID, 1)[0]
+				db.Create(&model.Comment{Body: "1", ThreadID: thread.ID, UserID: otherUser.ID})
+
+				url := "/threads/" + strconv.Itoa(int(thread.ID)) + "/comments"
+				requestAPI(http.MethodPost, url, token, getCreateCommentRequestBodyBites("コメント"))
+
+				list := getSubscriptionListResponse(requestAPI(http.MethodGet, "/me/subscriptions", token, nil))
+				Expect(list.Data.Total).To(Equal(int64(1)))
+				Expect(list.Data.Subscriptions[0].UnreadCount).To(BeZero())
+			})
+		})
+
+		Context("ログインしていない場合", func() {
+			It("ステータスコード401を返す", func() {
+				w := requestAPI(http.MethodGet, "/me/subscriptions", "", nil)
+				Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+	})
+})
+
+func subscriptionURL(threadId uint) string {
+	return "/threads/" + strconv.Itoa(int(threadId)) + "/subscription"
+}
+
+func getSubscriptionListResponse(w *httptest.ResponseRecorder) SubscriptionListResponse {
+	var res SubscriptionListResponse
+	decoder := json.NewDecoder(bytes.NewReader(w.Body.Bytes()))
+	decoder.Decode(&res)
+
+	return res
+}
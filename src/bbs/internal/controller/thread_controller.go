@@ -1,9 +1,11 @@
 package controller
 
 import (
+	"bbs/internal/contentfilter"
 	"bbs/internal/dto"
 	"bbs/internal/model"
 	"bbs/internal/service"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -33,12 +35,21 @@ func (c *ThreadController) Create(ctx *gin.Context) {
 		return
 	}
 
-	newThread, err := c.service.Create(input, userId)
+	newThread, err := c.service.Create(ctx.Request.Context(), input, userId)
 	if err != nil {
+		if err.Error() == "attachment not found" || err.Error() == "poll close time must be in the future" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, contentfilter.ErrRejected) {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	service.AnonymizeThread(newThread, currentUser(ctx))
 	ctx.JSON(http.StatusCreated, gin.H{"data": newThread})
 }
 
@@ -63,24 +74,33 @@ func (c *ThreadController) Update(ctx *gin.Context) {
 		return
 	}
 
-	updateThread, err := c.service.Update(uint(threadId), input, userId)
+	updateThread, err := c.service.Update(ctx.Request.Context(), uint(threadId), input, userId)
 	if err != nil {
 		if err.Error() == "user is not thread owner" {
 			ctx.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
+		if err.Error() == "thread is archived" {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		if err.Error() == "thread not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, contentfilter.ErrRejected) {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
 		return
 	}
 
+	service.AnonymizeThread(updateThread, currentUser(ctx))
 	ctx.JSON(http.StatusOK, gin.H{"data": updateThread})
 }
 
-func (c *ThreadController) Delete(ctx *gin.Context) {
+func (c *ThreadController) UpdateVisibility(ctx *gin.Context) {
 	user, exists := ctx.Get("user")
 	if !exists {
 		ctx.AbortWithStatus(http.StatusUnauthorized)
@@ -95,7 +115,13 @@ func (c *ThreadController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	err = c.service.Delete(uint(threadId), userId)
+	var input dto.UpdateThreadVisibilityInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updateThread, err := c.service.UpdateVisibility(ctx.Request.Context(), uint(threadId), input, userId)
 	if err != nil {
 		if err.Error() == "user is not thread owner" {
 			ctx.AbortWithStatus(http.StatusUnauthorized)
@@ -109,38 +135,136 @@ func (c *ThreadController) Delete(ctx *gin.Context) {
 		return
 	}
 
-	ctx.Status(http.StatusOK)
+	service.AnonymizeThread(updateThread, currentUser(ctx))
+	ctx.JSON(http.StatusOK, gin.H{"data": updateThread})
 }
 
-func (c *ThreadController) FindAll(ctx *gin.Context) {
-	// TODO: デフォルトのlimitは別ファイルで定数として定義する
-	limit := 10
-	if limitQuery := ctx.Query("limit"); limitQuery != "" {
-		limitInt, err := strconv.Atoi(limitQuery)
-		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+func (c *ThreadController) UpdateState(ctx *gin.Context) {
+	threadId, err := strconv.ParseUint(ctx.Param("threadId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	var input dto.UpdateThreadStateInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updateThread, err := c.service.UpdateState(ctx.Request.Context(), uint(threadId), input)
+	if err != nil {
+		if err.Error() == "thread not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		limit = limitInt
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
 	}
 
-	// TODO: デフォルトのoffsetは別ファイルで定数として定義する
-	offset := 0
-	if pageQuery := ctx.Query("page"); pageQuery != "" {
-		pageInt, err := strconv.Atoi(pageQuery)
-		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	service.AnonymizeThread(updateThread, currentUser(ctx))
+	ctx.JSON(http.StatusOK, gin.H{"data": updateThread})
+}
+
+func (c *ThreadController) Delete(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	threadId, err := strconv.ParseUint(ctx.Param("threadId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	err = c.service.Delete(ctx.Request.Context(), uint(threadId), userId)
+	if err != nil {
+		if err.Error() == "user is not thread owner" {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if err.Error() == "thread not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		// offsetは0始まりでpageは1始まりなので1を引く
-		offset = pageInt - 1
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (c *ThreadController) FindAll(ctx *gin.Context) {
+	limit, offset, err := parsePagination(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	threadList, err := c.service.FindAll(limit, offset)
+	threadList, err := c.service.FindAll(ctx.Request.Context(), limit, offset, viewerId(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	for i := range threadList.Threads {
+		service.AnonymizeThread(&threadList.Threads[i], currentUser(ctx))
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": threadList})
+}
+
+func (c *ThreadController) FindMine(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	limit, offset, err := parsePagination(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	threadList, err := c.service.FindByUserId(ctx.Request.Context(), userId, limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	for i := range threadList.Threads {
+		service.AnonymizeThread(&threadList.Threads[i], currentUser(ctx))
+	}
+	ctx.JSON(http.StatusOK, gin.H{"data": threadList})
+}
+
+func (c *ThreadController) FindParticipating(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	limit, offset, err := parsePagination(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	threadList, err := c.service.FindParticipating(ctx.Request.Context(), userId, limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+	for i := range threadList.Threads {
+		service.AnonymizeThread(&threadList.Threads[i], currentUser(ctx))
+	}
 	ctx.JSON(http.StatusOK, gin.H{"data": threadList})
 }
 
@@ -151,7 +275,7 @@ func (c *ThreadController) FindById(ctx *gin.Context) {
 		return
 	}
 
-	thread, err := c.service.FindById(uint(threadId))
+	thread, err := c.service.FindById(ctx.Request.Context(), uint(threadId), viewerId(ctx))
 	if err != nil {
 		if err.Error() == "thread not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -161,5 +285,62 @@ func (c *ThreadController) FindById(ctx *gin.Context) {
 		return
 	}
 
+	service.AnonymizeThread(thread, currentUser(ctx))
+	ctx.JSON(http.StatusOK, gin.H{"data": thread})
+}
+
+func (c *ThreadController) FindRevisions(ctx *gin.Context) {
+	threadId, err := strconv.ParseUint(ctx.Param("threadId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	revisions, err := c.service.FindRevisions(ctx.Request.Context(), uint(threadId), viewerId(ctx))
+	if err != nil {
+		if err.Error() == "thread not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	service.AnonymizeRevisions(*revisions, currentUser(ctx))
+	ctx.JSON(http.StatusOK, gin.H{"data": revisions})
+}
+
+func (c *ThreadController) RevertRevision(ctx *gin.Context) {
+	user, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	userId := user.(*model.User).ID
+
+	threadId, err := strconv.ParseUint(ctx.Param("threadId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+
+	revisionId, err := strconv.ParseUint(ctx.Param("revisionId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision id"})
+		return
+	}
+
+	thread, err := c.service.RevertRevision(ctx.Request.Context(), uint(threadId), uint(revisionId), userId)
+	if err != nil {
+		if err.Error() == "thread not found" || err.Error() == "revision not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	service.AnonymizeThread(thread, currentUser(ctx))
 	ctx.JSON(http.StatusOK, gin.H{"data": thread})
 }
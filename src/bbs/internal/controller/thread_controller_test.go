@@ -2,11 +2,16 @@ package controller_test
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -49,6 +54,17 @@ type UpdateResponse struct {
 	ErrorMessage string       `json:"error"`
 }
 
+type RevisionListResponse struct {
+	Revisions    []dto.RevisionOutput `json:"data"`
+	ErrorMessage string               `json:"error"`
+}
+
+type UpdateStateRequest struct {
+	Pinned   *bool `json:"pinned,omitempty"`
+	Locked   *bool `json:"locked,omitempty"`
+	Archived *bool `json:"archived,omitempty"`
+}
+
 var _ = Describe("ThreadController", func() {
 	BeforeEach(func() {
 		defaultBeforeEachFunc()
@@ -245,6 +261,40 @@ var _ = Describe("ThreadController", func() {
 			})
 		})
 
+		Context("本文がMarkdownの場合", func() {
+			It("本文とサニタイズ済みのHTMLを返す", func() {
+				title := "テスト"
+				body := "**テスト**\n\n<script>alert(1)</script>\n\n[link](javascript:alert(1))"
+
+				request := getCreateThreadRequestBodyBites(title, body)
+
+				url := "/threads"
+				w := requestAPI(http.MethodPost, url, token, request)
+
+				responseBody := getThreadCreateResponseBody(w)
+
+				Expect(responseBody.Thread.Body).To(Equal(body))
+				Expect(responseBody.Thread.BodyHTML).To(ContainSubstring("<strong>テスト</strong>"))
+				Expect(responseBody.Thread.BodyHTML).NotTo(ContainSubstring("<script>"))
+				Expect(responseBody.Thread.BodyHTML).NotTo(ContainSubstring("javascript:"))
+			})
+
+			It("GFMのテーブルとコードブロックをHTMLに変換する", func() {
+				title := "テスト"
+				body := "| a | b |\n|---|---|\n| 1 | 2 |\n\n```go\nfmt.Println(1)\n```"
+
+				request := getCreateThreadRequestBodyBites(title, body)
+
+				url := "/threads"
+				w := requestAPI(http.MethodPost, url, token, request)
+
+				responseBody := getThreadCreateResponseBody(w)
+
+				Expect(responseBody.Thread.BodyHTML).To(ContainSubstring("<table>"))
+				Expect(responseBody.Thread.BodyHTML).To(ContainSubstring(`<code class="language-go">`))
+			})
+		})
+
 		Context("認証トークンがない場合", func() {
 			It("ステータスコード401を返す", func() {
 				title := "テスト"
@@ -312,6 +362,37 @@ var _ = Describe("ThreadController", func() {
 				Expect(responseBody.ErrorMessage).To(Equal(expectErrorMessage))
 			})
 		})
+
+		Context("匿名スレッドとして作成した場合", func() {
+			It("ユーザーIDの代わりに投稿者IDが返る", func() {
+				request, _ := json.Marshal(map[string]interface{}{"title": "匿名スレッド", "body": "本文", "anonymous": true})
+
+				w := requestAPI(http.MethodPost, "/threads", token, request)
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				created := getThreadCreateResponseBody(w).Thread
+				Expect(created.Anonymous).To(BeTrue())
+				Expect(created.UserID).To(Equal(uint(0)))
+				Expect(created.PosterID).NotTo(BeEmpty())
+
+				w = requestAPI(http.MethodGet, "/threads/"+strconv.Itoa(int(created.ID)), "", nil)
+				thread := getThreadDetailResponseBody(w).Thread
+				Expect(thread.UserID).To(Equal(uint(0)))
+				Expect(thread.PosterID).To(Equal(created.PosterID))
+			})
+
+			It("空の鍵では投稿者IDを再現できない", func() {
+				request, _ := json.Marshal(map[string]interface{}{"title": "匿名スレッド", "body": "本文", "anonymous": true})
+
+				w := requestAPI(http.MethodPost, "/threads", token, request)
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				created := getThreadCreateResponseBody(w).Thread
+
+				mac := hmac.New(sha256.New, []byte(""))
+				mac.Write([]byte(strconv.FormatUint(uint64(user.ID), 10) + ":" + strconv.FormatUint(uint64(created.ID), 10) + ":" + created.CreatedAt.Local().Format(time.DateOnly)))
+				withEmptyKey := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:8]
+				Expect(created.PosterID).NotTo(Equal(withEmptyKey))
+			})
+		})
 	})
 
 	Describe("スレッド更新", func() {
@@ -470,6 +551,342 @@ var _ = Describe("ThreadController", func() {
 		})
 	})
 
+	Describe("スレッド状態更新", func() {
+		Context("モデレーターがピン留めした場合", func() {
+			It("ステータスコード200を返す", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+				moderatorToken := getModeratorAuthToken()
+
+				pinned := true
+				request := getUpdateThreadStateRequestBodyBites(UpdateStateRequest{Pinned: &pinned})
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/state"
+				w := requestAPI(http.MethodPut, url, moderatorToken, request)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+			})
+
+			It("スレッド一覧の先頭に表示される", func() {
+				testThread := createTestThread(db, user.ID, 3)[0]
+				moderatorToken := getModeratorAuthToken()
+
+				pinned := true
+				request := getUpdateThreadStateRequestBodyBites(UpdateStateRequest{Pinned: &pinned})
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/state"
+				requestAPI(http.MethodPut, url, moderatorToken, request)
+
+				w := requestAPI(http.MethodGet, "/threads", "", nil)
+				body := getThreadListResponseBody(w)
+
+				Expect(body.Data.Threads[0].ID).To(Equal(testThread.ID))
+				Expect(body.Data.Threads[0].Pinned).To(BeTrue())
+			})
+		})
+
+		Context("モデレーターがアーカイブした場合", func() {
+			It("スレッドを更新できず403エラーを返す", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+				moderatorToken := getModeratorAuthToken()
+
+				archived := true
+				stateRequest := getUpdateThreadStateRequestBodyBites(UpdateStateRequest{Archived: &archived})
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID))
+				requestAPI(http.MethodPut, url+"/state", moderatorToken, stateRequest)
+
+				request := getUpdateThreadRequestBodyBites("update", "testtest")
+				w := requestAPI(http.MethodPut, url, token, request)
+
+				res := getThreadUpdateResponseBody(w)
+
+				Expect(w.Code).To(Equal(http.StatusForbidden))
+				Expect(res.ErrorMessage).To(Equal("thread is archived"))
+			})
+		})
+
+		Context("モデレーターではない場合", func() {
+			It("ステータスコード403を返す", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+
+				locked := true
+				request := getUpdateThreadStateRequestBodyBites(UpdateStateRequest{Locked: &locked})
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID)) + "/state"
+				w := requestAPI(http.MethodPut, url, token, request)
+
+				Expect(w.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+
+		Context("スレッドが存在しない場合", func() {
+			It("ステータスコード404を返す", func() {
+				moderatorToken := getModeratorAuthToken()
+
+				locked := true
+				request := getUpdateThreadStateRequestBodyBites(UpdateStateRequest{Locked: &locked})
+
+				url := "/threads/" + strconv.Itoa(0) + "/state"
+				w := requestAPI(http.MethodPut, url, moderatorToken, request)
+
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("スレッド編集履歴", func() {
+		Context("スレッドを更新した場合", func() {
+			It("更新後のスレッドは編集済みになる", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+
+				request := getUpdateThreadRequestBodyBites("update", "testtest")
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID))
+				w := requestAPI(http.MethodPut, url, token, request)
+
+				res := getThreadUpdateResponseBody(w)
+
+				Expect(res.Thread.Edited).To(BeTrue())
+				Expect(res.Thread.EditedAt).NotTo(BeNil())
+			})
+
+			It("更新前の内容と差分を返す", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+
+				request := getUpdateThreadRequestBodyBites("update", "testtest")
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID))
+				requestAPI(http.MethodPut, url, token, request)
+
+				w := requestAPI(http.MethodGet, url+"/revisions", "", nil)
+
+				res := getRevisionListResponseBody(w)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				Expect(len(res.Revisions)).To(Equal(1))
+				Expect(res.Revisions[0].Revision).To(Equal(1))
+				Expect(res.Revisions[0].Title).To(Equal(testThread.Title))
+				Expect(res.Revisions[0].Body).To(Equal(testThread.Body))
+				Expect(res.Revisions[0].Diff).To(Equal([]string{"-" + testThread.Body, "+testtest"}))
+			})
+		})
+
+		Context("行数の多い本文を更新した場合", func() {
+			It("変更された行をすべて置き換えたものとして差分を返す", func() {
+				before := make([]string, 2001)
+				after := make([]string, 2001)
+				for i := range before {
+					before[i] = "before" + strconv.Itoa(i)
+					after[i] = "after" + strconv.Itoa(i)
+				}
+				before[1000] = "common"
+				after[1000] = "common"
+				testThread := model.Thread{UserID: user.ID, Title: "テストタイトル", Body: strings.Join(before, "\n")}
+				db.Create(&testThread)
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID))
+				w := requestAPI(http.MethodPut, url, token, getUpdateThreadRequestBodyBites("update", strings.Join(after, "\n")))
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				w = requestAPI(http.MethodGet, url+"/revisions", "", nil)
+				res := getRevisionListResponseBody(w)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				Expect(res.Revisions[0].Diff).To(HaveLen(4002))
+				Expect(res.Revisions[0].Diff[0]).To(Equal("-before0"))
+				Expect(res.Revisions[0].Diff[2001]).To(Equal("+after0"))
+				Expect(res.Revisions[0].Diff).NotTo(ContainElement(" common"))
+			})
+		})
+
+		Context("本文が長すぎる場合", func() {
+			It("ステータスコード400を返す", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID))
+				w := requestAPI(http.MethodPut, url, token, getUpdateThreadRequestBodyBites("update", strings.Repeat("あ", 20001)))
+				Expect(w.Code).To(Equal(http.StatusBadRequest))
+
+				w = requestAPI(http.MethodPost, "/threads", token, getCreateThreadRequestBodyBites("テストタイトル", strings.Repeat("あ", 20001)))
+				Expect(w.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("モデレーターが過去の版に戻した場合", func() {
+			It("スレッドの内容が過去の版に戻る", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+				moderatorToken := getModeratorAuthToken()
+
+				request := getUpdateThreadRequestBodyBites("update", "testtest")
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID))
+				requestAPI(http.MethodPut, url, token, request)
+
+				revisions := getRevisionListResponseBody(requestAPI(http.MethodGet, url+"/revisions", "", nil)).Revisions
+
+				revertUrl := url + "/revisions/" + strconv.Itoa(int(revisions[0].ID)) + "/revert"
+				w := requestAPI(http.MethodPost, revertUrl, moderatorToken, nil)
+
+				res := getThreadUpdateResponseBody(w)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				Expect(res.Thread.Title).To(Equal(testThread.Title))
+				Expect(res.Thread.Body).To(Equal(testThread.Body))
+
+				revisions = getRevisionListResponseBody(requestAPI(http.MethodGet, url+"/revisions", "", nil)).Revisions
+				Expect(len(revisions)).To(Equal(2))
+			})
+		})
+
+		Context("モデレーターではない場合", func() {
+			It("過去の版に戻せず403エラーを返す", func() {
+				testThread := createTestThread(db, user.ID, 1)[0]
+
+				request := getUpdateThreadRequestBodyBites("update", "testtest")
+
+				url := "/threads/" + strconv.Itoa(int(testThread.ID))
+				requestAPI(http.MethodPut, url, token, request)
+
+				revisions := getRevisionListResponseBody(requestAPI(http.MethodGet, url+"/revisions", "", nil)).Revisions
+
+				revertUrl := url + "/revisions/" + strconv.Itoa(int(revisions[0].ID)) + "/revert"
+				w := requestAPI(http.MethodPost, revertUrl, token, nil)
+
+				Expect(w.Code).To(Equal(http.StatusForbidden))
+			})
+		})
+	})
+
+	Describe("スレッドの公開範囲", func() {
+		Context("ログインユーザー限定のスレッドの場合", func() {
+			It("未ログインでは一覧にも詳細にも表示されない", func() {
+				createTestThread(db, user.ID, 1)
+				membersThread := model.Thread{Title: "限定", Body: "本文", UserID: user.ID, Visibility: model.VisibilityMembers}
+				db.Create(&membersThread)
+
+				w := requestAPI(http.MethodGet, "/threads", "", nil)
+				list := getThreadListResponseBody(w)
+				Expect(list.Data.Total).To(Equal(int64(1)))
+				Expect(list.Data.Threads).To(HaveLen(1))
+
+				url := "/threads/" + strconv.Itoa(int(membersThread.ID))
+				w = requestAPI(http.MethodGet, url, "", nil)
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+
+				w = requestAPI(http.MethodGet, url, token, nil)
+				Expect(w.Code).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("非公開のスレッドの場合", func() {
+			It("作成者と招待されたユーザーとモデレーターのみ閲覧できる", func() {
+				invited := createTestUser(r, db, "invited", "invited@example.com")
+				invitedToken := createTestUserToken(r, invited.Email)
+
+				request, _ := json.Marshal(map[string]interface{}{"title": "非公開", "body": "本文", "visibility": "private", "invitedUserIds": []uint{invited.ID}})
+				w := requestAPI(http.MethodPost, "/threads", token, request)
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				created := getThreadCreateResponseBody(w).Thread
+				Expect(created.Members).To(HaveLen(1))
+
+				url := "/threads/" + strconv.Itoa(int(created.ID))
+				Expect(requestAPI(http.MethodGet, url, token, nil).Code).To(Equal(http.StatusOK))
+				Expect(requestAPI(http.MethodGet, url, invitedToken, nil).Code).To(Equal(http.StatusOK))
+				Expect(requestAPI(http.MethodGet, url, getModeratorAuthToken(), nil).Code).To(Equal(http.StatusOK))
+				Expect(requestAPI(http.MethodGet, url, getOtherUserAuthToken(), nil).Code).To(Equal(http.StatusNotFound))
+				Expect(requestAPI(http.MethodGet, url, "", nil).Code).To(Equal(http.StatusNotFound))
+			})
+
+			It("招待されていないユーザーはコメントの取得も投稿もできない", func() {
+				privateThread := model.Thread{Title: "非公開", Body: "本文", UserID: user.ID, Visibility: model.VisibilityPrivate}
+				db.Create(&privateThread)
+				otherUserToken := getOtherUserAuthToken()
+
+				url := "/threads/" + strconv.Itoa(int(privateThread.ID)) + "/comments"
+				Expect(requestAPI(http.MethodGet, url, otherUserToken, nil).Code).To(Equal(http.StatusNotFound))
+				Expect(requestAPI(http.MethodPost, url, otherUserToken, getCreateCommentRequestBodyBites("コメント")).Code).To(Equal(http.StatusNotFound))
+				Expect(requestAPI(http.MethodGet, url, token, nil).Code).To(Equal(http.StatusOK))
+			})
+
+			It("添付ファイルも取得できない", func() {
+				privateThread := model.Thread{Title: "非公開", Body: "本文", UserID: user.ID, Visibility: model.VisibilityPrivate}
+				db.Create(&privateThread)
+				attachment := model.Attachment{UserID: user.ID, ThreadID: &privateThread.ID, FileName: "a.txt", ContentType: "text/plain", StorageKey: "attachments/a.txt"}
+				db.Create(&attachment)
+
+				w := requestAPI(http.MethodGet, "/attachments/"+strconv.Itoa(int(attachment.ID)), "", nil)
+				Expect(w.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("作成者が公開範囲を変更した場合", func() {
+			It("未ログインでも閲覧できるようになる", func() {
+				privateThread := model.Thread{Title: "非公開", Body: "本文", UserID: user.ID, Visibility: model.VisibilityPrivate}
+				db.Create(&privateThread)
+				url := "/threads/" + strconv.Itoa(int(privateThread.ID))
+
+				request, _ := json.Marshal(map[string]interface{}{"visibility": "public"})
+				w := requestAPI(http.MethodPut, url+"/visibility", token, request)
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				Expect(requestAPI(http.MethodGet, url, "", nil).Code).To(Equal(http.StatusOK))
+			})
+
+			It("作成者以外は変更できない", func() {
+				privateThread := model.Thread{Title: "非公開", Body: "本文", UserID: user.ID, Visibility: model.VisibilityPrivate}
+				db.Create(&privateThread)
+
+				request, _ := json.Marshal(map[string]interface{}{"visibility": "public"})
+				w := requestAPI(http.MethodPut, "/threads/"+strconv.Itoa(int(privateThread.ID))+"/visibility", getOtherUserAuthToken(), request)
+				Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+	})
+
+	Describe("自分の投稿", func() {
+		Context("自分のスレッドを取得した場合", func() {
+			It("自分が作成したスレッドのみ返す", func() {
+				createTestThread(db, user.ID, 2)
+				otherUser := createTestUser(r, db, "other", "other@example.com")
+				createTestThread(db, otherUser.ID, 1)
+
+				w := requestAPI(http.MethodGet, "/me/threads", token, nil)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				list := getThreadListResponseBody(w)
+				Expect(list.Data.Total).To(Equal(int64(2)))
+				for _, thread := range list.Data.Threads {
+					Expect(thread.UserID).To(Equal(user.ID))
+				}
+			})
+		})
+
+		Context("コメントしたスレッドを取得した場合", func() {
+			It("最終更新日時の新しい順に返す", func() {
+				otherUser := createTestUser(r, db, "other", "other@example.com")
+				threads := createTestThread(db, otherUser.ID, 3)
+				db.Create(&model.Comment{Body: "1", ThreadID: threads[0].ID, UserID: user.ID})
+				db.Create(&model.Comment{Body: "2", ThreadID: threads[1].ID, UserID: user.ID})
+				db.Model(&threads[0]).Update("last_activity_at", time.Now().Add(time.Hour))
+
+				w := requestAPI(http.MethodGet, "/me/participating", token, nil)
+
+				Expect(w.Code).To(Equal(http.StatusOK))
+				list := getThreadListResponseBody(w)
+				Expect(list.Data.Total).To(Equal(int64(2)))
+				Expect(list.Data.Threads[0].ID).To(Equal(threads[0].ID))
+				Expect(list.Data.Threads[1].ID).To(Equal(threads[1].ID))
+			})
+		})
+
+		Context("ログインしていない場合", func() {
+			It("ステータスコード401を返す", func() {
+				w := requestAPI(http.MethodGet, "/me/threads", "", nil)
+				Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+	})
+
 	Describe("スレッド削除", func() {
 		Context("スレッドを削除した場合", func() {
 			It("ステータスコード200を返す", func() {
@@ -629,6 +1046,20 @@ func getThreadUpdateResponseBody(w *httptest.ResponseRecorder) UpdateResponse {
 	return res
 }
 
+func getUpdateThreadStateRequestBodyBites(request UpdateStateRequest) []byte {
+	requestBytes, _ := json.Marshal(request)
+
+	return requestBytes
+}
+
+func getRevisionListResponseBody(w *httptest.ResponseRecorder) RevisionListResponse {
+	var res RevisionListResponse
+	decoder := json.NewDecoder(bytes.NewReader(w.Body.Bytes()))
+	decoder.Decode(&res)
+
+	return res
+}
+
 func getThreadDeleteResponseBody(w *httptest.ResponseRecorder) DeleteResponse {
 	var res DeleteResponse
 	decoder := json.NewDecoder(bytes.NewReader(w.Body.Bytes()))
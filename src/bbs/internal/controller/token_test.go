@@ -0,0 +1,225 @@
+package controller_test
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("トークンの署名鍵", func() {
+	BeforeEach(func() {
+		defaultBeforeEachFunc()
+	})
+
+	AfterEach(func() {
+		defaultAfterEachFunc()
+	})
+
+	Describe("JWKS", func() {
+		It("トークンの署名に使った鍵を公開する", func() {
+			w := requestAPI(http.MethodGet, "/.well-known/jwks.json", "", nil)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			var jwks dto.JWKSOutput
+			json.Unmarshal(w.Body.Bytes(), &jwks)
+			Expect(len(jwks.Keys)).To(Equal(1))
+			Expect(jwks.Keys[0].Kid).To(Equal(tokenKeyID(token)))
+			Expect(jwks.Keys[0].Alg).To(Equal(model.SigningAlgorithmEdDSA))
+			Expect(jwks.Keys[0].Kty).To(Equal("OKP"))
+			Expect(jwks.Keys[0].X).NotTo(BeEmpty())
+		})
+	})
+
+	Describe("署名鍵の保存", func() {
+		It("JWT_KEY_ENCRYPTION_KEYが設定されている場合は秘密鍵を暗号化して保存する", func() {
+			var key model.SigningKey
+			db.Where("key_id = ?", tokenKeyID(token)).First(&key)
+
+			Expect(key.PrivateKey).To(HavePrefix("aes256gcm:"))
+			Expect(key.PrivateKey).NotTo(ContainSubstring("PRIVATE KEY"))
+		})
+
+		It("平文で保存された鍵は使わない", func() {
+			_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+			der, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+			Expect(db.Create(&model.SigningKey{
+				KeyID:      "plaintext",
+				Algorithm:  model.SigningAlgorithmEdDSA,
+				PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+				ExpiresAt:  time.Now().Add(time.Hour),
+			}).Error).To(BeNil())
+			setGinRoute()
+
+			forged := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+				"sub": strconv.Itoa(int(user.ID)),
+				"iss": "bbs",
+				"aud": "bbs",
+				"exp": time.Now().Add(time.Hour).Unix(),
+			})
+			forged.Header["kid"] = "plaintext"
+			signed, _ := forged.SignedString(privateKey)
+
+			Expect(requestAPI(http.MethodGet, "/me/threads", signed, nil).Code).To(Equal(http.StatusUnauthorized))
+			Expect(requestAPI(http.MethodGet, "/me/threads", token, nil).Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("鍵のローテーション", func() {
+		Context("ローテーション期間を過ぎた場合", func() {
+			It("新しい鍵で署名し、古い鍵で署名されたトークンも引き続き使える", func() {
+				db.Model(&model.SigningKey{}).Where("key_id = ?", tokenKeyID(token)).Update("created_at", time.Now().AddDate(0, 0, -31))
+				setGinRoute()
+
+				newToken := createTestUserToken(r, user.Email)
+				Expect(tokenKeyID(newToken)).NotTo(Equal(tokenKeyID(token)))
+
+				Expect(requestAPI(http.MethodGet, "/me/threads", token, nil).Code).To(Equal(http.StatusOK))
+				Expect(requestAPI(http.MethodGet, "/me/threads", newToken, nil).Code).To(Equal(http.StatusOK))
+
+				var jwks dto.JWKSOutput
+				json.Unmarshal(requestAPI(http.MethodGet, "/.well-known/jwks.json", "", nil).Body.Bytes(), &jwks)
+				Expect(len(jwks.Keys)).To(Equal(2))
+			})
+		})
+
+		Context("ワーカーから定期的にローテーションする場合", func() {
+			It("トークンを発行しなくても期間を過ぎた鍵をローテーションし、期限が切れた鍵を削除する", func() {
+				db.Model(&model.SigningKey{}).Where("key_id = ?", tokenKeyID(token)).Update("created_at", time.Now().AddDate(0, 0, -31))
+				Expect(db.Create(&model.SigningKey{KeyID: "expired", Algorithm: model.SigningAlgorithmEdDSA, PrivateKey: "aes256gcm:", ExpiresAt: time.Now().Add(-time.Minute)}).Error).To(BeNil())
+
+				deleted, err := tokenService.RotateKeys(context.Background())
+				Expect(err).To(BeNil())
+				Expect(deleted).To(Equal(int64(1)))
+
+				var keys []model.SigningKey
+				db.Order("created_at desc").Find(&keys)
+				Expect(keys).To(HaveLen(2))
+				Expect(keys[0].KeyID).NotTo(Equal(tokenKeyID(token)))
+
+				// 期間内であれば何もしない
+				deleted, err = tokenService.RotateKeys(context.Background())
+				Expect(err).To(BeNil())
+				Expect(deleted).To(BeZero())
+				var count int64
+				db.Model(&model.SigningKey{}).Count(&count)
+				Expect(count).To(Equal(int64(2)))
+			})
+		})
+
+		Context("鍵の有効期限が切れた場合", func() {
+			It("その鍵で署名されたトークンはステータスコード401を返す", func() {
+				db.Model(&model.SigningKey{}).Where("key_id = ?", tokenKeyID(token)).Update("expires_at", time.Now().Add(-time.Minute))
+				setGinRoute()
+
+				w := requestAPI(http.MethodGet, "/me/threads", token, nil)
+				Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+	})
+
+	Describe("トークンの検証", func() {
+		Context("audienceが異なる場合", func() {
+			It("ステータスコード401を返す", func() {
+				DeferCleanup(os.Setenv, "JWT_AUDIENCE", os.Getenv("JWT_AUDIENCE"))
+				os.Setenv("JWT_AUDIENCE", "other-service")
+
+				w := requestAPI(http.MethodGet, "/me/threads", token, nil)
+				Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Context("クレームの型が不正な場合", func() {
+			It("パニックせずにステータスコード401を返す", func() {
+				malformed := signWithCurrentKey(jwt.MapClaims{
+					"sub": user.ID,
+					"iss": "bbs",
+					"aud": "bbs",
+					"exp": "tomorrow",
+				})
+
+				w := requestAPI(http.MethodGet, "/me/threads", malformed, nil)
+				Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Context("共通鍵で署名された場合", func() {
+			It("ステータスコード401を返す", func() {
+				hs256 := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+					"sub": "1",
+					"iss": "bbs",
+					"aud": "bbs",
+					"exp": time.Now().Add(time.Hour).Unix(),
+				})
+				hs256.Header["kid"] = tokenKeyID(token)
+				signed, _ := hs256.SignedString([]byte("shared-secret"))
+
+				w := requestAPI(http.MethodGet, "/me/threads", signed, nil)
+				Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			})
+		})
+	})
+})
+
+func tokenKeyID(tokenString string) string {
+	header, _ := base64.RawURLEncoding.DecodeString(strings.Split(tokenString, ".")[0])
+	var decoded struct {
+		Kid string `json:"kid"`
+	}
+	json.Unmarshal(header, &decoded)
+
+	return decoded.Kid
+}
+
+// DBに保存されている現在の鍵で任意のクレームに署名する
+func signWithCurrentKey(claims jwt.MapClaims) string {
+	var key model.SigningKey
+	Expect(db.First(&key, "key_id = ?", tokenKeyID(token)).Error).To(BeNil())
+
+	block, _ := pem.Decode(decryptStoredSigningKey(key.PrivateKey))
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	Expect(err).To(BeNil())
+
+	signed := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	signed.Header["kid"] = key.KeyID
+	tokenString, err := signed.SignedString(privateKey.(crypto.Signer))
+	Expect(err).To(BeNil())
+
+	return tokenString
+}
+
+// JWT_KEY_ENCRYPTION_KEYで暗号化して保存された秘密鍵をPEMに戻す
+func decryptStoredSigningKey(stored string) []byte {
+	encryptionKey, err := hex.DecodeString(os.Getenv("JWT_KEY_ENCRYPTION_KEY"))
+	Expect(err).To(BeNil())
+	block, err := aes.NewCipher(encryptionKey)
+	Expect(err).To(BeNil())
+	aead, err := cipher.NewGCM(block)
+	Expect(err).To(BeNil())
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, "aes256gcm:"))
+	Expect(err).To(BeNil())
+	decrypted, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	Expect(err).To(BeNil())
+
+	return decrypted
+}
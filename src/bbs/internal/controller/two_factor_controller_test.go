@@ -0,0 +1,301 @@
+package controller_test
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/totp"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type TwoFactorEnrollResponse struct {
+	Data dto.TwoFactorEnrollOutput `json:"data"`
+}
+
+type RecoveryCodesResponse struct {
+	Data dto.RecoveryCodesOutput `json:"data"`
+}
+
+type TwoFactorStatusResponse struct {
+	Data dto.TwoFactorStatusOutput `json:"data"`
+}
+
+var _ = Describe("TwoFactorController", func() {
+	BeforeEach(func() {
+		defaultBeforeEachFunc()
+	})
+
+	AfterEach(func() {
+		defaultAfterEachFunc()
+	})
+
+	Describe("二段階認証の登録", func() {
+		It("認証アプリに登録するotpauth URIを返す", func() {
+			w := requestAPI(http.MethodPost, "/auth/2fa/enroll", token, nil)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			var res TwoFactorEnrollResponse
+			json.Unmarshal(w.Body.Bytes(), &res)
+
+			uri, err := url.Parse(res.Data.OTPAuthURI)
+			Expect(err).To(BeNil())
+			Expect(uri.Scheme).To(Equal("otpauth"))
+			Expect(uri.Host).To(Equal("totp"))
+			Expect(uri.Query().Get("secret")).To(Equal(res.Data.Secret))
+			Expect(uri.Query().Get("issuer")).To(Equal("bbs"))
+			Expect(uri.Path).To(ContainSubstring(user.Email))
+		})
+
+		It("登録しただけではログインに影響しない", func() {
+			requestAPI(http.MethodPost, "/auth/2fa/enroll", token, nil)
+
+			res := loginWithPassword(user.Email)
+			Expect(res.Token).NotTo(BeNil())
+			Expect(res.TwoFactorRequired).To(BeFalse())
+		})
+
+		It("有効化済みの場合は409エラーを返す", func() {
+			enableTwoFactor()
+
+			w := requestAPI(http.MethodPost, "/auth/2fa/enroll", token, nil)
+
+			Expect(w.Code).To(Equal(http.StatusConflict))
+		})
+	})
+
+	Describe("二段階認証の有効化", func() {
+		It("コードを確認して有効にし、リカバリーコードを返す", func() {
+			secret, recoveryCodes := enableTwoFactor()
+
+			Expect(secret).NotTo(BeEmpty())
+			Expect(recoveryCodes).To(HaveLen(10))
+
+			w := requestAPI(http.MethodGet, "/auth/2fa", token, nil)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			var res TwoFactorStatusResponse
+			json.Unmarshal(w.Body.Bytes(), &res)
+			Expect(res.Data.Enabled).To(BeTrue())
+			Expect(res.Data.RemainingRecoveryCodes).To(Equal(int64(10)))
+		})
+
+		It("リカバリーコードはハッシュ化して保存する", func() {
+			_, recoveryCodes := enableTwoFactor()
+
+			var count int64
+			db.Table("recovery_codes").Where("code_hash = ?", recoveryCodes[0]).Count(&count)
+			Expect(count).To(BeZero())
+		})
+
+		It("コードが誤っている場合は400エラーを返し、有効にしない", func() {
+			requestAPI(http.MethodPost, "/auth/2fa/enroll", token, nil)
+
+			body, _ := json.Marshal(dto.TwoFactorCodeInput{Code: "000000"})
+			w := requestAPI(http.MethodPost, "/auth/2fa/enable", token, body)
+			if w.Code == http.StatusOK {
+				// 偶然一致した場合はもう一度試す
+				w = requestAPI(http.MethodPost, "/auth/2fa/enable", token, body)
+			}
+
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			res := loginWithPassword(user.Email)
+			Expect(res.Token).NotTo(BeNil())
+		})
+
+		It("登録前の場合は400エラーを返す", func() {
+			body, _ := json.Marshal(dto.TwoFactorCodeInput{Code: "123456"})
+			w := requestAPI(http.MethodPost, "/auth/2fa/enable", token, body)
+
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("二段階認証が有効な場合のログイン", func() {
+		var secret string
+		var recoveryCodes []string
+
+		BeforeEach(func() {
+			secret, recoveryCodes = enableTwoFactor()
+		})
+
+		It("トークンの代わりにチャレンジトークンを返す", func() {
+			res := loginWithPassword(user.Email)
+
+			Expect(res.TwoFactorRequired).To(BeTrue())
+			Expect(res.Token).To(BeNil())
+			Expect(res.ChallengeToken).NotTo(BeNil())
+		})
+
+		It("チャレンジトークンは認証に使えない", func() {
+			res := loginWithPassword(user.Email)
+
+			w := requestAPI(http.MethodGet, "/me/threads", *res.ChallengeToken, nil)
+
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("認証アプリのコードを検証してトークンを返す", func() {
+			res := loginWithPassword(user.Email)
+
+			w := verifyTwoFactorLogin(*res.ChallengeToken, nextTOTPCode(secret))
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			var verified dto.LoginOutput
+			json.Unmarshal(w.Body.Bytes(), &verified)
+			Expect(verified.Token).NotTo(BeNil())
+
+			w = requestAPI(http.MethodGet, "/me/threads", *verified.Token, nil)
+			Expect(w.Code).To(Equal(http.StatusOK))
+		})
+
+		It("使用済みのコードは再利用できない", func() {
+			res := loginWithPassword(user.Email)
+			code := nextTOTPCode(secret)
+			verifyTwoFactorLogin(*res.ChallengeToken, code)
+
+			w := verifyTwoFactorLogin(*res.ChallengeToken, code)
+
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("リカバリーコードは一度だけ使える", func() {
+			res := loginWithPassword(user.Email)
+
+			w := verifyTwoFactorLogin(*res.ChallengeToken, strings.ToUpper(strings.ReplaceAll(recoveryCodes[0], "-", "")))
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			w = verifyTwoFactorLogin(*res.ChallengeToken, recoveryCodes[0])
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+
+			w = requestAPI(http.MethodGet, "/auth/2fa", token, nil)
+			var status TwoFactorStatusResponse
+			json.Unmarshal(w.Body.Bytes(), &status)
+			Expect(status.Data.RemainingRecoveryCodes).To(Equal(int64(9)))
+		})
+
+		It("チャレンジトークンが不正な場合は401エラーを返す", func() {
+			w := verifyTwoFactorLogin(token, nextTOTPCode(secret))
+
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("続けて5回失敗すると正しいコードでも429エラーを返す", func() {
+			res := loginWithPassword(user.Email)
+			for i := 0; i < 5; i++ {
+				w := verifyTwoFactorLogin(*res.ChallengeToken, "000000")
+				Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			}
+
+			w := verifyTwoFactorLogin(*res.ChallengeToken, nextTOTPCode(secret))
+			Expect(w.Code).To(Equal(http.StatusTooManyRequests))
+
+			// チャレンジを取り直しても解除されない
+			res = loginWithPassword(user.Email)
+			w = verifyTwoFactorLogin(*res.ChallengeToken, recoveryCodes[0])
+			Expect(w.Code).To(Equal(http.StatusTooManyRequests))
+		})
+
+		It("成功すると失敗した回数は数え直す", func() {
+			res := loginWithPassword(user.Email)
+			for i := 0; i < 4; i++ {
+				verifyTwoFactorLogin(*res.ChallengeToken, "000000")
+			}
+			w := verifyTwoFactorLogin(*res.ChallengeToken, nextTOTPCode(secret))
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			for i := 0; i < 4; i++ {
+				verifyTwoFactorLogin(*res.ChallengeToken, "000000")
+			}
+			w = verifyTwoFactorLogin(*res.ChallengeToken, recoveryCodes[0])
+			Expect(w.Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("二段階認証の無効化", func() {
+		It("コードを確認して無効にし、パスワードだけでログインできるようにする", func() {
+			_, recoveryCodes := enableTwoFactor()
+
+			body, _ := json.Marshal(dto.TwoFactorCodeInput{Code: recoveryCodes[0]})
+			w := requestAPI(http.MethodPost, "/auth/2fa/disable", token, body)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			res := loginWithPassword(user.Email)
+			Expect(res.Token).NotTo(BeNil())
+
+			var count int64
+			db.Table("recovery_codes").Where("user_id = ?", user.ID).Count(&count)
+			Expect(count).To(BeZero())
+		})
+
+		It("失敗した回数とロックも解除する", func() {
+			_, recoveryCodes := enableTwoFactor()
+			db.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{"totp_failed_attempts": 4, "totp_locked_until": time.Now().Add(-time.Minute)})
+
+			body, _ := json.Marshal(dto.TwoFactorCodeInput{Code: recoveryCodes[0]})
+			w := requestAPI(http.MethodPost, "/auth/2fa/disable", token, body)
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			var disabled model.User
+			db.First(&disabled, user.ID)
+			Expect(disabled.TOTPFailedAttempts).To(BeZero())
+			Expect(disabled.TOTPLockedUntil).To(BeNil())
+		})
+
+		It("コードが誤っている場合は400エラーを返す", func() {
+			enableTwoFactor()
+
+			body, _ := json.Marshal(dto.TwoFactorCodeInput{Code: "invalid-code"})
+			w := requestAPI(http.MethodPost, "/auth/2fa/disable", token, body)
+
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			res := loginWithPassword(user.Email)
+			Expect(res.TwoFactorRequired).To(BeTrue())
+		})
+	})
+})
+
+// 登録と有効化を行い、秘密鍵とリカバリーコードを返す
+func enableTwoFactor() (string, []string) {
+	w := requestAPI(http.MethodPost, "/auth/2fa/enroll", token, nil)
+	Expect(w.Code).To(Equal(http.StatusOK))
+	var enrollRes TwoFactorEnrollResponse
+	json.Unmarshal(w.Body.Bytes(), &enrollRes)
+
+	code, err := totp.Code(enrollRes.Data.Secret, totp.Step(time.Now()))
+	Expect(err).To(BeNil())
+	body, _ := json.Marshal(dto.TwoFactorCodeInput{Code: code})
+	w = requestAPI(http.MethodPost, "/auth/2fa/enable", token, body)
+	Expect(w.Code).To(Equal(http.StatusOK))
+	var enableRes RecoveryCodesResponse
+	json.Unmarshal(w.Body.Bytes(), &enableRes)
+
+	return enrollRes.Data.Secret, enableRes.Data.RecoveryCodes
+}
+
+// 有効化で使ったステップは使用済みのため、許容される範囲内の次のステップのコードを使う
+func nextTOTPCode(secret string) string {
+	code, err := totp.Code(secret, totp.Step(time.Now())+1)
+	Expect(err).To(BeNil())
+	return code
+}
+
+func loginWithPassword(email string) dto.LoginOutput {
+	body, _ := json.Marshal(loginRequest{Email: email, Password: password})
+	w := requestAPI(http.MethodPost, "/auth/login", "", body)
+	Expect(w.Code).To(Equal(http.StatusOK))
+
+	var res dto.LoginOutput
+	json.Unmarshal(w.Body.Bytes(), &res)
+	return res
+}
+
+func verifyTwoFactorLogin(challengeToken string, code string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(dto.TwoFactorLoginInput{ChallengeToken: challengeToken, Code: code})
+	return requestAPI(http.MethodPost, "/auth/login/2fa", "", body)
+}
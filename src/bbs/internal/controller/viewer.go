@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"bbs/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 閲覧中のユーザー。認証を必要としないエンドポイントで未ログインの場合はnilを返す
+func currentUser(ctx *gin.Context) *model.User {
+	user, exists := ctx.Get("user")
+	if !exists {
+		return nil
+	}
+	return user.(*model.User)
+}
+
+// 閲覧中のユーザーのID。未ログインの場合は0を返す
+func viewerId(ctx *gin.Context) uint {
+	user := currentUser(ctx)
+	if user == nil {
+		return 0
+	}
+	return user.ID
+}
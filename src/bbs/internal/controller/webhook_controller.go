@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookController struct {
+	service service.IWebhookService
+}
+
+func NewWebhookController(service service.IWebhookService) IWebhookController {
+	return &WebhookController{service: service}
+}
+
+func (c *WebhookController) Create(ctx *gin.Context) {
+	admin, exists := ctx.Get("user")
+	if !exists {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	adminId := admin.(*model.User).ID
+
+	var input dto.CreateWebhookInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := c.service.Create(ctx.Request.Context(), input, adminId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": webhook})
+}
+
+func (c *WebhookController) FindAll(ctx *gin.Context) {
+	webhooks, err := c.service.FindAll(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": webhooks})
+}
+
+func (c *WebhookController) Delete(ctx *gin.Context) {
+	webhookId, err := strconv.ParseUint(ctx.Param("webhookId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook id"})
+		return
+	}
+
+	err = c.service.Delete(ctx.Request.Context(), uint(webhookId))
+	if err != nil {
+		if err.Error() == "webhook not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (c *WebhookController) FindDeliveries(ctx *gin.Context) {
+	webhookId, err := strconv.ParseUint(ctx.Param("webhookId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook id"})
+		return
+	}
+
+	limit, offset, err := parsePagination(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deliveries, err := c.service.FindDeliveries(ctx.Request.Context(), uint(webhookId), limit, offset)
+	if err != nil {
+		if err.Error() == "webhook not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": deliveries})
+}
+
+// 送信は非同期に行うため、作成した配信を返す
+func (c *WebhookController) Redeliver(ctx *gin.Context) {
+	webhookId, err := strconv.ParseUint(ctx.Param("webhookId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook id"})
+		return
+	}
+
+	deliveryId, err := strconv.ParseUint(ctx.Param("deliveryId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery id"})
+		return
+	}
+
+	delivery, err := c.service.Redeliver(ctx.Request.Context(), uint(webhookId), uint(deliveryId))
+	if err != nil {
+		if err.Error() == "webhook delivery not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Unexpected error"})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"data": delivery})
+}
@@ -0,0 +1,338 @@
+package controller_test
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"bbs/internal/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+)
+
+type createdWebhook struct {
+	model.Webhook
+	Secret string `json:"secret"`
+}
+
+type CreateWebhookResponse struct {
+	Data createdWebhook `json:"data"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Data dto.WebhookDeliveryListOutput `json:"data"`
+}
+
+type WebhookDeliveryResponse struct {
+	Data model.WebhookDelivery `json:"data"`
+}
+
+type receivedWebhook struct {
+	Header http.Header
+	Body   []byte
+}
+
+// Webhookを受信するローカルのHTTPサーバー
+type webhookReceiver struct {
+	server     *httptest.Server
+	mu         sync.Mutex
+	statusCode int
+	received   []receivedWebhook
+}
+
+func newWebhookReceiver() *webhookReceiver {
+	receiver := &webhookReceiver{statusCode: http.StatusOK}
+	receiver.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.received = append(receiver.received, receivedWebhook{Header: req.Header.Clone(), Body: body})
+		w.WriteHeader(receiver.statusCode)
+	}))
+	return receiver
+}
+
+func (r *webhookReceiver) setStatusCode(statusCode int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statusCode = statusCode
+}
+
+func (r *webhookReceiver) requests() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.received...)
+}
+
+var _ = Describe("WebhookController", func() {
+	var receiver *webhookReceiver
+	var adminToken string
+
+	BeforeEach(func() {
+		defaultBeforeEachFunc()
+		receiver = newWebhookReceiver()
+		DeferCleanup(receiver.server.Close)
+		adminToken = getAdminAuthToken()
+	})
+
+	AfterEach(func() {
+		defaultAfterEachFunc()
+	})
+
+	Describe("Webhookの登録", func() {
+		It("管理者以外の場合は403エラーを返す", func() {
+			body, _ := json.Marshal(dto.CreateWebhookInput{URL: receiver.server.URL, Events: []string{model.WebhookEventThreadCreated}})
+			w := requestAPI(http.MethodPost, "/admin/webhooks", token, body)
+
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("不明なイベントの場合は400エラーを返す", func() {
+			body, _ := json.Marshal(dto.CreateWebhookInput{URL: receiver.server.URL, Events: []string{"user.created"}})
+			w := requestAPI(http.MethodPost, "/admin/webhooks", adminToken, body)
+
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("イベントの配信", func() {
+		It("スレッドの作成をHMAC-SHA256で署名して送信し、配信記録に残す", func() {
+			webhook := createWebhook(adminToken, receiver.server.URL, nil, model.WebhookEventThreadCreated)
+
+			body, _ := json.Marshal(dto.CreateThreadInput{Title: "テストタイトル", Body: "テスト本文"})
+			w := requestAPI(http.MethodPost, "/threads", token, body)
+			Expect(w.Code).To(Equal(http.StatusCreated))
+			Expect(receiver.requests()).To(BeEmpty())
+
+			Expect(deliverWebhooks()).To(Equal(1))
+
+			requests := receiver.requests()
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].Header.Get("X-BBS-Event")).To(Equal(model.WebhookEventThreadCreated))
+			signature := service.SignWebhookPayload(webhook.Secret, requests[0].Header.Get("X-BBS-Timestamp"), requests[0].Body)
+			Expect(requests[0].Header.Get("X-BBS-Signature")).To(Equal(signature))
+
+			var payload struct {
+				Event string       `json:"event"`
+				Data  model.Thread `json:"data"`
+			}
+			json.Unmarshal(requests[0].Body, &payload)
+			Expect(payload.Event).To(Equal(model.WebhookEventThreadCreated))
+			Expect(payload.Data.Title).To(Equal("テストタイトル"))
+
+			deliveries := findWebhookDeliveries(adminToken, webhook.ID)
+			Expect(deliveries.Total).To(Equal(int64(1)))
+			Expect(deliveries.Deliveries[0].Status).To(Equal(model.WebhookDeliveryStatusSucceeded))
+			Expect(*deliveries.Deliveries[0].ResponseStatus).To(Equal(http.StatusOK))
+		})
+
+		It("購読していないイベントや公開範囲を限定したスレッドのイベントは送信しない", func() {
+			createWebhook(adminToken, receiver.server.URL, nil, model.WebhookEventCommentCreated)
+
+			body, _ := json.Marshal(dto.CreateThreadInput{Title: "テストタイトル", Body: "テスト本文"})
+			requestAPI(http.MethodPost, "/threads", token, body)
+
+			thread := createTestThread(db, user.ID, 1)[0]
+			db.Model(&thread).Update("visibility", model.VisibilityMembers)
+			body, _ = json.Marshal(dto.CreateComment{Body: "コメント"})
+			w := requestAPI(http.MethodPost, fmt.Sprintf("/threads/%d/comments", thread.ID), token, body)
+			Expect(w.Code).To(Equal(http.StatusCreated))
+
+			Expect(deliverWebhooks()).To(BeZero())
+		})
+
+		It("スレッドを指定した場合はそのスレッドのイベントだけを送信する", func() {
+			threads := createTestThread(db, user.ID, 2)
+			createWebhook(adminToken, receiver.server.URL, &threads[0].ID, model.WebhookEventCommentCreated)
+
+			body, _ := json.Marshal(dto.CreateComment{Body: "コメント"})
+			requestAPI(http.MethodPost, fmt.Sprintf("/threads/%d/comments", threads[0].ID), token, body)
+			requestAPI(http.MethodPost, fmt.Sprintf("/threads/%d/comments", threads[1].ID), token, body)
+
+			Expect(deliverWebhooks()).To(Equal(1))
+		})
+
+		It("匿名のスレッドは投稿者を伏せて送信する", func() {
+			createWebhook(adminToken, receiver.server.URL, nil, model.WebhookEventThreadCreated)
+
+			body, _ := json.Marshal(dto.CreateThreadInput{Title: "テストタイトル", Body: "テスト本文", Anonymous: true})
+			requestAPI(http.MethodPost, "/threads", token, body)
+			deliverWebhooks()
+
+			var payload struct {
+				Data model.Thread `json:"data"`
+			}
+			json.Unmarshal(receiver.requests()[0].Body, &payload)
+			Expect(payload.Data.UserID).To(BeZero())
+			Expect(payload.Data.PosterID).NotTo(BeEmpty())
+		})
+
+		It("スレッドの削除を送信する", func() {
+			createWebhook(adminToken, receiver.server.URL, nil, model.WebhookEventThreadDeleted)
+			thread := createTestThread(db, user.ID, 1)[0]
+
+			w := requestAPI(http.MethodDelete, fmt.Sprintf("/threads/%d", thread.ID), token, nil)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			deliverWebhooks()
+
+			var payload struct {
+				Data dto.WebhookDeletedData `json:"data"`
+			}
+			json.Unmarshal(receiver.requests()[0].Body, &payload)
+			Expect(payload.Data.ID).To(Equal(thread.ID))
+		})
+
+		Context("配信の登録に失敗した場合", func() {
+			BeforeEach(func() {
+				db.Callback().Create().Before("gorm:create").Register("test:fail_delivery", func(tx *gorm.DB) {
+					if tx.Statement.Table == "webhook_deliveries" {
+						tx.AddError(errors.New("delivery insert failed"))
+					}
+				})
+				DeferCleanup(func() {
+					db.Callback().Create().Remove("test:fail_delivery")
+				})
+			})
+
+			It("スレッドもロールバックされる", func() {
+				createWebhook(adminToken, receiver.server.URL, nil, model.WebhookEventThreadCreated)
+
+				body, _ := json.Marshal(dto.CreateThreadInput{Title: "テストタイトル", Body: "テスト本文"})
+				w := requestAPI(http.MethodPost, "/threads", token, body)
+				Expect(w.Code).To(Equal(http.StatusInternalServerError))
+
+				var threadCount int64
+				db.Model(&model.Thread{}).Where("user_id = ?", user.ID).Count(&threadCount)
+				Expect(threadCount).To(BeZero())
+			})
+
+			It("コメントもロールバックされる", func() {
+				createWebhook(adminToken, receiver.server.URL, nil, model.WebhookEventCommentCreated)
+				thread := createTestThread(db, user.ID, 1)[0]
+
+				body, _ := json.Marshal(dto.CreateComment{Body: "コメント"})
+				w := requestAPI(http.MethodPost, fmt.Sprintf("/threads/%d/comments", thread.ID), token, body)
+				Expect(w.Code).To(Equal(http.StatusInternalServerError))
+
+				var commentCount int64
+				db.Model(&model.Comment{}).Where("thread_id = ?", thread.ID).Count(&commentCount)
+				Expect(commentCount).To(BeZero())
+			})
+		})
+	})
+
+	Describe("配信の再試行", func() {
+		It("失敗した場合は間隔を空けて再送する", func() {
+			webhook := createWebhook(adminToken, receiver.server.URL, nil, model.WebhookEventThreadCreated)
+			receiver.setStatusCode(http.StatusInternalServerError)
+
+			body, _ := json.Marshal(dto.CreateThreadInput{Title: "テストタイトル", Body: "テスト本文"})
+			requestAPI(http.MethodPost, "/threads", token, body)
+			Expect(deliverWebhooks()).To(Equal(1))
+
+			delivery := findWebhookDeliveries(adminToken, webhook.ID).Deliveries[0]
+			Expect(delivery.Status).To(Equal(model.WebhookDeliveryStatusPending))
+			Expect(delivery.Attempts).To(Equal(1))
+			Expect(*delivery.ResponseStatus).To(Equal(http.StatusInternalServerError))
+			Expect(delivery.NextAttemptAt.After(time.Now().Add(20 * time.Second))).To(BeTrue())
+
+			// 再送の時刻になるまでは送信しない
+			Expect(deliverWebhooks()).To(BeZero())
+
+			receiver.setStatusCode(http.StatusOK)
+			db.Model(&model.WebhookDelivery{}).Where("id = ?", delivery.ID).Update("next_attempt_at", time.Now().Add(-time.Second))
+			Expect(deliverWebhooks()).To(Equal(1))
+
+			delivery = findWebhookDeliveries(adminToken, webhook.ID).Deliveries[0]
+			Expect(delivery.Status).To(Equal(model.WebhookDeliveryStatusSucceeded))
+			Expect(delivery.Attempts).To(Equal(2))
+		})
+
+		It("上限まで失敗した場合は失敗とし、再送しない", func() {
+			webhook := createWebhook(adminToken, receiver.server.URL, nil, model.WebhookEventThreadCreated)
+			receiver.setStatusCode(http.StatusInternalServerError)
+
+			body, _ := json.Marshal(dto.CreateThreadInput{Title: "テストタイトル", Body: "テスト本文"})
+			requestAPI(http.MethodPost, "/threads", token, body)
+			db.Model(&model.WebhookDelivery{}).Where("webhook_id = ?", webhook.ID).Update("attempts", 7)
+			deliverWebhooks()
+
+			delivery := findWebhookDeliveries(adminToken, webhook.ID).Deliveries[0]
+			Expect(delivery.Status).To(Equal(model.WebhookDeliveryStatusFailed))
+			Expect(delivery.NextAttemptAt).To(BeNil())
+		})
+	})
+
+	Describe("手動での再送", func() {
+		It("同じ内容の配信を新しく作成して送信する", func() {
+			webhook := createWebhook(adminToken, receiver.server.URL, nil, model.WebhookEventThreadCreated)
+			body, _ := json.Marshal(dto.CreateThreadInput{Title: "テストタイトル", Body: "テスト本文"})
+			requestAPI(http.MethodPost, "/threads", token, body)
+			deliverWebhooks()
+			original := findWebhookDeliveries(adminToken, webhook.ID).Deliveries[0]
+
+			w := requestAPI(http.MethodPost, fmt.Sprintf("/admin/webhooks/%d/deliveries/%d/redeliver", webhook.ID, original.ID), adminToken, nil)
+
+			Expect(w.Code).To(Equal(http.StatusAccepted))
+			var res WebhookDeliveryResponse
+			json.Unmarshal(w.Body.Bytes(), &res)
+			Expect(*res.Data.RedeliveryOf).To(Equal(original.ID))
+			Expect(res.Data.Payload).To(Equal(original.Payload))
+
+			Expect(deliverWebhooks()).To(Equal(1))
+			requests := receiver.requests()
+			Expect(requests).To(HaveLen(2))
+			Expect(requests[1].Body).To(Equal(requests[0].Body))
+		})
+
+		It("他のWebhookの配信の場合は404エラーを返す", func() {
+			webhook := createWebhook(adminToken, receiver.server.URL, nil, model.WebhookEventThreadCreated)
+			otherWebhook := createWebhook(adminToken, receiver.server.URL, nil, model.WebhookEventThreadCreated)
+			body, _ := json.Marshal(dto.CreateThreadInput{Title: "テストタイトル", Body: "テスト本文"})
+			requestAPI(http.MethodPost, "/threads", token, body)
+			delivery := findWebhookDeliveries(adminToken, webhook.ID).Deliveries[0]
+
+			w := requestAPI(http.MethodPost, fmt.Sprintf("/admin/webhooks/%d/deliveries/%d/redeliver", otherWebhook.ID, delivery.ID), adminToken, nil)
+
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+})
+
+func createWebhook(adminToken string, url string, threadId *uint, events ...string) createdWebhook {
+	body, _ := json.Marshal(dto.CreateWebhookInput{URL: url, Events: events, ThreadID: threadId})
+	w := requestAPI(http.MethodPost, "/admin/webhooks", adminToken, body)
+	Expect(w.Code).To(Equal(http.StatusCreated))
+
+	var res CreateWebhookResponse
+	json.Unmarshal(w.Body.Bytes(), &res)
+	Expect(res.Data.Secret).NotTo(BeEmpty())
+	return res.Data
+}
+
+func findWebhookDeliveries(adminToken string, webhookId uint) dto.WebhookDeliveryListOutput {
+	w := requestAPI(http.MethodGet, fmt.Sprintf("/admin/webhooks/%d/deliveries", webhookId), adminToken, nil)
+	Expect(w.Code).To(Equal(http.StatusOK))
+
+	var res WebhookDeliveryListResponse
+	json.Unmarshal(w.Body.Bytes(), &res)
+	return res.Data
+}
+
+// 本番では定期的に実行される配信処理を同期的に実行する
+func deliverWebhooks() int {
+	dispatcher := service.NewWebhookDispatcher(repository.NewWebhookRepository(db), http.DefaultClient)
+	sent, err := dispatcher.DeliverPending(context.Background())
+	Expect(err).To(BeNil())
+	return sent
+}
@@ -0,0 +1,83 @@
+package cron_test
+
+import (
+	"bbs/internal/cron"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCron(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cron Suite")
+}
+
+func at(value string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", value)
+	Expect(err).To(BeNil())
+	return t
+}
+
+var _ = Describe("Parse", func() {
+	DescribeTable("不正な書式の場合はエラーを返す",
+		func(spec string) {
+			_, err := cron.Parse(spec)
+			Expect(err).NotTo(BeNil())
+		},
+		Entry("空", ""),
+		Entry("フィールドが足りない", "* * * *"),
+		Entry("フィールドが多い", "* * * * * *"),
+		Entry("分が範囲外", "60 * * * *"),
+		Entry("時が範囲外", "* 24 * * *"),
+		Entry("日が0", "* * 0 * *"),
+		Entry("月が範囲外", "* * * 13 *"),
+		Entry("曜日が範囲外", "* * * * 8"),
+		Entry("数値でない", "a * * * *"),
+		Entry("範囲が逆", "30-10 * * * *"),
+		Entry("間隔が0", "*/0 * * * *"),
+		Entry("間隔が数値でない", "*/x * * * *"),
+		Entry("未知の略記", "@minutely"),
+		Entry("不正な間隔", "@every abc"),
+		Entry("0以下の間隔", "@every 0s"),
+	)
+})
+
+var _ = Describe("Next", func() {
+	DescribeTable("次に実行する時刻を返す",
+		func(spec string, from string, expected string) {
+			schedule, err := cron.Parse(spec)
+			Expect(err).To(BeNil())
+			Expect(schedule.Next(at(from))).To(Equal(at(expected)))
+		},
+		Entry("毎分", "* * * * *", "2024-09-03 10:07", "2024-09-03 10:08"),
+		Entry("同じ分の途中からは次の分", "30 10 * * *", "2024-09-03 10:30", "2024-09-04 10:30"),
+		Entry("*/15", "*/15 * * * *", "2024-09-03 10:07", "2024-09-03 10:15"),
+		Entry("*/15で時をまたぐ", "*/15 * * * *", "2024-09-03 10:45", "2024-09-03 11:00"),
+		Entry("1-30/5", "1-30/5 * * * *", "2024-09-03 10:02", "2024-09-03 10:06"),
+		Entry("1-30/5の範囲外からは次の時", "1-30/5 * * * *", "2024-09-03 10:26", "2024-09-03 11:01"),
+		Entry("開始値と間隔", "5/20 * * * *", "2024-09-03 10:46", "2024-09-03 11:05"),
+		Entry("カンマ区切り", "0 9,18 * * *", "2024-09-03 10:00", "2024-09-03 18:00"),
+		Entry("月をまたぐ", "0 0 1 * *", "2024-01-31 12:00", "2024-02-01 00:00"),
+		Entry("年をまたぐ", "0 0 1 * *", "2024-12-15 00:00", "2025-01-01 00:00"),
+		Entry("31日のない月は飛ばす", "0 0 31 * *", "2024-04-01 00:00", "2024-05-31 00:00"),
+		Entry("うるう日", "0 0 29 2 *", "2025-01-01 00:00", "2028-02-29 00:00"),
+		Entry("日曜日を0で指定", "0 9 * * 0", "2024-09-03 00:00", "2024-09-08 09:00"),
+		Entry("日曜日を7で指定", "0 9 * * 7", "2024-09-03 00:00", "2024-09-08 09:00"),
+		Entry("日と曜日はどちらかに一致すればよい(曜日)", "0 0 1 * 1", "2024-09-03 00:00", "2024-09-09 00:00"),
+		Entry("日と曜日はどちらかに一致すればよい(日)", "0 0 1 * 1", "2024-09-30 00:00", "2024-10-01 00:00"),
+		Entry("曜日が*の場合は日だけで決まる", "0 0 13 * *", "2024-09-01 00:00", "2024-09-13 00:00"),
+		Entry("日が*の場合は曜日だけで決まる", "0 0 * * 5", "2024-09-01 00:00", "2024-09-06 00:00"),
+		Entry("@daily", "@daily", "2024-09-03 10:00", "2024-09-04 00:00"),
+		Entry("@weekly", "@weekly", "2024-09-03 10:00", "2024-09-08 00:00"),
+		Entry("@every", "@every 90m", "2024-09-03 10:00", "2024-09-03 11:30"),
+	)
+
+	It("存在しない日付しか一致しない場合はゼロ値を返す", func() {
+		schedule, err := cron.Parse("0 0 30 2 *")
+		Expect(err).To(BeNil())
+
+		Expect(schedule.Next(at("2024-01-01 00:00")).IsZero()).To(BeTrue())
+	})
+})
@@ -0,0 +1,16 @@
+package dto
+
+import "bbs/internal/model"
+
+// ExpiresInDaysを省略した場合は無期限
+type CreateAPITokenInput struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,unique,dive,oneof=read write:threads write:comments"`
+	ExpiresInDays int      `json:"expiresInDays" binding:"omitempty,min=1,max=365"`
+}
+
+// トークンは作成時にしか確認できない
+type CreateAPITokenOutput struct {
+	*model.APIToken
+	Token string `json:"token"`
+}
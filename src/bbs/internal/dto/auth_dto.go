@@ -10,3 +10,20 @@ type LoginInput struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8"`
 }
+
+type OIDCAuthorizeOutput struct {
+	AuthorizationURL string `json:"authorizationUrl"`
+	State            string `json:"state"`
+}
+
+type OIDCCallbackInput struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// 二段階認証が有効なユーザーはTokenの代わりにChallengeTokenを受け取り、コードを検証してからTokenを受け取る
+type LoginOutput struct {
+	Token             *string `json:"token,omitempty"`
+	TwoFactorRequired bool    `json:"twoFactorRequired"`
+	ChallengeToken    *string `json:"challengeToken,omitempty"`
+}
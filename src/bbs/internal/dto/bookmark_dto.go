@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"bbs/internal/model"
+	"time"
+)
+
+type SaveBookmarkInput struct {
+	Note string `json:"note" binding:"max=1000"`
+}
+
+// 対象が削除された場合や閲覧できなくなった場合はDeletedをtrueにし、内容は含めない
+type BookmarkOutput struct {
+	ID        uint           `json:"id"`
+	ThreadID  uint           `json:"threadId"`
+	CommentID *uint          `json:"commentId"`
+	Note      string         `json:"note"`
+	Deleted   bool           `json:"deleted"`
+	Thread    *model.Thread  `json:"thread,omitempty"`
+	Comment   *model.Comment `json:"comment,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+}
+
+type BookmarkListOutput struct {
+	Total     int64            `json:"total"`
+	Bookmarks []BookmarkOutput `json:"bookmarks"`
+}
@@ -1,9 +1,30 @@
 package dto
 
+import "bbs/internal/model"
+
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
 type CreateComment struct {
-	Body string `json:"body" binding:"required"`
+	Body          string `json:"body" binding:"required,max=10000"`
+	AttachmentIDs []uint `json:"attachmentIds"`
 }
 
 type UpdateComment struct {
-	Body string `json:"body" binding:"required"`
+	Body string `json:"body" binding:"required,max=10000"`
+}
+
+// Offsetは0始まりのページ番号。AuthorIDを指定した場合はそのユーザーのコメントのみ
+type CommentListQuery struct {
+	Limit    int
+	Offset   int
+	Order    string
+	AuthorID *uint
+}
+
+type CommentListOutput struct {
+	Total    int64           `json:"total"`
+	Comments []model.Comment `json:"comments"`
 }
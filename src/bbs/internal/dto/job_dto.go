@@ -0,0 +1,8 @@
+package dto
+
+import "bbs/internal/model"
+
+type JobListOutput struct {
+	Total int64       `json:"total"`
+	Jobs  []model.Job `json:"jobs"`
+}
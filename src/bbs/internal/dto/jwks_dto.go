@@ -0,0 +1,17 @@
+package dto
+
+// RFC 7517のJSON Web Key。RSAの場合はnとe、Ed25519の場合はcrvとxを使う
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSOutput struct {
+	Keys []JWK `json:"keys"`
+}
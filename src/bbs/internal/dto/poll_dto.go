@@ -0,0 +1,15 @@
+package dto
+
+import "time"
+
+type CreatePollInput struct {
+	Question       string     `json:"question" binding:"required,max=200"`
+	Options        []string   `json:"options" binding:"required,min=2,max=20,unique,dive,required,max=100"`
+	MultipleChoice bool       `json:"multipleChoice"`
+	PublicVotes    bool       `json:"publicVotes"`
+	ClosesAt       *time.Time `json:"closesAt"`
+}
+
+type VotePollInput struct {
+	OptionIDs []uint `json:"optionIds" binding:"required,min=1"`
+}
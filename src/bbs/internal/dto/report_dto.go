@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"bbs/internal/model"
+	"time"
+)
+
+type CreateReportInput struct {
+	Reason string `json:"reason" binding:"required,max=1000"`
+}
+
+type ResolveReportInput struct {
+	Action string `json:"action" binding:"required,oneof=dismiss hide delete"`
+}
+
+// 同じ対象への未対応の通報をまとめたもの
+type ModerationQueueItem struct {
+	ThreadID         uint           `json:"threadId"`
+	CommentID        *uint          `json:"commentId"`
+	ReportCount      int64          `json:"reportCount"`
+	LatestReportedAt time.Time      `json:"latestReportedAt"`
+	Thread           *model.Thread  `json:"thread"`
+	Comment          *model.Comment `json:"comment"`
+	Reports          []model.Report `json:"reports"`
+}
+
+type ModerationQueueOutput struct {
+	Total int64                 `json:"total"`
+	Items []ModerationQueueItem `json:"items"`
+}
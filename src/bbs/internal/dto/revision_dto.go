@@ -0,0 +1,16 @@
+package dto
+
+import "time"
+
+type RevisionOutput struct {
+	ID        uint      `json:"id"`
+	Revision  int       `json:"revision"`
+	Title     string    `json:"title,omitempty"`
+	Body      string    `json:"body"`
+	EditedBy  uint      `json:"editedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	// 次の版(最新版を含む)との本文の差分
+	Diff []string `json:"diff"`
+	// 匿名の投稿の版の場合、モデレーター以外には編集者を伏せる
+	Anonymous bool `json:"-"`
+}
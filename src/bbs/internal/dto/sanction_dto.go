@@ -0,0 +1,8 @@
+package dto
+
+// 停止の場合はDurationHoursで期間を指定する。追放は無期限
+type CreateSanctionInput struct {
+	Type          string `json:"type" binding:"required,oneof=suspension ban"`
+	Reason        string `json:"reason" binding:"required,max=1000"`
+	DurationHours int    `json:"durationHours" binding:"required_if=Type suspension,omitempty,min=1"`
+}
@@ -0,0 +1,24 @@
+package dto
+
+import (
+	"bbs/internal/model"
+	"time"
+)
+
+// LastReadCommentIDを省略した場合はすべて既読にする
+type SubscribeInput struct {
+	LastReadCommentID *uint `json:"lastReadCommentId"`
+}
+
+type SubscriptionOutput struct {
+	ThreadID          uint         `json:"threadId"`
+	Thread            model.Thread `json:"thread"`
+	LastReadCommentID uint         `json:"lastReadCommentId"`
+	UnreadCount       int64        `json:"unreadCount"`
+	SubscribedAt      time.Time    `json:"subscribedAt"`
+}
+
+type SubscriptionListOutput struct {
+	Total         int64                `json:"total"`
+	Subscriptions []SubscriptionOutput `json:"subscriptions"`
+}
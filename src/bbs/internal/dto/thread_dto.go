@@ -3,16 +3,33 @@ package dto
 import "bbs/internal/model"
 
 type CreateThreadInput struct {
-	Title string `json:"title" binding:"required"`
-	Body  string `json:"body" binding:"required"`
+	Title          string           `json:"title" binding:"required"`
+	Body           string           `json:"body" binding:"required,max=20000"`
+	AttachmentIDs  []uint           `json:"attachmentIds"`
+	Anonymous      bool             `json:"anonymous"`
+	Visibility     string           `json:"visibility" binding:"omitempty,oneof=public members private"`
+	InvitedUserIDs []uint           `json:"invitedUserIds"`
+	Poll           *CreatePollInput `json:"poll"`
 }
 
 type UpdateThreadInput struct {
 	Title *string `json:"title"`
-	Body  *string `json:"body"`
+	Body  *string `json:"body" binding:"omitempty,max=20000"`
 }
 
 type ThreadListOutput struct {
 	Total   int64          `json:"total"`
 	Threads []model.Thread `json:"threads"`
 }
+
+type UpdateThreadStateInput struct {
+	Pinned   *bool `json:"pinned"`
+	Locked   *bool `json:"locked"`
+	Archived *bool `json:"archived"`
+}
+
+// InvitedUserIDsを省略した場合は招待済みのユーザーを変更しない
+type UpdateThreadVisibilityInput struct {
+	Visibility     string `json:"visibility" binding:"required,oneof=public members private"`
+	InvitedUserIDs []uint `json:"invitedUserIds"`
+}
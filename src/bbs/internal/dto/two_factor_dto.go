@@ -0,0 +1,26 @@
+package dto
+
+type TwoFactorEnrollOutput struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+}
+
+// Codeには認証アプリのコードの他にリカバリーコードも指定できる(有効化の確認を除く)
+type TwoFactorCodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorLoginInput struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// リカバリーコードは発行時にしか確認できない
+type RecoveryCodesOutput struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type TwoFactorStatusOutput struct {
+	Enabled                bool  `json:"enabled"`
+	RemainingRecoveryCodes int64 `json:"remainingRecoveryCodes"`
+}
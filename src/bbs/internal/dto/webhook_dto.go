@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"bbs/internal/model"
+	"time"
+)
+
+// Secretを省略した場合は生成する
+type CreateWebhookInput struct {
+	URL      string   `json:"url" binding:"required,url,startswith=http"`
+	Secret   string   `json:"secret" binding:"omitempty,min=16,max=256"`
+	Events   []string `json:"events" binding:"required,min=1,unique,dive,oneof=thread.created thread.deleted comment.created comment.deleted"`
+	ThreadID *uint    `json:"threadId"`
+}
+
+// 署名の検証に使うシークレットは作成時にしか確認できない
+type CreateWebhookOutput struct {
+	*model.Webhook
+	Secret string `json:"secret"`
+}
+
+type WebhookDeliveryListOutput struct {
+	Total      int64                   `json:"total"`
+	Deliveries []model.WebhookDelivery `json:"deliveries"`
+}
+
+// 通知先に送るリクエストボディ
+type WebhookPayload struct {
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// 削除イベントでは対象のIDだけを送る
+type WebhookDeletedData struct {
+	ID       uint `json:"id"`
+	ThreadID uint `json:"threadId"`
+}
@@ -4,24 +4,79 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+const (
+	DBDriverMySQL    = "mysql"
+	DBDriverPostgres = "postgres"
+	DBDriverSQLite   = "sqlite"
+)
+
+// DB_DRIVERでmysql(未設定の場合)、postgres、sqliteを選択する
 func SetUpDB() *gorm.DB {
-	dsn := fmt.Sprintf(
-		"%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_NAME"),
-	)
-
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	dialector, err := newDialector(os.Getenv("DB_DRIVER"))
+	if err != nil {
+		panic(err)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		panic("failed to connect database")
 	}
 
+	// SQLiteは書き込みが同時に1つしかできないため、接続を1つにして待たせる。
+	// :memory:の場合は接続ごとに別のDBになるため、同じ接続を使い続ける必要もある
+	if dialector.Name() == DBDriverSQLite {
+		sqlDB, err := db.DB()
+		if err != nil {
+			panic("failed to connect database")
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+
 	return db
 }
+
+func newDialector(driver string) (gorm.Dialector, error) {
+	switch driver {
+	case "", DBDriverMySQL:
+		dsn := fmt.Sprintf(
+			"%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			os.Getenv("DB_USER"),
+			os.Getenv("DB_PASSWORD"),
+			os.Getenv("DB_HOST"),
+			os.Getenv("DB_PORT"),
+			os.Getenv("DB_NAME"),
+		)
+		return mysql.Open(dsn), nil
+	case DBDriverPostgres:
+		dsn := fmt.Sprintf(
+			"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
+			os.Getenv("DB_HOST"),
+			os.Getenv("DB_USER"),
+			os.Getenv("DB_PASSWORD"),
+			os.Getenv("DB_NAME"),
+			os.Getenv("DB_PORT"),
+			postgresSSLMode(),
+		)
+		return postgres.Open(dsn), nil
+	case DBDriverSQLite:
+		// DB_NAMEはファイルのパスで、:memory:の場合はプロセス内のメモリに作成する
+		dsn := os.Getenv("DB_NAME") + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+		return sqlite.Open(dsn), nil
+	}
+	return nil, fmt.Errorf("unsupported DB_DRIVER: %s", driver)
+}
+
+// DB_SSLMODEが未設定の場合はdisableとする
+func postgresSSLMode() string {
+	sslMode := os.Getenv("DB_SSLMODE")
+	if sslMode == "" {
+		return "disable"
+	}
+	return sslMode
+}
@@ -2,15 +2,25 @@ package infra
 
 import (
 	"log"
+	"os"
 
 	"github.com/joho/godotenv"
 )
 
+// 未設定のまま起動すると安全でない動作になる環境変数
+var requiredEnvs = []string{
+	// 匿名の投稿者IDのHMACの鍵。空だと誰でも投稿者IDからユーザーを割り出せる
+	"ANONYMOUS_ID_SECRET",
+	// DBに保存するJWTの署名鍵を暗号化する鍵。空だとDBを読めれば誰でもトークンを偽造できる
+	"JWT_KEY_ENCRYPTION_KEY",
+}
+
 func Init() {
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatal("error loading .env file")
 	}
+	checkRequiredEnvs()
 }
 
 func TestInit(envFilePath string) {
@@ -18,4 +28,13 @@ func TestInit(envFilePath string) {
 	if err != nil {
 		log.Fatal("error loading .env.test file", err)
 	}
+	checkRequiredEnvs()
+}
+
+func checkRequiredEnvs() {
+	for _, key := range requiredEnvs {
+		if os.Getenv(key) == "" {
+			log.Fatalf("%s is required", key)
+		}
+	}
 }
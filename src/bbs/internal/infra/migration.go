@@ -0,0 +1,98 @@
+package infra
+
+import (
+	"bbs/internal/markdown"
+	"bbs/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func Migrate(db *gorm.DB) error {
+	if err := migrateBookmarkTarget(db); err != nil {
+		return err
+	}
+	for _, table := range []string{"threads", "comments"} {
+		if err := migrateBodyHTML(db, table); err != nil {
+			return err
+		}
+	}
+
+	return db.AutoMigrate(
+		&model.User{},
+		&model.Thread{},
+		&model.Comment{},
+		&model.ThreadRevision{},
+		&model.CommentRevision{},
+		&model.CommentMention{},
+		&model.CommentReference{},
+		&model.Attachment{},
+		&model.Report{},
+		&model.Sanction{},
+		&model.ThreadMember{},
+		&model.Subscription{},
+		&model.Bookmark{},
+		&model.Poll{},
+		&model.PollOption{},
+		&model.PollVote{},
+		&model.PollVoteChoice{},
+		&model.UserIdentity{},
+		&model.OIDCLoginState{},
+		&model.SigningKey{},
+		&model.RecoveryCode{},
+		&model.APIToken{},
+		&model.Webhook{},
+		&model.WebhookDelivery{},
+		&model.Job{},
+		&model.JobSchedule{},
+	)
+}
+
+// 一意制約を追加する前に、既存のブックマークのtarget_comment_idを埋めて重複を取り除く
+func migrateBookmarkTarget(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&model.Bookmark{}) || migrator.HasColumn(&model.Bookmark{}, "TargetCommentID") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&model.Bookmark{}, "TargetCommentID"); err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE bookmarks SET target_comment_id = COALESCE(comment_id, 0)").Error; err != nil {
+			return err
+		}
+		return tx.Exec("DELETE FROM bookmarks WHERE id NOT IN (SELECT id FROM (SELECT MIN(id) AS id FROM bookmarks GROUP BY user_id, thread_id, target_comment_id) AS kept)").Error
+	})
+}
+
+// body_htmlが追加される前に投稿された本文をHTMLに変換して埋める。
+// 既存の行があるとnot nullの列は追加できないため、null許容で追加して埋めた後にAutoMigrateでnot nullにする
+func migrateBodyHTML(db *gorm.DB, table string) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(table) {
+		return nil
+	}
+	if !migrator.HasColumn(table, "body_html") {
+		if err := db.Exec("ALTER TABLE " + table + " ADD COLUMN body_html text").Error; err != nil {
+			return err
+		}
+	}
+
+	type post struct {
+		ID   uint
+		Body string
+	}
+	var posts []post
+	return db.Table(table).Select("id", "body").Where("body_html IS NULL OR body_html = ''").FindInBatches(&posts, 100, func(tx *gorm.DB, batch int) error {
+		for _, p := range posts {
+			bodyHTML, err := markdown.Render(p.Body)
+			if err != nil {
+				return err
+			}
+			if err := db.Table(table).Where("id = ?", p.ID).Update("body_html", bodyHTML).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
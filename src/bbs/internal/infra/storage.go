@@ -0,0 +1,30 @@
+package infra
+
+import (
+	"bbs/internal/storage"
+	"os"
+)
+
+// STORAGE_DRIVERがs3の場合はS3互換ストレージ、それ以外はローカルのディレクトリに保存する
+func SetUpStorage() storage.Storage {
+	if os.Getenv("STORAGE_DRIVER") == "s3" {
+		st, err := storage.NewS3Storage(storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			UseSSL:    os.Getenv("S3_USE_SSL") == "true",
+		})
+		if err != nil {
+			panic("failed to connect storage")
+		}
+		return st
+	}
+
+	dir := os.Getenv("STORAGE_LOCAL_DIR")
+	if dir == "" {
+		dir = "storage"
+	}
+	return storage.NewLocalStorage(dir)
+}
@@ -0,0 +1,56 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// CommonMarkにGFMのテーブル・取り消し線・自動リンク・タスクリストを加えたもの
+var converter = goldmark.New(
+	goldmark.WithExtensions(
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+		extension.Strikethrough,
+		extension.Linkify,
+		extension.TaskList,
+	),
+	goldmark.WithRendererOptions(html.WithHardWraps()),
+)
+
+// 許可リスト方式のサニタイザ。生のHTMLはgoldmark側でも出力しないが、念のためここでも除去する
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// 行頭の>>123が引用として解釈されないようにエスケープする
+var anchorPattern = regexp.MustCompile(`(?m)^( {0,3})>>(\d+)`)
+
+// Markdownをサニタイズ済みのHTMLに変換する
+func Render(source string) (string, error) {
+	source = anchorPattern.ReplaceAllString(source, `$1\>\>$2`)
+
+	var buf bytes.Buffer
+	if err := converter.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+
+	return Sanitize(buf.String()), nil
+}
+
+// 許可リストにない要素と属性をHTMLから取り除く
+func Sanitize(html string) string {
+	return policy.Sanitize(html)
+}
@@ -0,0 +1,90 @@
+package markdown_test
+
+import (
+	"bbs/internal/markdown"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMarkdown(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Markdown Suite")
+}
+
+var _ = Describe("Render", func() {
+	render := func(source string) string {
+		html, err := markdown.Render(source)
+		Expect(err).To(BeNil())
+		return html
+	}
+
+	It("MarkdownをHTMLに変換する", func() {
+		html := render("**太字** と ~~取り消し~~")
+
+		Expect(html).To(ContainSubstring("<strong>太字</strong>"))
+		Expect(html).To(ContainSubstring("<del>取り消し</del>"))
+	})
+
+	Context("scriptタグが含まれる場合", func() {
+		It("scriptタグを出力しない", func() {
+			html := render("本文<script>alert(1)</script>\n\n<script>\nalert(2)\n</script>")
+
+			Expect(html).NotTo(ContainSubstring("<script"))
+		})
+	})
+
+	Context("javascript:のリンクが含まれる場合", func() {
+		It("href属性を出力しない", func() {
+			html := render("[クリック](javascript:alert(1)) <a href=\"javascript:alert(2)\">a</a>")
+
+			Expect(html).NotTo(ContainSubstring("javascript:"))
+			Expect(html).To(ContainSubstring("クリック"))
+		})
+	})
+
+	Context("on*属性が含まれる場合", func() {
+		It("イベントハンドラの属性を出力しない", func() {
+			html := render("<img src=\"x\" onerror=\"alert(1)\">\n\n<p onclick=\"alert(2)\">p</p>")
+
+			Expect(html).NotTo(ContainSubstring("onerror"))
+			Expect(html).NotTo(ContainSubstring("onclick"))
+		})
+	})
+
+	Context("input要素が含まれる場合", func() {
+		It("タスクリストのチェックボックスは残す", func() {
+			html := render("- [x] 完了")
+
+			Expect(html).To(ContainSubstring(`type="checkbox"`))
+			Expect(html).To(ContainSubstring("checked"))
+		})
+
+		It("checkbox以外のtype属性は取り除く", func() {
+			html := markdown.Sanitize(`<input type="password" name="password"><input type="submit">`)
+
+			Expect(html).NotTo(ContainSubstring("password"))
+			Expect(html).NotTo(ContainSubstring("submit"))
+		})
+	})
+
+	Context("外部へのリンクの場合", func() {
+		It("nofollowと別タブで開く属性を付ける", func() {
+			html := render("https://example.com")
+
+			Expect(html).To(ContainSubstring(`href="https://example.com"`))
+			Expect(html).To(ContainSubstring(`rel="nofollow noopener"`))
+			Expect(html).To(ContainSubstring(`target="_blank"`))
+		})
+	})
+
+	Context("行頭に>>123がある場合", func() {
+		It("引用として扱わない", func() {
+			html := render(">>123 返信")
+
+			Expect(html).NotTo(ContainSubstring("<blockquote>"))
+			Expect(html).To(ContainSubstring("&gt;&gt;123"))
+		})
+	})
+})
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"bbs/internal/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthMiddlewareの後に設定すること
+func AdminMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, exists := ctx.Get("user")
+		if !exists {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		if !user.(*model.User).IsAdmin() {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "user is not admin"})
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"bbs/internal/model"
 	"bbs/internal/service"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
@@ -9,7 +11,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(authService service.IAuthService) gin.HandlerFunc {
+// 個人アクセストークンの場合、参照にはreadスコープが必要で、更新はscopesのいずれかを持つ場合だけ許可する。
+// scopesを指定しないルートは個人アクセストークンでは更新できない
+func AuthMiddleware(authService service.IAuthService, scopes ...string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		header := ctx.GetHeader("Authorization")
 		if header == "" {
@@ -23,12 +27,22 @@ func AuthMiddleware(authService service.IAuthService) gin.HandlerFunc {
 		}
 
 		tokenString := strings.TrimPrefix(header, "Bearer ")
-		user, err := authService.GetUserFromToken(tokenString)
+		user, apiToken, err := authService.GetUserFromToken(ctx.Request.Context(), tokenString)
 		if err != nil {
+			// 停止・追放中の場合は理由と期限がわかるようにする
+			if errors.Is(err, service.ErrUserSuspended) || errors.Is(err, service.ErrUserBanned) {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+				return
+			}
 			ctx.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
 
+		if apiToken != nil && !allowedByScope(ctx.Request.Method, apiToken, scopes) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient token scope"})
+			return
+		}
+
 		log.Println(user.ID)
 
 		ctx.Set("user", user)
@@ -36,3 +50,28 @@ func AuthMiddleware(authService service.IAuthService) gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// 未ログインでも通すが、トークンがある場合はAuthMiddlewareと同じく検証してユーザーを設定する
+func OptionalAuthMiddleware(authService service.IAuthService, scopes ...string) gin.HandlerFunc {
+	authenticate := AuthMiddleware(authService, scopes...)
+	return func(ctx *gin.Context) {
+		if ctx.GetHeader("Authorization") == "" {
+			ctx.Next()
+			return
+		}
+		authenticate(ctx)
+	}
+}
+
+func allowedByScope(method string, apiToken *model.APIToken, scopes []string) bool {
+	if method == http.MethodGet || method == http.MethodHead {
+		return apiToken.HasScope(model.ScopeRead)
+	}
+
+	for _, scope := range scopes {
+		if apiToken.HasScope(scope) {
+			return true
+		}
+	}
+	return false
+}
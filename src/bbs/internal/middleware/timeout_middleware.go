@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultQueryTimeout = 10 * time.Second
+
+// リクエストのcontextに期限を設定し、時間のかかるDBへの問い合わせを打ち切る。
+// クライアントが切断した場合も同じcontextで問い合わせが中断される。timeoutが0以下の場合は期限を設定しない
+func QueryTimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if timeout <= 0 {
+			ctx.Next()
+			return
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+		defer cancel()
+
+		ctx.Request = ctx.Request.WithContext(timeoutCtx)
+		ctx.Next()
+	}
+}
+
+// DB_QUERY_TIMEOUT_SECONDSが未設定の場合は10秒、0の場合は期限なしとする
+func QueryTimeout() time.Duration {
+	value := os.Getenv("DB_QUERY_TIMEOUT_SECONDS")
+	if value == "" {
+		return defaultQueryTimeout
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultQueryTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
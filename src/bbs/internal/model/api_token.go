@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+const (
+	ScopeRead          = "read"
+	ScopeWriteThreads  = "write:threads"
+	ScopeWriteComments = "write:comments"
+)
+
+// ボットやスクリプト向けの個人アクセストークン。トークンはハッシュ化して保存し、一覧ではPrefixで見分ける
+type APIToken struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"userId"`
+	Name       string     `gorm:"not null;size:100" json:"name"`
+	Prefix     string     `gorm:"not null;size:16" json:"prefix"`
+	TokenHash  string     `gorm:"not null;size:64;uniqueIndex" json:"-"`
+	Scopes     []string   `gorm:"not null;type:text;serializer:json" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// 取り消されておらず期限も過ぎていないか(期限なしの場合もある)
+func (t *APIToken) IsActive(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || t.ExpiresAt.After(now)
+}
+
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+package model
+
+import "gorm.io/gorm"
+
+// スレッドかコメントのどちらか一方に紐づく。アップロード直後はどちらにも紐づいていない
+type Attachment struct {
+	gorm.Model
+	UserID       uint   `gorm:"not null;index" json:"userId"`
+	ThreadID     *uint  `gorm:"index" json:"threadId"`
+	CommentID    *uint  `gorm:"index" json:"commentId"`
+	FileName     string `gorm:"not null" json:"fileName"`
+	ContentType  string `gorm:"not null" json:"contentType"`
+	Size         int64  `gorm:"not null" json:"size"`
+	StorageKey   string `gorm:"not null;unique" json:"-"`
+	ThumbnailKey string `json:"-"`
+	HasThumbnail bool   `gorm:"not null;default:false" json:"hasThumbnail"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CommentIDがnilの場合はスレッドへのブックマーク
+type Bookmark struct {
+	ID        uint  `gorm:"primarykey" json:"id"`
+	UserID    uint  `gorm:"not null;uniqueIndex:idx_bookmark;index" json:"userId"`
+	ThreadID  uint  `gorm:"not null;uniqueIndex:idx_bookmark;index" json:"threadId"`
+	CommentID *uint `gorm:"index" json:"commentId"`
+	// 一意制約に使うCommentID。NULLは重複とみなされないため、スレッドへのブックマークは0にする
+	TargetCommentID uint      `gorm:"not null;default:0;uniqueIndex:idx_bookmark" json:"-"`
+	Note            string    `gorm:"not null;type:text" json:"note"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+func (b *Bookmark) BeforeSave(tx *gorm.DB) error {
+	b.TargetCommentID = 0
+	if b.CommentID != nil {
+		b.TargetCommentID = *b.CommentID
+	}
+	return nil
+}
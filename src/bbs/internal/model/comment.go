@@ -1,10 +1,27 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Comment struct {
 	gorm.Model
-	Body     string `gorm:"not null" json:"body"`
-	UserID   uint   `gorm:"not null" json:"userId"`
-	ThreadID uint   `gorm:"not null" json:"threadId"`
+	Body       string     `gorm:"not null" json:"body"`
+	BodyHTML   string     `gorm:"not null;type:text" json:"bodyHtml"`
+	UserID     uint       `gorm:"not null" json:"userId"`
+	ThreadID   uint       `gorm:"not null" json:"threadId"`
+	Hidden     bool       `gorm:"not null;default:false" json:"hidden"`
+	AutoHidden bool       `gorm:"not null;default:false" json:"-"`
+	Anonymous  bool       `gorm:"not null;default:false" json:"anonymous"`
+	PosterID   string     `gorm:"-" json:"posterId,omitempty"`
+	Bookmarked bool       `gorm:"-" json:"bookmarked"`
+	Edited     bool       `gorm:"not null;default:false" json:"edited"`
+	EditedAt   *time.Time `json:"editedAt"`
+	// 本文中の言及・参照と、このコメントを参照しているコメント(被参照)
+	Mentions     []CommentMention   `gorm:"foreignKey:CommentID" json:"mentions"`
+	References   []CommentReference `gorm:"foreignKey:CommentID" json:"references"`
+	ReferencedBy []CommentReference `gorm:"foreignKey:ReferencedCommentID" json:"referencedBy"`
+	Attachments  []Attachment       `gorm:"foreignKey:CommentID" json:"attachments"`
 }
@@ -0,0 +1,16 @@
+package model
+
+// コメント本文中の@usernameで言及されたユーザー
+type CommentMention struct {
+	ID        uint   `gorm:"primarykey" json:"id"`
+	CommentID uint   `gorm:"not null;uniqueIndex:idx_comment_mention" json:"commentId"`
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_comment_mention" json:"userId"`
+	Name      string `gorm:"not null" json:"name"`
+}
+
+// コメント本文中の>>123で参照された同じスレッドのコメント
+type CommentReference struct {
+	ID                  uint `gorm:"primarykey" json:"id"`
+	CommentID           uint `gorm:"not null;uniqueIndex:idx_comment_reference" json:"commentId"`
+	ReferencedCommentID uint `gorm:"not null;uniqueIndex:idx_comment_reference;index" json:"referencedCommentId"`
+}
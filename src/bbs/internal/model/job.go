@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	// 上限まで失敗したジョブ。管理者が再実行するまで実行しない
+	JobStatusDead = "dead"
+)
+
+const (
+	JobTypeArchiveThreads    = "threads.archive_inactive"
+	JobTypeDeliverWebhooks   = "webhooks.deliver"
+	JobTypePurgeJobs         = "jobs.purge"
+	JobTypeRotateSigningKeys = "signing_keys.rotate"
+)
+
+// リクエストの処理とは別にワーカーが実行する処理。少なくとも1回は実行されるため、処理は冪等にする
+type Job struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	Type        string     `gorm:"not null;size:100;index" json:"type"`
+	Payload     string     `gorm:"not null;type:text" json:"payload"`
+	Status      string     `gorm:"not null;size:20;default:pending;index:idx_job_due" json:"status"`
+	RunAt       time.Time  `gorm:"not null;index:idx_job_due" json:"runAt"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int        `gorm:"not null;default:5" json:"maxAttempts"`
+	LockedAt    *time.Time `gorm:"index" json:"lockedAt"`
+	LockedBy    string     `gorm:"size:100" json:"lockedBy"`
+	LastError   string     `gorm:"type:text" json:"lastError"`
+	FinishedAt  *time.Time `json:"finishedAt"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// 定期実行の次回の予定。複数のワーカーがいても1回だけジョブを登録するために使う
+type JobSchedule struct {
+	ID        uint      `gorm:"primarykey"`
+	Name      string    `gorm:"not null;size:100;uniqueIndex"`
+	NextRunAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// スレッドに1つだけ付けられる投票。PublicVotesがfalseの場合は誰が投票したかを公開しない
+type Poll struct {
+	ID             uint         `gorm:"primarykey" json:"id"`
+	ThreadID       uint         `gorm:"not null;uniqueIndex" json:"threadId"`
+	Question       string       `gorm:"not null" json:"question"`
+	MultipleChoice bool         `gorm:"not null;default:false" json:"multipleChoice"`
+	PublicVotes    bool         `gorm:"not null;default:false" json:"publicVotes"`
+	ClosesAt       *time.Time   `json:"closesAt"`
+	Options        []PollOption `gorm:"foreignKey:PollID" json:"options"`
+	Closed         bool         `gorm:"-" json:"closed"`
+	TotalVoters    int64        `gorm:"-" json:"totalVoters"`
+	MyChoices      []uint       `gorm:"-" json:"myChoices"`
+	CreatedAt      time.Time    `json:"createdAt"`
+}
+
+func (p *Poll) IsClosed(now time.Time) bool {
+	return p.ClosesAt != nil && !p.ClosesAt.After(now)
+}
+
+type PollOption struct {
+	ID        uint   `gorm:"primarykey" json:"id"`
+	PollID    uint   `gorm:"not null;index" json:"pollId"`
+	Label     string `gorm:"not null" json:"label"`
+	Position  int    `gorm:"not null" json:"position"`
+	VoteCount int64  `gorm:"-" json:"voteCount"`
+	Voters    []uint `gorm:"-" json:"voters,omitempty"`
+}
+
+// 1人のユーザーの投票。ユーザーごとに1件のみで、選んだ選択肢はChoicesに記録する
+type PollVote struct {
+	ID        uint             `gorm:"primarykey" json:"id"`
+	PollID    uint             `gorm:"not null;uniqueIndex:idx_poll_vote" json:"pollId"`
+	UserID    uint             `gorm:"not null;uniqueIndex:idx_poll_vote" json:"userId"`
+	Choices   []PollVoteChoice `gorm:"foreignKey:VoteID;constraint:OnDelete:CASCADE" json:"choices"`
+	CreatedAt time.Time        `json:"createdAt"`
+}
+
+type PollVoteChoice struct {
+	ID       uint `gorm:"primarykey" json:"id"`
+	VoteID   uint `gorm:"not null;uniqueIndex:idx_poll_vote_choice" json:"voteId"`
+	OptionID uint `gorm:"not null;uniqueIndex:idx_poll_vote_choice;index" json:"optionId"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// 二段階認証のリカバリーコード。コードはハッシュ化して保存し、一度使うとUsedAtが設定される
+type RecoveryCode struct {
+	ID        uint       `gorm:"primarykey"`
+	UserID    uint       `gorm:"not null;index"`
+	CodeHash  string     `gorm:"not null;size:64;uniqueIndex"`
+	UsedAt    *time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
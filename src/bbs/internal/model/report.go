@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusDismissed = "dismissed"
+	ReportStatusHidden    = "hidden"
+	ReportStatusDeleted   = "deleted"
+)
+
+// 投稿時の自動チェックで要確認とされた場合の通報者
+const SystemReporterID uint = 0
+
+// CommentIDがnilの場合はスレッド本体への通報
+type Report struct {
+	gorm.Model
+	ReporterID uint       `gorm:"not null;index" json:"reporterId"`
+	ThreadID   uint       `gorm:"not null;index" json:"threadId"`
+	CommentID  *uint      `gorm:"index" json:"commentId"`
+	Reason     string     `gorm:"not null;type:text" json:"reason"`
+	Status     string     `gorm:"not null;default:open;index" json:"status"`
+	ResolvedBy *uint      `json:"resolvedBy"`
+	ResolvedAt *time.Time `json:"resolvedAt"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "gorm.io/gorm"
+
+// 更新前のスレッドの内容
+type ThreadRevision struct {
+	gorm.Model
+	ThreadID uint   `gorm:"not null;uniqueIndex:idx_thread_revision" json:"threadId"`
+	Revision int    `gorm:"not null;uniqueIndex:idx_thread_revision" json:"revision"`
+	Title    string `gorm:"not null" json:"title"`
+	Body     string `gorm:"not null" json:"body"`
+	EditedBy uint   `gorm:"not null" json:"editedBy"`
+}
+
+// 更新前のコメントの内容
+type CommentRevision struct {
+	gorm.Model
+	CommentID uint   `gorm:"not null;uniqueIndex:idx_comment_revision" json:"commentId"`
+	Revision  int    `gorm:"not null;uniqueIndex:idx_comment_revision" json:"revision"`
+	Body      string `gorm:"not null" json:"body"`
+	EditedBy  uint   `gorm:"not null" json:"editedBy"`
+}
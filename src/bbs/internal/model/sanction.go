@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	SanctionTypeSuspension = "suspension"
+	SanctionTypeBan        = "ban"
+)
+
+// ユーザーへの制裁。解除しても履歴として残すためRevokedAtを設定する
+type Sanction struct {
+	gorm.Model
+	UserID    uint       `gorm:"not null;index" json:"userId"`
+	Type      string     `gorm:"not null" json:"type"`
+	Reason    string     `gorm:"not null;type:text" json:"reason"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	IssuedBy  uint       `gorm:"not null" json:"issuedBy"`
+	RevokedAt *time.Time `json:"revokedAt"`
+	RevokedBy *uint      `json:"revokedBy"`
+}
+
+// 解除されておらず期限も過ぎていないか(永久追放は期限なし)
+func (s *Sanction) IsActive(now time.Time) bool {
+	if s.RevokedAt != nil {
+		return false
+	}
+	return s.ExpiresAt == nil || s.ExpiresAt.After(now)
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+const (
+	SigningAlgorithmRS256 = "RS256"
+	SigningAlgorithmEdDSA = "EdDSA"
+)
+
+// JWTの署名鍵。最新の鍵で署名し、ExpiresAtまでは古い鍵で署名されたトークンも検証できる
+type SigningKey struct {
+	ID         uint      `gorm:"primarykey"`
+	KeyID      string    `gorm:"not null;size:64;uniqueIndex"`
+	Algorithm  string    `gorm:"not null"`
+	PrivateKey string    `gorm:"not null;type:text"`
+	ExpiresAt  time.Time `gorm:"not null;index"`
+	CreatedAt  time.Time
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// スレッドの購読。LastReadCommentIDより後のコメントを未読として数える
+type Subscription struct {
+	ID                uint      `gorm:"primarykey" json:"id"`
+	UserID            uint      `gorm:"not null;uniqueIndex:idx_subscription" json:"userId"`
+	ThreadID          uint      `gorm:"not null;uniqueIndex:idx_subscription;index" json:"threadId"`
+	LastReadCommentID uint      `gorm:"not null;default:0" json:"lastReadCommentId"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
@@ -1,11 +1,38 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"time"
 
+	"gorm.io/gorm"
+)
+
+const (
+	VisibilityPublic  = "public"
+	VisibilityMembers = "members"
+	VisibilityPrivate = "private"
+)
+
+// Visibilityがmembersの場合はログインユーザーのみ、privateの場合は作成者と招待されたユーザーのみ閲覧できる
 type Thread struct {
 	gorm.Model
-	Title    string    `gorm:"not null" json:"title"`
-	Body     string    `gorm:"not null" json:"body"`
-	UserID   uint      `gorm:"not null" json:"userId"`
-	Comments []Comment `gorm:"constraint:OnDlete:CASCADE" json:"comments"`
+	Title          string         `gorm:"not null" json:"title"`
+	Body           string         `gorm:"not null" json:"body"`
+	BodyHTML       string         `gorm:"not null;type:text" json:"bodyHtml"`
+	UserID         uint           `gorm:"not null" json:"userId"`
+	Pinned         bool           `gorm:"not null;default:false" json:"pinned"`
+	Locked         bool           `gorm:"not null;default:false" json:"locked"`
+	Archived       bool           `gorm:"not null;default:false" json:"archived"`
+	Hidden         bool           `gorm:"not null;default:false" json:"hidden"`
+	AutoHidden     bool           `gorm:"not null;default:false" json:"-"`
+	Anonymous      bool           `gorm:"not null;default:false" json:"anonymous"`
+	Visibility     string         `gorm:"not null;default:public;index" json:"visibility"`
+	PosterID       string         `gorm:"-" json:"posterId,omitempty"`
+	Bookmarked     bool           `gorm:"-" json:"bookmarked"`
+	LastActivityAt time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"lastActivityAt"`
+	Edited         bool           `gorm:"not null;default:false" json:"edited"`
+	EditedAt       *time.Time     `json:"editedAt"`
+	Comments       []Comment      `gorm:"constraint:OnDlete:CASCADE" json:"comments"`
+	Attachments    []Attachment   `gorm:"foreignKey:ThreadID" json:"attachments"`
+	Members        []ThreadMember `gorm:"foreignKey:ThreadID" json:"members,omitempty"`
+	Poll           *Poll          `gorm:"foreignKey:ThreadID" json:"poll,omitempty"`
 }
@@ -0,0 +1,8 @@
+package model
+
+// 非公開スレッドに招待されたユーザー
+type ThreadMember struct {
+	ID       uint `gorm:"primarykey" json:"id"`
+	ThreadID uint `gorm:"not null;uniqueIndex:idx_thread_member" json:"threadId"`
+	UserID   uint `gorm:"not null;uniqueIndex:idx_thread_member;index" json:"userId"`
+}
@@ -1,12 +1,40 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RoleMember    = "member"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
 
 type User struct {
 	gorm.Model
 	Name     string    `gorm:"not null"`
 	Email    string    `gorm:"not null;unique"`
 	Password string    `gorm:"not null"`
+	Role     string    `gorm:"not null;default:member"`
 	Threads  []Thread  `gorm:"constrant:OnDelete:CASCADE"`
 	Comments []Comment `gorm:"constraint:OnDlete:CASCADE"`
+	// 登録中のTOTPの秘密鍵。確認のコードが検証されるまでTOTPEnabledはfalseのまま
+	TOTPSecret  string `gorm:"not null;default:''" json:"-"`
+	TOTPEnabled bool   `gorm:"not null;default:false"`
+	// 同じコードを再利用できないよう最後に使われたステップを記録する
+	TOTPLastStep int64 `gorm:"not null;default:0" json:"-"`
+	// ログインの2段階目で続けて失敗した回数。上限に達するとTOTPLockedUntilまでログインできない
+	TOTPFailedAttempts int        `gorm:"not null;default:0" json:"-"`
+	TOTPLockedUntil    *time.Time `json:"-"`
+}
+
+// モデレーター権限を持つか(管理者はモデレーターを兼ねる)
+func (u *User) IsModerator() bool {
+	return u.Role == RoleModerator || u.Role == RoleAdmin
+}
+
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
 }
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// OIDCプロバイダのアカウントとユーザーの紐づけ
+type UserIdentity struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"userId"`
+	Provider  string    `gorm:"not null;size:64;uniqueIndex:idx_user_identity" json:"provider"`
+	Subject   string    `gorm:"not null;size:255;uniqueIndex:idx_user_identity" json:"subject"`
+	Email     string    `gorm:"not null" json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// 認可リクエストごとのstate。コールバックで1回だけ使える
+type OIDCLoginState struct {
+	ID           uint      `gorm:"primarykey"`
+	State        string    `gorm:"not null;size:64;uniqueIndex"`
+	Provider     string    `gorm:"not null;size:64"`
+	Nonce        string    `gorm:"not null"`
+	CodeVerifier string    `gorm:"not null"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+	CreatedAt    time.Time
+}
@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+const (
+	WebhookEventThreadCreated  = "thread.created"
+	WebhookEventThreadDeleted  = "thread.deleted"
+	WebhookEventCommentCreated = "comment.created"
+	WebhookEventCommentDeleted = "comment.deleted"
+)
+
+const (
+	WebhookDeliveryStatusPending   = "pending"
+	WebhookDeliveryStatusSucceeded = "succeeded"
+	WebhookDeliveryStatusFailed    = "failed"
+)
+
+// 外部への通知先。ThreadIDを指定した場合はそのスレッドのイベントだけを、指定しない場合はすべてのイベントを通知する
+type Webhook struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	URL       string    `gorm:"not null;size:2048" json:"url"`
+	Secret    string    `gorm:"not null" json:"-"`
+	Events    []string  `gorm:"not null;type:text;serializer:json" json:"events"`
+	ThreadID  *uint     `gorm:"index" json:"threadId"`
+	Active    bool      `gorm:"not null;default:true" json:"active"`
+	CreatedBy uint      `gorm:"not null" json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// 通知の配信記録。失敗した場合はNextAttemptAtに再送する
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	WebhookID      uint       `gorm:"not null;index" json:"webhookId"`
+	Webhook        *Webhook   `json:"-"`
+	Event          string     `gorm:"not null" json:"event"`
+	Payload        string     `gorm:"not null;type:text" json:"payload"`
+	Status         string     `gorm:"not null;default:pending;index:idx_webhook_delivery_due" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  *time.Time `gorm:"index:idx_webhook_delivery_due" json:"nextAttemptAt"`
+	ResponseStatus *int       `json:"responseStatus"`
+	ResponseBody   string     `gorm:"type:text" json:"responseBody"`
+	Error          string     `gorm:"type:text" json:"error"`
+	DeliveredAt    *time.Time `json:"deliveredAt"`
+	// 再送で作成した場合は元の配信
+	RedeliveryOf *uint     `json:"redeliveryOf"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,55 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// リダイレクト先はフロントエンドのURLで、フロントエンドが受け取ったcodeとstateをAPIに送る
+type ProviderConfig struct {
+	Name         string   `json:"name"`
+	IssuerURL    string   `json:"issuerUrl"`
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"clientSecret"`
+	RedirectURL  string   `json:"redirectUrl"`
+	Scopes       []string `json:"scopes"`
+}
+
+type Config struct {
+	Providers []ProviderConfig `json:"providers"`
+}
+
+// 設定ファイル中の${VAR}は環境変数で置き換えるため、クライアントシークレットはファイルに直接書かなくてよい
+func ParseConfig(data []byte) (*Config, error) {
+	var config Config
+	if err := json.Unmarshal([]byte(os.ExpandEnv(string(data))), &config); err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for _, provider := range config.Providers {
+		if provider.Name == "" || provider.IssuerURL == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			return nil, fmt.Errorf("oidc provider %q: name, issuerUrl, clientId and redirectUrl are required", provider.Name)
+		}
+		if names[provider.Name] {
+			return nil, fmt.Errorf("oidc provider %q is duplicated", provider.Name)
+		}
+		names[provider.Name] = true
+	}
+
+	return &config, nil
+}
+
+// ファイルが存在しない場合はプロバイダなしとして扱う
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
@@ -0,0 +1,113 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// IDトークンから読み取るユーザー情報
+type Claims struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+type Provider struct {
+	name     string
+	oauth2   oauth2.Config
+	verifier *gooidc.IDTokenVerifier
+}
+
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// 認可コードフローの認可URL。PKCEのチャレンジとnonceを含める
+func (p *Provider) AuthCodeURL(state string, nonce string, codeVerifier string) string {
+	return p.oauth2.AuthCodeURL(state, oauth2.S256ChallengeOption(codeVerifier), gooidc.Nonce(nonce))
+}
+
+// 認可コードをトークンに交換し、IDトークンの署名・発行者・audience・nonceを検証する
+func (p *Provider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*Claims, error) {
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("id_token is missing")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("invalid nonce")
+	}
+
+	var claims Claims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// 設定されたプロバイダ。ディスカバリは初めて使われたときに行い、結果を使い回す
+type Registry struct {
+	mu        sync.Mutex
+	configs   map[string]ProviderConfig
+	providers map[string]*Provider
+}
+
+func NewRegistry(config *Config) *Registry {
+	configs := make(map[string]ProviderConfig, len(config.Providers))
+	for _, provider := range config.Providers {
+		configs[provider.Name] = provider
+	}
+	return &Registry{configs: configs, providers: map[string]*Provider{}}
+}
+
+func (r *Registry) Provider(ctx context.Context, name string) (*Provider, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if provider, ok := r.providers[name]; ok {
+		return provider, nil
+	}
+
+	config, ok := r.configs[name]
+	if !ok {
+		return nil, errors.New("provider not found")
+	}
+
+	discovered, err := gooidc.NewProvider(ctx, config.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{gooidc.ScopeOpenID, "email", "profile"}
+	}
+
+	provider := &Provider{
+		name: name,
+		oauth2: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     discovered.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier: discovered.Verifier(&gooidc.Config{ClientID: config.ClientID}),
+	}
+	r.providers[name] = provider
+	return provider, nil
+}
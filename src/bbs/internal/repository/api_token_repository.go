@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"bbs/internal/model"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APITokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAPITokenRepository(db *gorm.DB) IAPITokenRepository {
+	return &APITokenRepository{db: db}
+}
+
+func (r *APITokenRepository) Create(ctx context.Context, newToken model.APIToken) (*model.APIToken, error) {
+	result := r.db.WithContext(ctx).Create(&newToken)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newToken, nil
+}
+
+// 取り消したトークンは一覧に含めない
+func (r *APITokenRepository) FindByUserId(ctx context.Context, userId uint) (*[]model.APIToken, error) {
+	var tokens []model.APIToken
+	result := r.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL", userId).Order("id DESC").Find(&tokens)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &tokens, nil
+}
+
+func (r *APITokenRepository) FindByHash(ctx context.Context, tokenHash string) (*model.APIToken, error) {
+	var token model.APIToken
+	result := r.db.WithContext(ctx).First(&token, "token_hash = ?", tokenHash)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("api token not found")
+		}
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+func (r *APITokenRepository) Revoke(ctx context.Context, id uint, userId uint, now time.Time) error {
+	result := r.db.WithContext(ctx).Model(&model.APIToken{}).Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userId).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("api token not found")
+	}
+	return nil
+}
+
+func (r *APITokenRepository) UpdateLastUsedAt(ctx context.Context, id uint, now time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.APIToken{}).Where("id = ?", id).Update("last_used_at", now).Error
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"bbs/internal/model"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type AttachmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAttachmentRepository(db *gorm.DB) IAttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+func (r *AttachmentRepository) Create(ctx context.Context, newAttachment model.Attachment) (*model.Attachment, error) {
+	result := r.db.WithContext(ctx).Create(&newAttachment)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newAttachment, nil
+}
+
+func (r *AttachmentRepository) FindById(ctx context.Context, id uint) (*model.Attachment, error) {
+	var attachment model.Attachment
+	result := r.db.WithContext(ctx).First(&attachment, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("attachment not found")
+		}
+		return nil, result.Error
+	}
+	return &attachment, nil
+}
+
+// 閲覧できないスレッドやそのコメントの添付ファイルは存在しないものとして扱う。投稿前の添付ファイルは誰でも取得できる
+func (r *AttachmentRepository) FindVisibleById(ctx context.Context, id uint, userId uint) (*model.Attachment, error) {
+	// 非表示のスレッドやコメントに添付されたファイルは、スレッドやコメントと同様に見せない
+	visibleThreads := visibleThreadIds(r.db, userId).Where("threads.hidden = ?", false)
+	visibleComments := r.db.Model(&model.Comment{}).Select("comments.id").Where("comments.hidden = ? AND comments.thread_id IN (?)", false, visibleThreadIds(r.db, userId).Where("threads.hidden = ?", false))
+	// まだ投稿に紐付いていないファイルはアップロードした本人だけが見られる
+	unlinked := r.db.Where("thread_id IS NULL AND comment_id IS NULL AND user_id = ?", userId)
+
+	var attachment model.Attachment
+	result := r.db.WithContext(ctx).
+		Where(r.db.Where("thread_id IN (?)", visibleThreads).Or("comment_id IN (?)", visibleComments).Or(unlinked)).
+		First(&attachment, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("attachment not found")
+		}
+		return nil, result.Error
+	}
+	return &attachment, nil
+}
+
+func (r *AttachmentRepository) CountUnlinked(ctx context.Context, ids []uint, userId uint) (int64, error) {
+	var count int64
+	result := r.db.WithContext(ctx).Model(&model.Attachment{}).
+		Where("id IN ? AND user_id = ? AND thread_id IS NULL AND comment_id IS NULL", ids, userId).
+		Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+func (r *AttachmentRepository) Link(ctx context.Context, ids []uint, userId uint, threadId *uint, commentId *uint) error {
+	result := r.db.WithContext(ctx).Model(&model.Attachment{}).
+		Where("id IN ? AND user_id = ? AND thread_id IS NULL AND comment_id IS NULL", ids, userId).
+		Updates(map[string]interface{}{"thread_id": threadId, "comment_id": commentId})
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// スレッド本体とスレッド内のコメントに紐づく添付ファイルを返す
+func (r *AttachmentRepository) FindByThreadId(ctx context.Context, threadId uint) (*[]model.Attachment, error) {
+	var attachments []model.Attachment
+	commentIds := r.db.WithContext(ctx).Model(&model.Comment{}).Unscoped().Select("id").Where("thread_id = ?", threadId)
+	result := r.db.WithContext(ctx).Where("thread_id = ? OR comment_id IN (?)", threadId, commentIds).Find(&attachments)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &attachments, nil
+}
+
+func (r *AttachmentRepository) FindByCommentId(ctx context.Context, commentId uint) (*[]model.Attachment, error) {
+	var attachments []model.Attachment
+	result := r.db.WithContext(ctx).Where("comment_id = ?", commentId).Find(&attachments)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &attachments, nil
+}
+
+func (r *AttachmentRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&model.Attachment{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
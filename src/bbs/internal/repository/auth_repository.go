@@ -2,6 +2,7 @@ package repository
 
 import (
 	"bbs/internal/model"
+	"context"
 	"errors"
 
 	"gorm.io/gorm"
@@ -15,8 +16,8 @@ func NewAuthRepository(db *gorm.DB) IAuthRepository {
 	return &AuthRepository{db: db}
 }
 
-func (r *AuthRepository) CreateUser(user model.User) error {
-	result := r.db.Create(&user)
+func (r *AuthRepository) CreateUser(ctx context.Context, user model.User) error {
+	result := r.db.WithContext(ctx).Create(&user)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -24,9 +25,9 @@ func (r *AuthRepository) CreateUser(user model.User) error {
 	return nil
 }
 
-func (r *AuthRepository) FindUser(email string) (*model.User, error) {
+func (r *AuthRepository) FindUser(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
-	result := r.db.First(&user, "email = ?", email)
+	result := r.db.WithContext(ctx).First(&user, "email = ?", email)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
@@ -35,3 +36,24 @@ func (r *AuthRepository) FindUser(email string) (*model.User, error) {
 	}
 	return &user, nil
 }
+
+func (r *AuthRepository) FindUserById(ctx context.Context, id uint) (*model.User, error) {
+	var user model.User
+	result := r.db.WithContext(ctx).First(&user, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, result.Error
+	}
+	return &user, nil
+}
+
+func (r *AuthRepository) FindUsersByNames(ctx context.Context, names []string) (*[]model.User, error) {
+	var users []model.User
+	result := r.db.WithContext(ctx).Where("name IN ?", names).Find(&users)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &users, nil
+}
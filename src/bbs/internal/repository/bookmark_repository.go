@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BookmarkRepository struct {
+	db *gorm.DB
+}
+
+func NewBookmarkRepository(db *gorm.DB) IBookmarkRepository {
+	return &BookmarkRepository{db: db}
+}
+
+func (r *BookmarkRepository) Find(ctx context.Context, userId uint, threadId uint, commentId *uint) (*model.Bookmark, error) {
+	var bookmark model.Bookmark
+	result := r.target(ctx, userId, threadId, commentId).First(&bookmark)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bookmark not found")
+		}
+		return nil, result.Error
+	}
+	return &bookmark, nil
+}
+
+// ブックマーク済みの場合はメモのみ更新する
+func (r *BookmarkRepository) Save(ctx context.Context, bookmark model.Bookmark) (*model.Bookmark, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "thread_id"}, {Name: "target_comment_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"note", "updated_at"}),
+	}).Create(&bookmark)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return r.Find(ctx, bookmark.UserID, bookmark.ThreadID, bookmark.CommentID)
+}
+
+func (r *BookmarkRepository) Delete(ctx context.Context, userId uint, threadId uint, commentId *uint) error {
+	result := r.target(ctx, userId, threadId, commentId).Delete(&model.Bookmark{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("bookmark not found")
+	}
+	return nil
+}
+
+// 削除・非表示にされた対象や閲覧できなくなった対象は内容を含めずに返す
+func (r *BookmarkRepository) FindByUserId(ctx context.Context, userId uint, limit int, offset int) (*dto.BookmarkListOutput, error) {
+	var bookmarkList dto.BookmarkListOutput
+
+	query := r.db.WithContext(ctx).Model(&model.Bookmark{}).Where("user_id = ?", userId)
+	if err := query.Session(&gorm.Session{}).Count(&bookmarkList.Total).Error; err != nil {
+		return nil, err
+	}
+
+	var bookmarks []model.Bookmark
+	result := query.Session(&gorm.Session{}).Order("id desc").Limit(limit).Offset(offset * limit).Find(&bookmarks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	// ページ内のスレッドとコメントはそれぞれ1回の問い合わせでまとめて読み込む
+	threadIds := make([]uint, 0, len(bookmarks))
+	commentIds := []uint{}
+	for _, bookmark := range bookmarks {
+		threadIds = append(threadIds, bookmark.ThreadID)
+		if bookmark.CommentID != nil {
+			commentIds = append(commentIds, *bookmark.CommentID)
+		}
+	}
+
+	var threads []model.Thread
+	result = r.db.WithContext(ctx).Scopes(visibleTo(userId)).Where("threads.hidden = ?", false).Find(&threads, "threads.id IN ?", threadIds)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	threadsById := make(map[uint]*model.Thread, len(threads))
+	for i := range threads {
+		threadsById[threads[i].ID] = &threads[i]
+	}
+
+	var comments []model.Comment
+	result = r.db.WithContext(ctx).Where("hidden = ?", false).Find(&comments, "id IN ?", commentIds)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	commentsById := make(map[uint]*model.Comment, len(comments))
+	for i := range comments {
+		commentsById[comments[i].ID] = &comments[i]
+	}
+
+	bookmarkList.Bookmarks = make([]dto.BookmarkOutput, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		item := dto.BookmarkOutput{
+			ID:        bookmark.ID,
+			ThreadID:  bookmark.ThreadID,
+			CommentID: bookmark.CommentID,
+			Note:      bookmark.Note,
+			CreatedAt: bookmark.CreatedAt,
+		}
+
+		thread, ok := threadsById[bookmark.ThreadID]
+		var comment *model.Comment
+		if ok && bookmark.CommentID != nil {
+			comment = commentsById[*bookmark.CommentID]
+			ok = comment != nil && comment.ThreadID == bookmark.ThreadID
+		}
+		if ok {
+			item.Thread = thread
+			item.Comment = comment
+		} else {
+			item.Deleted = true
+		}
+
+		bookmarkList.Bookmarks[i] = item
+	}
+
+	return &bookmarkList, nil
+}
+
+// 指定したスレッドのうちブックマーク済みのもののID
+func (r *BookmarkRepository) FindBookmarkedThreadIds(ctx context.Context, userId uint, threadIds []uint) ([]uint, error) {
+	var ids []uint
+	result := r.db.WithContext(ctx).Model(&model.Bookmark{}).
+		Where("user_id = ? AND thread_id IN ? AND comment_id IS NULL", userId, threadIds).
+		Pluck("thread_id", &ids)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return ids, nil
+}
+
+// 指定したコメントのうちブックマーク済みのもののID
+func (r *BookmarkRepository) FindBookmarkedCommentIds(ctx context.Context, userId uint, commentIds []uint) ([]uint, error) {
+	var ids []uint
+	result := r.db.WithContext(ctx).Model(&model.Bookmark{}).
+		Where("user_id = ? AND comment_id IN ?", userId, commentIds).
+		Pluck("comment_id", &ids)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return ids, nil
+}
+
+func (r *BookmarkRepository) target(ctx context.Context, userId uint, threadId uint, commentId *uint) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&model.Bookmark{}).Where("user_id = ? AND thread_id = ?", userId, threadId)
+	if commentId == nil {
+		return query.Where("comment_id IS NULL")
+	}
+	return query.Where("comment_id = ?", *commentId)
+}
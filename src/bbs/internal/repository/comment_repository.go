@@ -1,10 +1,13 @@
 package repository
 
 import (
+	"bbs/internal/dto"
 	"bbs/internal/model"
+	"context"
 	"errors"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CommentRepository struct {
@@ -15,8 +18,8 @@ func NewCommentRepository(db *gorm.DB) ICommentRepository {
 	return &CommentRepository{db: db}
 }
 
-func (r *CommentRepository) Create(newComment model.Comment) (*model.Comment, error) {
-	result := r.db.Create(&newComment)
+func (r *CommentRepository) Create(ctx context.Context, newComment model.Comment) (*model.Comment, error) {
+	result := r.db.WithContext(ctx).Omit(clause.Associations).Create(&newComment)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -24,9 +27,58 @@ func (r *CommentRepository) Create(newComment model.Comment) (*model.Comment, er
 	return &newComment, nil
 }
 
-func (r *CommentRepository) FindByThreadId(threadId uint, userId uint) (*[]model.Comment, error) {
-	var comments []model.Comment
-	result := r.db.Where("thread_id = ? AND user_id = ?", threadId, userId).Find(&comments)
+// userIdは閲覧するユーザーで、未ログインの場合は0
+func (r *CommentRepository) FindByThreadId(ctx context.Context, threadId uint, query dto.CommentListQuery, userId uint) (*dto.CommentListOutput, error) {
+	return r.findList(
+		r.db.WithContext(ctx).Model(&model.Comment{}).
+			Where("comments.thread_id = ? AND comments.hidden = ?", threadId, false).
+			Where("comments.thread_id IN (?)", visibleThreadIds(r.db, userId)),
+		query, userId,
+	)
+}
+
+// 非表示のスレッドや閲覧できないスレッドへのコメントは除く
+func (r *CommentRepository) FindByUserId(ctx context.Context, authorId uint, query dto.CommentListQuery, userId uint) (*dto.CommentListOutput, error) {
+	query.AuthorID = &authorId
+	return r.findList(
+		r.db.WithContext(ctx).Model(&model.Comment{}).
+			Where("comments.hidden = ?", false).
+			Where("comments.thread_id IN (?)", visibleThreadIds(r.db, userId).Where("threads.hidden = ?", false)),
+		query, userId,
+	)
+}
+
+func (r *CommentRepository) findList(tx *gorm.DB, query dto.CommentListQuery, userId uint) (*dto.CommentListOutput, error) {
+	if query.AuthorID != nil {
+		tx = tx.Where("comments.user_id = ?", *query.AuthorID).Scopes(anonymousHiddenFrom(userId))
+	}
+
+	var commentList dto.CommentListOutput
+	result := tx.Session(&gorm.Session{}).Count(&commentList.Total)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	order := "comments.id asc"
+	if query.Order == dto.OrderDesc {
+		order = "comments.id desc"
+	}
+
+	result = tx.Session(&gorm.Session{}).
+		Scopes(preloadCommentAssociations).
+		Order(order).
+		Limit(query.Limit).Offset(query.Offset * query.Limit).
+		Find(&commentList.Comments)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &commentList, nil
+}
+
+func (r *CommentRepository) FindById(ctx context.Context, id uint, threadId uint) (*model.Comment, error) {
+	var comment model.Comment
+	result := r.db.WithContext(ctx).Scopes(preloadCommentAssociations).First(&comment, "id = ? AND thread_id = ?", id, threadId)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("comment not found")
@@ -34,12 +86,13 @@ func (r *CommentRepository) FindByThreadId(threadId uint, userId uint) (*[]model
 		return nil, result.Error
 	}
 
-	return &comments, nil
+	return &comment, nil
 }
 
-func (r *CommentRepository) FindById(id uint, threadId uint) (*model.Comment, error) {
+// ロック中は他のトランザクションから削除・更新されない
+func (r *CommentRepository) FindByIdForUpdate(ctx context.Context, id uint, threadId uint) (*model.Comment, error) {
 	var comment model.Comment
-	result := r.db.First(&comment, "id = ? AND thread_id = ?", id, threadId)
+	result := r.db.WithContext(ctx).Scopes(forUpdate(false)).First(&comment, "id = ? AND thread_id = ?", id, threadId)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("comment not found")
@@ -50,8 +103,9 @@ func (r *CommentRepository) FindById(id uint, threadId uint) (*model.Comment, er
 	return &comment, nil
 }
 
-func (r *CommentRepository) Update(updateComment model.Comment) (*model.Comment, error) {
-	result := r.db.Save(&updateComment)
+func (r *CommentRepository) Update(ctx context.Context, updateComment model.Comment) (*model.Comment, error) {
+	// 言及・参照はReplaceLinksで更新する
+	result := r.db.WithContext(ctx).Omit(clause.Associations).Save(&updateComment)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -59,15 +113,51 @@ func (r *CommentRepository) Update(updateComment model.Comment) (*model.Comment,
 	return &updateComment, nil
 }
 
-func (r *CommentRepository) Delete(id uint, threadId uint, userId uint) error {
-	deleteComment, err := r.FindById(id, threadId)
+func (r *CommentRepository) Delete(ctx context.Context, id uint, threadId uint, userId uint) error {
+	deleteComment, err := r.FindById(ctx, id, threadId)
 	if err != nil {
 		return err
 	}
-	result := r.db.Delete(&deleteComment)
+	result := r.db.WithContext(ctx).Delete(&deleteComment)
 	if result.Error != nil {
 		return result.Error
 	}
 
 	return nil
 }
+
+func (r *CommentRepository) FindByIds(ctx context.Context, ids []uint, threadId uint) (*[]model.Comment, error) {
+	var comments []model.Comment
+	result := r.db.WithContext(ctx).Where("id IN ? AND thread_id = ?", ids, threadId).Find(&comments)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &comments, nil
+}
+
+func (r *CommentRepository) ReplaceLinks(ctx context.Context, commentId uint, mentions []model.CommentMention, references []model.CommentReference) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("comment_id = ?", commentId).Delete(&model.CommentMention{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id = ?", commentId).Delete(&model.CommentReference{}).Error; err != nil {
+			return err
+		}
+		if len(mentions) > 0 {
+			if err := tx.Create(&mentions).Error; err != nil {
+				return err
+			}
+		}
+		if len(references) > 0 {
+			if err := tx.Create(&references).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func preloadCommentAssociations(db *gorm.DB) *gorm.DB {
+	return db.Preload("Mentions").Preload("References").Preload("ReferencedBy").Preload("Attachments")
+}
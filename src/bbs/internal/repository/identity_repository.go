@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"bbs/internal/model"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type IdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewIdentityRepository(db *gorm.DB) IIdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+func (r *IdentityRepository) FindIdentity(ctx context.Context, provider string, subject string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	result := r.db.WithContext(ctx).First(&identity, "provider = ? AND subject = ?", provider, subject)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("identity not found")
+		}
+		return nil, result.Error
+	}
+	return &identity, nil
+}
+
+func (r *IdentityRepository) CreateIdentity(ctx context.Context, newIdentity model.UserIdentity) (*model.UserIdentity, error) {
+	result := r.db.WithContext(ctx).Create(&newIdentity)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newIdentity, nil
+}
+
+// OIDCのアカウントだけで新規登録する場合は、ユーザーとの紐づけを同時に作成する
+func (r *IdentityRepository) CreateUserWithIdentity(ctx context.Context, newUser model.User, newIdentity model.UserIdentity) (*model.User, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newUser).Error; err != nil {
+			return err
+		}
+		newIdentity.UserID = newUser.ID
+		return tx.Create(&newIdentity).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &newUser, nil
+}
+
+func (r *IdentityRepository) CreateLoginState(ctx context.Context, newState model.OIDCLoginState) error {
+	return r.db.WithContext(ctx).Create(&newState).Error
+}
+
+// stateは使い捨てのため、見つかった場合は削除してから返す
+func (r *IdentityRepository) ConsumeLoginState(ctx context.Context, state string, provider string, now time.Time) (*model.OIDCLoginState, error) {
+	var loginState model.OIDCLoginState
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&loginState, "state = ? AND provider = ?", state, provider).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&loginState)
+		if result.Error != nil {
+			return result.Error
+		}
+		// 同じstateが同時に使われた場合は先に削除できた方だけを有効にする
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid state")
+		}
+		return nil, err
+	}
+
+	if !loginState.ExpiresAt.After(now) {
+		return nil, errors.New("invalid state")
+	}
+	return &loginState, nil
+}
+
+func (r *IdentityRepository) DeleteExpiredLoginStates(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&model.OIDCLoginState{}).Error
+}
@@ -3,25 +3,170 @@ package repository
 import (
 	"bbs/internal/dto"
 	"bbs/internal/model"
+	"context"
+	"time"
 )
 
 type IAuthRepository interface {
-	CreateUser(user model.User) error
-	FindUser(email string) (*model.User, error)
+	CreateUser(ctx context.Context, user model.User) error
+	FindUser(ctx context.Context, email string) (*model.User, error)
+	FindUsersByNames(ctx context.Context, names []string) (*[]model.User, error)
+	FindUserById(ctx context.Context, id uint) (*model.User, error)
 }
 
 type IThreadRepository interface {
-	Create(newThread model.Thread) (*model.Thread, error)
-	Update(updateThread model.Thread) (*model.Thread, error)
-	Delete(threadId uint, userId uint) error
-	FindAll(limit int, offset int) (*dto.ThreadListOutput, error)
-	FindById(threadId uint) (*model.Thread, error)
+	Create(ctx context.Context, newThread model.Thread) (*model.Thread, error)
+	Update(ctx context.Context, updateThread model.Thread) (*model.Thread, error)
+	Delete(ctx context.Context, threadId uint, userId uint) error
+	FindAll(ctx context.Context, limit int, offset int, userId uint) (*dto.ThreadListOutput, error)
+	FindById(ctx context.Context, threadId uint) (*model.Thread, error)
+	FindByIdForUpdate(ctx context.Context, threadId uint) (*model.Thread, error)
+	FindVisibleById(ctx context.Context, threadId uint, userId uint) (*model.Thread, error)
+	FindByUserId(ctx context.Context, userId uint, limit int, offset int) (*dto.ThreadListOutput, error)
+	FindParticipating(ctx context.Context, userId uint, limit int, offset int) (*dto.ThreadListOutput, error)
+	ReplaceMembers(ctx context.Context, threadId uint, userIds []uint) error
+	UpdateLastActivity(ctx context.Context, threadId uint, at time.Time) error
+	ArchiveInactive(ctx context.Context, before time.Time) (int64, error)
 }
 
 type ICommentRepository interface {
-	Create(newComment model.Comment) (*model.Comment, error)
-	FindByThreadId(threadId uint, userId uint) (*[]model.Comment, error)
-	FindById(id uint, threadId uint) (*model.Comment, error)
-	Update(updateComment model.Comment) (*model.Comment, error)
-	Delete(id uint, threadId uint, userId uint) error
+	Create(ctx context.Context, newComment model.Comment) (*model.Comment, error)
+	FindByThreadId(ctx context.Context, threadId uint, query dto.CommentListQuery, userId uint) (*dto.CommentListOutput, error)
+	FindByUserId(ctx context.Context, authorId uint, query dto.CommentListQuery, userId uint) (*dto.CommentListOutput, error)
+	FindById(ctx context.Context, id uint, threadId uint) (*model.Comment, error)
+	FindByIdForUpdate(ctx context.Context, id uint, threadId uint) (*model.Comment, error)
+	Update(ctx context.Context, updateComment model.Comment) (*model.Comment, error)
+	Delete(ctx context.Context, id uint, threadId uint, userId uint) error
+	FindByIds(ctx context.Context, ids []uint, threadId uint) (*[]model.Comment, error)
+	ReplaceLinks(ctx context.Context, commentId uint, mentions []model.CommentMention, references []model.CommentReference) error
+}
+
+type IRevisionRepository interface {
+	CreateThreadRevision(ctx context.Context, newRevision model.ThreadRevision) (*model.ThreadRevision, error)
+	FindThreadRevisions(ctx context.Context, threadId uint) (*[]model.ThreadRevision, error)
+	FindThreadRevision(ctx context.Context, id uint, threadId uint) (*model.ThreadRevision, error)
+	CreateCommentRevision(ctx context.Context, newRevision model.CommentRevision) (*model.CommentRevision, error)
+	FindCommentRevisions(ctx context.Context, commentId uint) (*[]model.CommentRevision, error)
+	FindCommentRevision(ctx context.Context, id uint, commentId uint) (*model.CommentRevision, error)
+}
+
+type IAttachmentRepository interface {
+	Create(ctx context.Context, newAttachment model.Attachment) (*model.Attachment, error)
+	FindById(ctx context.Context, id uint) (*model.Attachment, error)
+	FindVisibleById(ctx context.Context, id uint, userId uint) (*model.Attachment, error)
+	CountUnlinked(ctx context.Context, ids []uint, userId uint) (int64, error)
+	Link(ctx context.Context, ids []uint, userId uint, threadId *uint, commentId *uint) error
+	FindByThreadId(ctx context.Context, threadId uint) (*[]model.Attachment, error)
+	FindByCommentId(ctx context.Context, commentId uint) (*[]model.Attachment, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type IReportRepository interface {
+	Create(ctx context.Context, newReport model.Report) (*model.Report, error)
+	CountByReporter(ctx context.Context, reporterId uint, threadId uint, commentId *uint) (int64, error)
+	CountOpen(ctx context.Context, threadId uint, commentId *uint) (int64, error)
+	FindQueue(ctx context.Context, limit int, offset int) (*dto.ModerationQueueOutput, error)
+	Resolve(ctx context.Context, threadId uint, commentId *uint, status string, moderatorId uint) (int64, error)
+}
+
+type IPostRepository interface {
+	CountRecentByUser(ctx context.Context, userId uint, body string, since time.Time) (int64, error)
+}
+
+type ISanctionRepository interface {
+	Create(ctx context.Context, newSanction model.Sanction) (*model.Sanction, error)
+	Update(ctx context.Context, updateSanction model.Sanction) (*model.Sanction, error)
+	FindByUserId(ctx context.Context, userId uint) (*[]model.Sanction, error)
+	FindById(ctx context.Context, id uint, userId uint) (*model.Sanction, error)
+	FindActive(ctx context.Context, userId uint, now time.Time) (*model.Sanction, error)
+}
+
+type ISubscriptionRepository interface {
+	Subscribe(ctx context.Context, userId uint, threadId uint, lastReadCommentId uint) (*model.Subscription, error)
+	Unsubscribe(ctx context.Context, userId uint, threadId uint) error
+	FindByUserId(ctx context.Context, userId uint, limit int, offset int) (*dto.SubscriptionListOutput, error)
+	LatestCommentId(ctx context.Context, threadId uint) (uint, error)
+}
+
+type IBookmarkRepository interface {
+	Find(ctx context.Context, userId uint, threadId uint, commentId *uint) (*model.Bookmark, error)
+	Save(ctx context.Context, bookmark model.Bookmark) (*model.Bookmark, error)
+	Delete(ctx context.Context, userId uint, threadId uint, commentId *uint) error
+	FindByUserId(ctx context.Context, userId uint, limit int, offset int) (*dto.BookmarkListOutput, error)
+	FindBookmarkedThreadIds(ctx context.Context, userId uint, threadIds []uint) ([]uint, error)
+	FindBookmarkedCommentIds(ctx context.Context, userId uint, commentIds []uint) ([]uint, error)
+}
+
+type IPollRepository interface {
+	Create(ctx context.Context, newPoll model.Poll) (*model.Poll, error)
+	FindByThreadId(ctx context.Context, threadId uint, userId uint) (*model.Poll, error)
+	CreateVote(ctx context.Context, newVote model.PollVote) error
+	DeleteVote(ctx context.Context, pollId uint, userId uint) error
+}
+
+type IIdentityRepository interface {
+	FindIdentity(ctx context.Context, provider string, subject string) (*model.UserIdentity, error)
+	CreateIdentity(ctx context.Context, newIdentity model.UserIdentity) (*model.UserIdentity, error)
+	CreateUserWithIdentity(ctx context.Context, newUser model.User, newIdentity model.UserIdentity) (*model.User, error)
+	CreateLoginState(ctx context.Context, newState model.OIDCLoginState) error
+	ConsumeLoginState(ctx context.Context, state string, provider string, now time.Time) (*model.OIDCLoginState, error)
+	DeleteExpiredLoginStates(ctx context.Context, now time.Time) error
+}
+
+type ISigningKeyRepository interface {
+	Create(ctx context.Context, newKey model.SigningKey) (*model.SigningKey, error)
+	FindActive(ctx context.Context, now time.Time) ([]model.SigningKey, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type ITwoFactorRepository interface {
+	UpdateSecret(ctx context.Context, userId uint, secret string) error
+	Enable(ctx context.Context, userId uint, step int64, codeHashes []string) error
+	Disable(ctx context.Context, userId uint) error
+	UseStep(ctx context.Context, userId uint, step int64) error
+	UseRecoveryCode(ctx context.Context, userId uint, codeHash string, now time.Time) error
+	CountUnusedRecoveryCodes(ctx context.Context, userId uint) (int64, error)
+	RecordFailure(ctx context.Context, userId uint, maxAttempts int, lockedUntil time.Time) error
+	ResetFailures(ctx context.Context, userId uint) error
+}
+
+type IAPITokenRepository interface {
+	Create(ctx context.Context, newToken model.APIToken) (*model.APIToken, error)
+	FindByUserId(ctx context.Context, userId uint) (*[]model.APIToken, error)
+	FindByHash(ctx context.Context, tokenHash string) (*model.APIToken, error)
+	Revoke(ctx context.Context, id uint, userId uint, now time.Time) error
+	UpdateLastUsedAt(ctx context.Context, id uint, now time.Time) error
+}
+
+type IWebhookRepository interface {
+	Create(ctx context.Context, newWebhook model.Webhook) (*model.Webhook, error)
+	FindAll(ctx context.Context) (*[]model.Webhook, error)
+	FindById(ctx context.Context, id uint) (*model.Webhook, error)
+	Delete(ctx context.Context, id uint) error
+	FindActiveByThreadId(ctx context.Context, threadId uint) (*[]model.Webhook, error)
+	CreateDelivery(ctx context.Context, newDelivery model.WebhookDelivery) (*model.WebhookDelivery, error)
+	CreateDeliveries(ctx context.Context, newDeliveries []model.WebhookDelivery) error
+	FindDeliveries(ctx context.Context, webhookId uint, limit int, offset int) (*dto.WebhookDeliveryListOutput, error)
+	FindDelivery(ctx context.Context, id uint, webhookId uint) (*model.WebhookDelivery, error)
+	FindDueDeliveries(ctx context.Context, now time.Time, limit int) (*[]model.WebhookDelivery, error)
+	ClaimDelivery(ctx context.Context, id uint, now time.Time, leaseUntil time.Time) (bool, error)
+	UpdateDelivery(ctx context.Context, delivery model.WebhookDelivery) error
+}
+
+type IJobRepository interface {
+	Enqueue(ctx context.Context, newJob model.Job) (*model.Job, error)
+	Claim(ctx context.Context, types []string, workerId string, now time.Time) (*model.Job, error)
+	Complete(ctx context.Context, job model.Job, now time.Time) error
+	Retry(ctx context.Context, job model.Job, lastError string, runAt time.Time) error
+	Kill(ctx context.Context, job model.Job, lastError string, now time.Time) error
+	RequeueStale(ctx context.Context, lockedBefore time.Time) (int64, error)
+	FindAll(ctx context.Context, status string, limit int, offset int) (*dto.JobListOutput, error)
+	Revive(ctx context.Context, id uint, now time.Time) (*model.Job, error)
+	DeleteSucceeded(ctx context.Context, finishedBefore time.Time) (int64, error)
+	EnsureSchedule(ctx context.Context, name string, nextRunAt time.Time) error
+	ClaimSchedule(ctx context.Context, name string, now time.Time, nextRunAt time.Time) (bool, error)
+}
+
+type IUnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
 }
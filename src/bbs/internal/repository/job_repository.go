@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) IJobRepository {
+	return &JobRepository{db: db}
+}
+
+func (r *JobRepository) Enqueue(ctx context.Context, newJob model.Job) (*model.Job, error) {
+	result := r.db.WithContext(ctx).Create(&newJob)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newJob, nil
+}
+
+// 実行予定時刻を過ぎたジョブを1件取得して実行中にする。該当するジョブがない場合はnilを返す。
+// 他のワーカーがロックしている行はSKIP LOCKEDで飛ばし、更新も実行待ちの場合に限るため、同じジョブを同時に取得することはない
+func (r *JobRepository) Claim(ctx context.Context, types []string, workerId string, now time.Time) (*model.Job, error) {
+	var job *model.Job
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var candidates []model.Job
+		result := tx.Scopes(forUpdate(true)).
+			Where("status = ? AND run_at <= ? AND type IN ?", model.JobStatusPending, now, types).
+			Order("run_at").Order("id").Limit(1).
+			Find(&candidates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if len(candidates) == 0 {
+			return nil
+		}
+
+		claimed := candidates[0]
+		claimed.Status = model.JobStatusRunning
+		claimed.Attempts++
+		claimed.LockedAt = &now
+		claimed.LockedBy = workerId
+		result = tx.Model(&claimed).Where("status = ?", model.JobStatusPending).
+			Select("Status", "Attempts", "LockedAt", "LockedBy").Updates(&claimed)
+		if result.Error != nil {
+			return result.Error
+		}
+		// 行ロックのないDBで他のワーカーが先に取得した場合
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		job = &claimed
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (r *JobRepository) Complete(ctx context.Context, job model.Job, now time.Time) error {
+	return r.finish(ctx, job, map[string]interface{}{
+		"status":      model.JobStatusSucceeded,
+		"locked_at":   nil,
+		"locked_by":   "",
+		"last_error":  "",
+		"finished_at": now,
+	})
+}
+
+func (r *JobRepository) Retry(ctx context.Context, job model.Job, lastError string, runAt time.Time) error {
+	return r.finish(ctx, job, map[string]interface{}{
+		"status":     model.JobStatusPending,
+		"run_at":     runAt,
+		"locked_at":  nil,
+		"locked_by":  "",
+		"last_error": lastError,
+	})
+}
+
+func (r *JobRepository) Kill(ctx context.Context, job model.Job, lastError string, now time.Time) error {
+	return r.finish(ctx, job, map[string]interface{}{
+		"status":      model.JobStatusDead,
+		"locked_at":   nil,
+		"locked_by":   "",
+		"last_error":  lastError,
+		"finished_at": now,
+	})
+}
+
+// タイムアウトで他のワーカーに取得し直されたジョブの結果は書き込まない
+func (r *JobRepository) finish(ctx context.Context, job model.Job, values map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.Job{}).
+		Where("id = ? AND status = ? AND locked_by = ?", job.ID, model.JobStatusRunning, job.LockedBy).
+		Updates(values).Error
+}
+
+// ワーカーが停止するなどして実行中のまま残ったジョブを再実行できるようにする
+func (r *JobRepository) RequeueStale(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&model.Job{}).
+		Where("status = ? AND locked_at < ?", model.JobStatusRunning, lockedBefore).
+		Updates(map[string]interface{}{"status": model.JobStatusPending, "locked_at": nil, "locked_by": ""})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// statusが空の場合はすべてのジョブを返す
+func (r *JobRepository) FindAll(ctx context.Context, status string, limit int, offset int) (*dto.JobListOutput, error) {
+	var output dto.JobListOutput
+
+	query := r.db.WithContext(ctx).Model(&model.Job{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if result := query.Count(&output.Total); result.Error != nil {
+		return nil, result.Error
+	}
+
+	result := query.Order("id desc").Limit(limit).Offset(offset * limit).Find(&output.Jobs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &output, nil
+}
+
+// 失敗したジョブを回数を数え直して再実行する
+func (r *JobRepository) Revive(ctx context.Context, id uint, now time.Time) (*model.Job, error) {
+	result := r.db.WithContext(ctx).Model(&model.Job{}).Where("id = ? AND status = ?", id, model.JobStatusDead).
+		Updates(map[string]interface{}{"status": model.JobStatusPending, "attempts": 0, "run_at": now, "finished_at": nil})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("job not found")
+	}
+
+	var job model.Job
+	if err := r.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *JobRepository) DeleteSucceeded(ctx context.Context, finishedBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("status = ? AND finished_at < ?", model.JobStatusSucceeded, finishedBefore).Delete(&model.Job{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// 初めて登録する定期実行の場合だけ次回の予定を作成する
+func (r *JobRepository) EnsureSchedule(ctx context.Context, name string, nextRunAt time.Time) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.JobSchedule{Name: name, NextRunAt: nextRunAt}).Error
+}
+
+// 予定時刻を過ぎていれば次回の予定に進める。他のワーカーが先に進めていた場合はfalseを返す
+func (r *JobRepository) ClaimSchedule(ctx context.Context, name string, now time.Time, nextRunAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.JobSchedule{}).Where("name = ? AND next_run_at <= ?", name, now).
+		Update("next_run_at", nextRunAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
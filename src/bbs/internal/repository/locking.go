@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// トランザクションが終わるまで取得した行をロックする。skipLockedの場合は他のトランザクションがロックしている行を飛ばす。
+// SQLiteは行ロックに対応しておらず、書き込みのトランザクションがDB全体で直列化されるためロックしない
+func forUpdate(skipLocked bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if db.Dialector.Name() == "sqlite" {
+			return db
+		}
+
+		locking := clause.Locking{Strength: "UPDATE"}
+		if skipLocked {
+			locking.Options = "SKIP LOCKED"
+		}
+		return db.Clauses(locking)
+	}
+}
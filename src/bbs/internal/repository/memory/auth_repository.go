@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type AuthRepository struct {
+	store *Store
+}
+
+func NewAuthRepository(store *Store) repository.IAuthRepository {
+	return &AuthRepository{store: store}
+}
+
+func (r *AuthRepository) CreateUser(ctx context.Context, user model.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// emailはユニーク制約があるため、削除済みのユーザーとも重複できない
+	for _, existing := range r.store.users {
+		if existing.Email == user.Email {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	now := r.store.now()
+	user.ID = r.store.nextID("users")
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	if user.Role == "" {
+		user.Role = model.RoleMember
+	}
+	user.Threads = nil
+	user.Comments = nil
+	r.store.users[user.ID] = user
+	return nil
+}
+
+func (r *AuthRepository) FindUser(ctx context.Context, email string) (*model.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, user := range r.store.users {
+		if user.Email == email && !user.DeletedAt.Valid {
+			return &user, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *AuthRepository) FindUserById(ctx context.Context, id uint) (*model.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return nil, errors.New("user not found")
+	}
+	return &user, nil
+}
+
+func (r *AuthRepository) FindUsersByNames(ctx context.Context, names []string) (*[]model.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	users := []model.User{}
+	for _, user := range r.store.users {
+		if user.DeletedAt.Valid {
+			continue
+		}
+		for _, name := range names {
+			if user.Name == name {
+				users = append(users, user)
+				break
+			}
+		}
+	}
+	sortByID(users, func(user model.User) uint { return user.ID })
+	return &users, nil
+}
@@ -0,0 +1,207 @@
+package memory
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"context"
+	"errors"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+type CommentRepository struct {
+	store *Store
+}
+
+func NewCommentRepository(store *Store) repository.ICommentRepository {
+	return &CommentRepository{store: store}
+}
+
+func (r *CommentRepository) Create(ctx context.Context, newComment model.Comment) (*model.Comment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := r.store.now()
+	newComment.ID = r.store.nextID("comments")
+	newComment.CreatedAt = now
+	newComment.UpdatedAt = now
+	r.store.comments[newComment.ID] = stripComment(newComment)
+	return &newComment, nil
+}
+
+// userIdは閲覧するユーザーで、未ログインの場合は0
+func (r *CommentRepository) FindByThreadId(ctx context.Context, threadId uint, query dto.CommentListQuery, userId uint) (*dto.CommentListOutput, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.findList(func(comment model.Comment, thread model.Thread) bool {
+		return comment.ThreadID == threadId
+	}, query, userId), nil
+}
+
+// 非表示のスレッドや閲覧できないスレッドへのコメントは除く
+func (r *CommentRepository) FindByUserId(ctx context.Context, authorId uint, query dto.CommentListQuery, userId uint) (*dto.CommentListOutput, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	query.AuthorID = &authorId
+	return r.findList(func(comment model.Comment, thread model.Thread) bool {
+		return !thread.Hidden
+	}, query, userId), nil
+}
+
+// 閲覧できるスレッドへの非表示でないコメントのうち、matchに該当するものを返す
+func (r *CommentRepository) findList(match func(comment model.Comment, thread model.Thread) bool, query dto.CommentListQuery, userId uint) *dto.CommentListOutput {
+	comments := []model.Comment{}
+	for _, comment := range r.store.comments {
+		if comment.DeletedAt.Valid || comment.Hidden {
+			continue
+		}
+		thread, ok := r.store.threads[comment.ThreadID]
+		if !ok || thread.DeletedAt.Valid || !r.store.visibleTo(thread, userId) || !match(comment, thread) {
+			continue
+		}
+		if query.AuthorID != nil && (comment.UserID != *query.AuthorID || !r.store.anonymousVisibleTo(comment, userId)) {
+			continue
+		}
+		comments = append(comments, comment)
+	}
+
+	sort.Slice(comments, func(i, j int) bool {
+		if query.Order == dto.OrderDesc {
+			return comments[i].ID > comments[j].ID
+		}
+		return comments[i].ID < comments[j].ID
+	})
+
+	commentList := dto.CommentListOutput{Total: int64(len(comments)), Comments: paginate(comments, query.Limit, query.Offset)}
+	for i := range commentList.Comments {
+		r.loadAssociations(&commentList.Comments[i])
+	}
+	return &commentList
+}
+
+func (r *CommentRepository) FindById(ctx context.Context, id uint, threadId uint) (*model.Comment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	comment, err := r.find(id, threadId)
+	if err != nil {
+		return nil, err
+	}
+	r.loadAssociations(&comment)
+	return &comment, nil
+}
+
+// ストアの操作はすべてロックして行うため、行のロックは不要
+func (r *CommentRepository) FindByIdForUpdate(ctx context.Context, id uint, threadId uint) (*model.Comment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	comment, err := r.find(id, threadId)
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (r *CommentRepository) Update(ctx context.Context, updateComment model.Comment) (*model.Comment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// 言及・参照はReplaceLinksで更新する
+	now := r.store.now()
+	if updateComment.ID == 0 {
+		updateComment.ID = r.store.nextID("comments")
+		updateComment.CreatedAt = now
+	}
+	updateComment.UpdatedAt = now
+	r.store.comments[updateComment.ID] = stripComment(updateComment)
+	return &updateComment, nil
+}
+
+func (r *CommentRepository) Delete(ctx context.Context, id uint, threadId uint, userId uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	comment, err := r.find(id, threadId)
+	if err != nil {
+		return err
+	}
+	comment.DeletedAt = gorm.DeletedAt{Time: r.store.now(), Valid: true}
+	r.store.comments[comment.ID] = comment
+	return nil
+}
+
+func (r *CommentRepository) FindByIds(ctx context.Context, ids []uint, threadId uint) (*[]model.Comment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	comments := []model.Comment{}
+	for _, id := range ids {
+		if comment, err := r.find(id, threadId); err == nil {
+			comments = append(comments, comment)
+		}
+	}
+	sortByID(comments, func(comment model.Comment) uint { return comment.ID })
+	return &comments, nil
+}
+
+func (r *CommentRepository) ReplaceLinks(ctx context.Context, commentId uint, mentions []model.CommentMention, references []model.CommentReference) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// ユニーク制約に違反する場合は何も変更しない
+	mentioned := map[uint]bool{}
+	for _, mention := range mentions {
+		if mentioned[mention.UserID] {
+			return gorm.ErrDuplicatedKey
+		}
+		mentioned[mention.UserID] = true
+	}
+	referenced := map[uint]bool{}
+	for _, reference := range references {
+		if referenced[reference.ReferencedCommentID] {
+			return gorm.ErrDuplicatedKey
+		}
+		referenced[reference.ReferencedCommentID] = true
+	}
+
+	for id, mention := range r.store.mentions {
+		if mention.CommentID == commentId {
+			delete(r.store.mentions, id)
+		}
+	}
+	for id, reference := range r.store.references {
+		if reference.CommentID == commentId {
+			delete(r.store.references, id)
+		}
+	}
+
+	for _, mention := range mentions {
+		mention.ID = r.store.nextID("comment_mentions")
+		r.store.mentions[mention.ID] = mention
+	}
+	for _, reference := range references {
+		reference.ID = r.store.nextID("comment_references")
+		r.store.references[reference.ID] = reference
+	}
+	return nil
+}
+
+func (r *CommentRepository) find(id uint, threadId uint) (model.Comment, error) {
+	comment, ok := r.store.comments[id]
+	if !ok || comment.ThreadID != threadId || comment.DeletedAt.Valid {
+		return model.Comment{}, errors.New("comment not found")
+	}
+	return comment, nil
+}
+
+// 添付ファイルはIAttachmentRepositoryで管理するため、このストアには保持しない
+func (r *CommentRepository) loadAssociations(comment *model.Comment) {
+	comment.Mentions = r.store.commentMentions(comment.ID)
+	comment.References, comment.ReferencedBy = r.store.commentReferences(comment.ID)
+	comment.Attachments = []model.Attachment{}
+}
@@ -0,0 +1,25 @@
+package memory_test
+
+import (
+	"bbs/internal/repository/memory"
+	"bbs/internal/repository/repositorytest"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMemory(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Memory Repository Suite")
+}
+
+var _ = repositorytest.DescribeContract("in-memory", func() repositorytest.Repositories {
+	store := memory.NewStore()
+
+	return repositorytest.Repositories{
+		Auth:     memory.NewAuthRepository(store),
+		Threads:  memory.NewThreadRepository(store),
+		Comments: memory.NewCommentRepository(store),
+	}
+})
@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"bbs/internal/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// リポジトリ間で共有するデータ。GORMの実装と同じく、スレッドの閲覧権限はユーザーの権限や招待されたメンバーから判定する
+type Store struct {
+	mu         sync.Mutex
+	users      map[uint]model.User
+	threads    map[uint]model.Thread
+	members    map[uint]model.ThreadMember
+	comments   map[uint]model.Comment
+	mentions   map[uint]model.CommentMention
+	references map[uint]model.CommentReference
+	lastID     map[string]uint
+	now        func() time.Time
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:      map[uint]model.User{},
+		threads:    map[uint]model.Thread{},
+		members:    map[uint]model.ThreadMember{},
+		comments:   map[uint]model.Comment{},
+		mentions:   map[uint]model.CommentMention{},
+		references: map[uint]model.CommentReference{},
+		lastID:     map[string]uint{},
+		now:        time.Now,
+	}
+}
+
+// テーブルごとの自動採番と同じく、IDは1から振り、削除されても再利用しない
+func (s *Store) nextID(table string) uint {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+func (s *Store) isModerator(userId uint) bool {
+	user, ok := s.users[userId]
+	return ok && !user.DeletedAt.Valid && user.IsModerator()
+}
+
+func (s *Store) isMember(threadId uint, userId uint) bool {
+	for _, member := range s.members {
+		if member.ThreadID == threadId && member.UserID == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// userIdのユーザーが閲覧できるか。userIdが0の場合は未ログインとして扱う
+func (s *Store) visibleTo(thread model.Thread, userId uint) bool {
+	if userId == 0 {
+		return thread.Visibility == model.VisibilityPublic
+	}
+	return thread.Visibility == model.VisibilityPublic ||
+		thread.Visibility == model.VisibilityMembers ||
+		thread.UserID == userId ||
+		s.isMember(thread.ID, userId) ||
+		s.isModerator(userId)
+}
+
+// 匿名の投稿は本人とモデレーター以外には投稿者で絞り込んだ結果に含めない
+func (s *Store) anonymousVisibleTo(comment model.Comment, userId uint) bool {
+	if userId == 0 {
+		return !comment.Anonymous
+	}
+	return !comment.Anonymous || comment.UserID == userId || s.isModerator(userId)
+}
+
+// 削除されていない非表示でないコメントをID順に返す
+func (s *Store) visibleComments(threadId uint) []model.Comment {
+	comments := []model.Comment{}
+	for _, comment := range s.comments {
+		if comment.ThreadID == threadId && !comment.DeletedAt.Valid && !comment.Hidden {
+			comments = append(comments, stripComment(comment))
+		}
+	}
+	sortByID(comments, func(comment model.Comment) uint { return comment.ID })
+	return comments
+}
+
+func (s *Store) threadMembers(threadId uint) []model.ThreadMember {
+	members := []model.ThreadMember{}
+	for _, member := range s.members {
+		if member.ThreadID == threadId {
+			members = append(members, member)
+		}
+	}
+	sortByID(members, func(member model.ThreadMember) uint { return member.ID })
+	return members
+}
+
+func (s *Store) commentMentions(commentId uint) []model.CommentMention {
+	mentions := []model.CommentMention{}
+	for _, mention := range s.mentions {
+		if mention.CommentID == commentId {
+			mentions = append(mentions, mention)
+		}
+	}
+	sortByID(mentions, func(mention model.CommentMention) uint { return mention.ID })
+	return mentions
+}
+
+// 参照(references)と被参照(referencedBy)を返す
+func (s *Store) commentReferences(commentId uint) ([]model.CommentReference, []model.CommentReference) {
+	references := []model.CommentReference{}
+	referencedBy := []model.CommentReference{}
+	for _, reference := range s.references {
+		if reference.CommentID == commentId {
+			references = append(references, reference)
+		}
+		if reference.ReferencedCommentID == commentId {
+			referencedBy = append(referencedBy, reference)
+		}
+	}
+	sortByID(references, func(reference model.CommentReference) uint { return reference.ID })
+	sortByID(referencedBy, func(reference model.CommentReference) uint { return reference.ID })
+	return references, referencedBy
+}
+
+func sortByID[T any](items []T, id func(T) uint) {
+	sort.Slice(items, func(i, j int) bool { return id(items[i]) < id(items[j]) })
+}
+
+// limitとoffsetはGORMの実装と同じく、offsetをページ番号として扱う。limitが負の場合は件数を制限しない
+func paginate[T any](items []T, limit int, offset int) []T {
+	if limit < 0 {
+		return items
+	}
+	start := offset * limit
+	if start < 0 || start >= len(items) {
+		return []T{}
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+// 関連はそれぞれのテーブルで管理するため、保存するときは取り除く
+func stripThread(thread model.Thread) model.Thread {
+	thread.Comments = nil
+	thread.Attachments = nil
+	thread.Members = nil
+	thread.Poll = nil
+	return thread
+}
+
+func stripComment(comment model.Comment) model.Comment {
+	comment.Mentions = nil
+	comment.References = nil
+	comment.ReferencedBy = nil
+	comment.Attachments = nil
+	return comment
+}
@@ -0,0 +1,246 @@
+package memory
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ThreadRepository struct {
+	store *Store
+}
+
+func NewThreadRepository(store *Store) repository.IThreadRepository {
+	return &ThreadRepository{store: store}
+}
+
+func (r *ThreadRepository) Create(ctx context.Context, newThread model.Thread) (*model.Thread, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := r.store.now()
+	newThread.ID = r.store.nextID("threads")
+	newThread.CreatedAt = now
+	newThread.UpdatedAt = now
+	if newThread.Visibility == "" {
+		newThread.Visibility = model.VisibilityPublic
+	}
+	if newThread.LastActivityAt.IsZero() {
+		newThread.LastActivityAt = now
+	}
+	r.store.threads[newThread.ID] = stripThread(newThread)
+	return &newThread, nil
+}
+
+func (r *ThreadRepository) Update(ctx context.Context, updateThread model.Thread) (*model.Thread, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := r.store.now()
+	if updateThread.ID == 0 {
+		updateThread.ID = r.store.nextID("threads")
+		updateThread.CreatedAt = now
+	}
+	updateThread.UpdatedAt = now
+	r.store.threads[updateThread.ID] = stripThread(updateThread)
+	return &updateThread, nil
+}
+
+func (r *ThreadRepository) Delete(ctx context.Context, threadId uint, userId uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	thread, err := r.find(threadId)
+	if err != nil {
+		return err
+	}
+	thread.DeletedAt = gorm.DeletedAt{Time: r.store.now(), Valid: true}
+	r.store.threads[thread.ID] = thread
+	return nil
+}
+
+func (r *ThreadRepository) FindAll(ctx context.Context, limit int, offset int, userId uint) (*dto.ThreadListOutput, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	threads := r.filter(func(thread model.Thread) bool {
+		return !thread.Hidden && r.store.visibleTo(thread, userId)
+	})
+	sort.SliceStable(threads, func(i, j int) bool {
+		if threads[i].Pinned != threads[j].Pinned {
+			return threads[i].Pinned
+		}
+		return threads[i].ID > threads[j].ID
+	})
+	return r.list(threads, limit, offset), nil
+}
+
+func (r *ThreadRepository) FindById(ctx context.Context, threadId uint) (*model.Thread, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	thread, err := r.find(threadId)
+	if err != nil {
+		return nil, err
+	}
+	// 添付ファイルはIAttachmentRepositoryで管理するため、このストアには保持しない
+	thread.Attachments = []model.Attachment{}
+	return &thread, nil
+}
+
+// ストアの操作はすべてロックして行うため、行のロックは不要
+func (r *ThreadRepository) FindByIdForUpdate(ctx context.Context, threadId uint) (*model.Thread, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	thread, err := r.find(threadId)
+	if err != nil {
+		return nil, err
+	}
+	return &thread, nil
+}
+
+func (r *ThreadRepository) FindByUserId(ctx context.Context, userId uint, limit int, offset int) (*dto.ThreadListOutput, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	threads := r.filter(func(thread model.Thread) bool {
+		return thread.UserID == userId && !thread.Hidden
+	})
+	sort.Slice(threads, func(i, j int) bool { return threads[i].ID > threads[j].ID })
+	return r.list(threads, limit, offset), nil
+}
+
+// コメントしたスレッドを最終更新日時の新しい順に返す。招待を取り消されたなど閲覧できなくなったスレッドは除く
+func (r *ThreadRepository) FindParticipating(ctx context.Context, userId uint, limit int, offset int) (*dto.ThreadListOutput, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	commented := map[uint]bool{}
+	for _, comment := range r.store.comments {
+		if comment.UserID == userId && !comment.DeletedAt.Valid {
+			commented[comment.ThreadID] = true
+		}
+	}
+
+	threads := r.filter(func(thread model.Thread) bool {
+		return commented[thread.ID] && !thread.Hidden && r.store.visibleTo(thread, userId)
+	})
+	sort.Slice(threads, func(i, j int) bool {
+		if !threads[i].LastActivityAt.Equal(threads[j].LastActivityAt) {
+			return threads[i].LastActivityAt.After(threads[j].LastActivityAt)
+		}
+		return threads[i].ID > threads[j].ID
+	})
+	return r.list(threads, limit, offset), nil
+}
+
+// 閲覧できないスレッドは存在しないものとして扱う
+func (r *ThreadRepository) FindVisibleById(ctx context.Context, threadId uint, userId uint) (*model.Thread, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	thread, err := r.find(threadId)
+	if err != nil {
+		return nil, err
+	}
+	if !r.store.visibleTo(thread, userId) {
+		return nil, errors.New("thread not found")
+	}
+	thread.Attachments = []model.Attachment{}
+	thread.Members = r.store.threadMembers(thread.ID)
+	return &thread, nil
+}
+
+func (r *ThreadRepository) ReplaceMembers(ctx context.Context, threadId uint, userIds []uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// ユニーク制約に違反する場合は何も変更しない
+	seen := map[uint]bool{}
+	for _, userId := range userIds {
+		if seen[userId] {
+			return gorm.ErrDuplicatedKey
+		}
+		seen[userId] = true
+	}
+
+	for id, member := range r.store.members {
+		if member.ThreadID == threadId {
+			delete(r.store.members, id)
+		}
+	}
+	for _, userId := range userIds {
+		id := r.store.nextID("thread_members")
+		r.store.members[id] = model.ThreadMember{ID: id, ThreadID: threadId, UserID: userId}
+	}
+	return nil
+}
+
+func (r *ThreadRepository) UpdateLastActivity(ctx context.Context, threadId uint, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	thread, err := r.find(threadId)
+	if err != nil {
+		// GORMの実装と同じく、対象がなくてもエラーにしない
+		return nil
+	}
+	thread.LastActivityAt = at
+	thread.UpdatedAt = r.store.now()
+	r.store.threads[thread.ID] = thread
+	return nil
+}
+
+func (r *ThreadRepository) ArchiveInactive(ctx context.Context, before time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// ピン留めされたスレッドは放置されていてもアーカイブしない
+	var archived int64
+	for id, thread := range r.store.threads {
+		if thread.DeletedAt.Valid || thread.Archived || thread.Pinned || !thread.LastActivityAt.Before(before) {
+			continue
+		}
+		thread.Archived = true
+		thread.UpdatedAt = r.store.now()
+		r.store.threads[id] = thread
+		archived++
+	}
+	return archived, nil
+}
+
+func (r *ThreadRepository) find(threadId uint) (model.Thread, error) {
+	thread, ok := r.store.threads[threadId]
+	if !ok || thread.DeletedAt.Valid {
+		return model.Thread{}, errors.New("thread not found")
+	}
+	return thread, nil
+}
+
+// 削除されていないスレッドのうちmatchに該当するものを返す
+func (r *ThreadRepository) filter(match func(thread model.Thread) bool) []model.Thread {
+	threads := []model.Thread{}
+	for _, thread := range r.store.threads {
+		if !thread.DeletedAt.Valid && match(thread) {
+			threads = append(threads, thread)
+		}
+	}
+	sortByID(threads, func(thread model.Thread) uint { return thread.ID })
+	return threads
+}
+
+// 並べ替え済みのスレッドを1ページ分に絞り、非表示でないコメントを読み込む
+func (r *ThreadRepository) list(threads []model.Thread, limit int, offset int) *dto.ThreadListOutput {
+	threadList := dto.ThreadListOutput{Total: int64(len(threads)), Threads: paginate(threads, limit, offset)}
+	for i := range threadList.Threads {
+		threadList.Threads[i].Comments = r.store.visibleComments(threadList.Threads[i].ID)
+	}
+	return &threadList
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"bbs/internal/model"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PollRepository struct {
+	db *gorm.DB
+}
+
+func NewPollRepository(db *gorm.DB) IPollRepository {
+	return &PollRepository{db: db}
+}
+
+func (r *PollRepository) Create(ctx context.Context, newPoll model.Poll) (*model.Poll, error) {
+	result := r.db.WithContext(ctx).Create(&newPoll)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newPoll, nil
+}
+
+// 選択肢ごとの集計と、userIdのユーザーが選んだ選択肢を含めて返す。userIdが0の場合は未ログインとして扱う
+func (r *PollRepository) FindByThreadId(ctx context.Context, threadId uint, userId uint) (*model.Poll, error) {
+	var poll model.Poll
+	result := r.db.WithContext(ctx).Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).First(&poll, "thread_id = ?", threadId)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("poll not found")
+		}
+		return nil, result.Error
+	}
+
+	poll.Closed = poll.IsClosed(time.Now())
+
+	if err := r.db.WithContext(ctx).Model(&model.PollVote{}).Where("poll_id = ?", poll.ID).Count(&poll.TotalVoters).Error; err != nil {
+		return nil, err
+	}
+
+	var choices []struct {
+		OptionID uint
+		UserID   uint
+	}
+	result = r.db.WithContext(ctx).Model(&model.PollVoteChoice{}).
+		Select("poll_vote_choices.option_id, poll_votes.user_id").
+		Joins("JOIN poll_votes ON poll_votes.id = poll_vote_choices.vote_id").
+		Where("poll_votes.poll_id = ?", poll.ID).
+		Order("poll_votes.id").
+		Scan(&choices)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	options := make(map[uint]*model.PollOption, len(poll.Options))
+	for i := range poll.Options {
+		options[poll.Options[i].ID] = &poll.Options[i]
+	}
+	poll.MyChoices = []uint{}
+	for _, choice := range choices {
+		option, ok := options[choice.OptionID]
+		if !ok {
+			continue
+		}
+		option.VoteCount++
+		if poll.PublicVotes {
+			option.Voters = append(option.Voters, choice.UserID)
+		}
+		if userId != 0 && choice.UserID == userId {
+			poll.MyChoices = append(poll.MyChoices, choice.OptionID)
+		}
+	}
+
+	return &poll, nil
+}
+
+// 同じユーザーの投票が同時に行われた場合もユニークインデックスにより1件しか登録されない
+func (r *PollRepository) CreateVote(ctx context.Context, newVote model.PollVote) error {
+	result := r.db.WithContext(ctx).Create(&newVote)
+	if result.Error != nil {
+		var count int64
+		if err := r.db.WithContext(ctx).Model(&model.PollVote{}).Where("poll_id = ? AND user_id = ?", newVote.PollID, newVote.UserID).Count(&count).Error; err == nil && count > 0 {
+			return errors.New("already voted")
+		}
+		return result.Error
+	}
+	return nil
+}
+
+func (r *PollRepository) DeleteVote(ctx context.Context, pollId uint, userId uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var vote model.PollVote
+		if err := tx.First(&vote, "poll_id = ? AND user_id = ?", pollId, userId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("vote not found")
+			}
+			return err
+		}
+		if err := tx.Where("vote_id = ?", vote.ID).Delete(&model.PollVoteChoice{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&vote).Error
+	})
+}
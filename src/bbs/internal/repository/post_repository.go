@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"bbs/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// スレッドとコメントをまとめて投稿として扱う
+type PostRepository struct {
+	db *gorm.DB
+}
+
+func NewPostRepository(db *gorm.DB) IPostRepository {
+	return &PostRepository{db: db}
+}
+
+func (r *PostRepository) CountRecentByUser(ctx context.Context, userId uint, body string, since time.Time) (int64, error) {
+	var threadCount int64
+	result := r.db.WithContext(ctx).Model(&model.Thread{}).Where("user_id = ? AND body = ? AND created_at >= ?", userId, body, since).Count(&threadCount)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	var commentCount int64
+	result = r.db.WithContext(ctx).Model(&model.Comment{}).Where("user_id = ? AND body = ? AND created_at >= ?", userId, body, since).Count(&commentCount)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return threadCount + commentCount, nil
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReportRepository struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) IReportRepository {
+	return &ReportRepository{db: db}
+}
+
+func (r *ReportRepository) Create(ctx context.Context, newReport model.Report) (*model.Report, error) {
+	result := r.db.WithContext(ctx).Create(&newReport)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newReport, nil
+}
+
+func (r *ReportRepository) CountByReporter(ctx context.Context, reporterId uint, threadId uint, commentId *uint) (int64, error) {
+	var count int64
+	result := r.target(ctx, threadId, commentId).Where("reporter_id = ?", reporterId).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+func (r *ReportRepository) CountOpen(ctx context.Context, threadId uint, commentId *uint) (int64, error) {
+	var count int64
+	result := r.target(ctx, threadId, commentId).Where("status = ?", model.ReportStatusOpen).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+func (r *ReportRepository) FindQueue(ctx context.Context, limit int, offset int) (*dto.ModerationQueueOutput, error) {
+	var queue dto.ModerationQueueOutput
+
+	open := r.db.WithContext(ctx).Model(&model.Report{}).Where("status = ?", model.ReportStatusOpen)
+
+	// 未対応の通報がある対象の数
+	result := r.db.WithContext(ctx).Table("(?) AS targets", open.Session(&gorm.Session{}).Select("thread_id, comment_id").Group("thread_id, comment_id")).Count(&queue.Total)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var targets []struct {
+		ThreadID    uint
+		CommentID   *uint
+		ReportCount int64
+	}
+	result = open.Session(&gorm.Session{}).
+		Select("thread_id, comment_id, COUNT(*) AS report_count").
+		Group("thread_id, comment_id").
+		Order("MAX(id) desc").
+		Limit(limit).Offset(offset * limit).
+		Scan(&targets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	// ページ内の通報・スレッド・コメントはそれぞれ1回の問い合わせでまとめて読み込む
+	threadIds := make([]uint, 0, len(targets))
+	threadTargetIds := []uint{}
+	commentIds := []uint{}
+	for _, target := range targets {
+		threadIds = append(threadIds, target.ThreadID)
+		if target.CommentID == nil {
+			threadTargetIds = append(threadTargetIds, target.ThreadID)
+		} else {
+			commentIds = append(commentIds, *target.CommentID)
+		}
+	}
+
+	var reports []model.Report
+	result = r.db.WithContext(ctx).
+		Where("status = ?", model.ReportStatusOpen).
+		Where(r.db.Where("comment_id IS NULL AND thread_id IN ?", threadTargetIds).Or("comment_id IN ?", commentIds)).
+		Order("id desc").
+		Find(&reports)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	threadReports := map[uint][]model.Report{}
+	commentReports := map[uint][]model.Report{}
+	for _, report := range reports {
+		if report.CommentID == nil {
+			threadReports[report.ThreadID] = append(threadReports[report.ThreadID], report)
+		} else {
+			commentReports[*report.CommentID] = append(commentReports[*report.CommentID], report)
+		}
+	}
+
+	// 非表示にされた内容もモデレーターが確認できるよう、通報対象はここで直接読み込む
+	var threads []model.Thread
+	if err := r.db.WithContext(ctx).Find(&threads, "id IN ?", threadIds).Error; err != nil {
+		return nil, err
+	}
+	threadsById := make(map[uint]*model.Thread, len(threads))
+	for i := range threads {
+		threadsById[threads[i].ID] = &threads[i]
+	}
+	var comments []model.Comment
+	if err := r.db.WithContext(ctx).Find(&comments, "id IN ?", commentIds).Error; err != nil {
+		return nil, err
+	}
+	commentsById := make(map[uint]*model.Comment, len(comments))
+	for i := range comments {
+		commentsById[comments[i].ID] = &comments[i]
+	}
+
+	queue.Items = make([]dto.ModerationQueueItem, len(targets))
+	for i, target := range targets {
+		item := dto.ModerationQueueItem{
+			ThreadID:    target.ThreadID,
+			CommentID:   target.CommentID,
+			ReportCount: target.ReportCount,
+			Thread:      threadsById[target.ThreadID],
+		}
+		if target.CommentID == nil {
+			item.Reports = threadReports[target.ThreadID]
+		} else {
+			item.Reports = commentReports[*target.CommentID]
+			item.Comment = commentsById[*target.CommentID]
+		}
+		if len(item.Reports) > 0 {
+			item.LatestReportedAt = item.Reports[0].CreatedAt
+		}
+
+		queue.Items[i] = item
+	}
+
+	return &queue, nil
+}
+
+func (r *ReportRepository) Resolve(ctx context.Context, threadId uint, commentId *uint, status string, moderatorId uint) (int64, error) {
+	result := r.target(ctx, threadId, commentId).
+		Where("status = ?", model.ReportStatusOpen).
+		Updates(map[string]interface{}{"status": status, "resolved_by": moderatorId, "resolved_at": time.Now()})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *ReportRepository) target(ctx context.Context, threadId uint, commentId *uint) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&model.Report{}).Where("thread_id = ?", threadId)
+	if commentId == nil {
+		return query.Where("comment_id IS NULL")
+	}
+	return query.Where("comment_id = ?", *commentId)
+}
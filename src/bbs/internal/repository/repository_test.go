@@ -0,0 +1,75 @@
+package repository_test
+
+import (
+	"bbs/internal/infra"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"bbs/internal/repository/repositorytest"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var db *gorm.DB
+
+func TestRepository(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Repository Suite")
+}
+
+var _ = BeforeSuite(func() {
+	currentDir, _ := os.Getwd()
+	infra.TestInit(filepath.Join(currentDir, "..", "..", "configs", ".env.test"))
+	db = infra.SetUpDB()
+	Expect(infra.Migrate(db)).To(Succeed())
+})
+
+// テストごとにトランザクションを開始し、終了後にロールバックする
+var _ = repositorytest.DescribeContract("GORM", func() repositorytest.Repositories {
+	tx := db.Begin()
+	DeferCleanup(func() {
+		tx.Rollback()
+	})
+
+	return repositorytest.Repositories{
+		Auth:     repository.NewAuthRepository(tx),
+		Threads:  repository.NewThreadRepository(tx),
+		Comments: repository.NewCommentRepository(tx),
+	}
+})
+
+var _ = Describe("RevisionRepository", func() {
+	var tx *gorm.DB
+
+	BeforeEach(func() {
+		tx = db.Begin()
+		DeferCleanup(func() {
+			tx.Rollback()
+		})
+	})
+
+	It("版の番号は連番になり、同じ番号の版は作成できない", func() {
+		revisions := repository.NewRevisionRepository(tx)
+		ctx := context.Background()
+
+		first, err := revisions.CreateThreadRevision(ctx, model.ThreadRevision{ThreadID: 1, Title: "1", Body: "1", EditedBy: 1})
+		Expect(err).To(BeNil())
+		second, err := revisions.CreateThreadRevision(ctx, model.ThreadRevision{ThreadID: 1, Title: "2", Body: "2", EditedBy: 1})
+		Expect(err).To(BeNil())
+		Expect([]int{first.Revision, second.Revision}).To(Equal([]int{1, 2}))
+
+		// PostgreSQLでは失敗した時点でトランザクションが中断されるため、セーブポイントまで戻してから続ける
+		tx.SavePoint("duplicate")
+		Expect(tx.Create(&model.ThreadRevision{ThreadID: 1, Revision: 2, Title: "3", Body: "3", EditedBy: 1}).Error).NotTo(BeNil())
+		tx.RollbackTo("duplicate")
+
+		Expect(tx.Create(&model.CommentRevision{CommentID: 1, Revision: 1, Body: "1", EditedBy: 1}).Error).To(BeNil())
+		Expect(tx.Create(&model.CommentRevision{CommentID: 1, Revision: 1, Body: "2", EditedBy: 1}).Error).NotTo(BeNil())
+	})
+})
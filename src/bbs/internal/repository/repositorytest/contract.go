@@ -0,0 +1,528 @@
+// 各リポジトリの実装が同じ振る舞いをすることを確認する共通のテスト
+package repositorytest
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type Repositories struct {
+	Auth     repository.IAuthRepository
+	Threads  repository.IThreadRepository
+	Comments repository.ICommentRepository
+}
+
+// newRepositoriesは各テストの前に呼ばれ、データが空の状態のリポジトリを返すこと
+func DescribeContract(name string, newRepositories func() Repositories) bool {
+	return Describe(name, func() {
+		var repos Repositories
+		var ctx context.Context
+
+		BeforeEach(func() {
+			repos = newRepositories()
+			ctx = context.Background()
+		})
+
+		createUser := func(name string, role string) *model.User {
+			Expect(repos.Auth.CreateUser(ctx, model.User{Name: name, Email: name + "@example.com", Password: "password", Role: role})).To(Succeed())
+			user, err := repos.Auth.FindUser(ctx, name+"@example.com")
+			Expect(err).To(BeNil())
+			return user
+		}
+
+		createThread := func(thread model.Thread) *model.Thread {
+			if thread.Title == "" {
+				thread.Title = "テストタイトル"
+			}
+			thread.Body = "テスト本文"
+			created, err := repos.Threads.Create(ctx, thread)
+			Expect(err).To(BeNil())
+			return created
+		}
+
+		createComment := func(comment model.Comment) *model.Comment {
+			comment.Body = "コメント本文"
+			created, err := repos.Comments.Create(ctx, comment)
+			Expect(err).To(BeNil())
+			return created
+		}
+
+		threadIds := func(threadList *dto.ThreadListOutput) []uint {
+			ids := []uint{}
+			for _, thread := range threadList.Threads {
+				ids = append(ids, thread.ID)
+			}
+			return ids
+		}
+
+		commentIds := func(commentList *dto.CommentListOutput) []uint {
+			ids := []uint{}
+			for _, comment := range commentList.Comments {
+				ids = append(ids, comment.ID)
+			}
+			return ids
+		}
+
+		Describe("IAuthRepository", func() {
+			It("作成したユーザーをemailとIDで取得できる", func() {
+				user := createUser("alice", "")
+
+				Expect(user.ID).NotTo(BeZero())
+				Expect(user.Name).To(Equal("alice"))
+				Expect(user.Role).To(Equal(model.RoleMember))
+
+				found, err := repos.Auth.FindUserById(ctx, user.ID)
+				Expect(err).To(BeNil())
+				Expect(found.Email).To(Equal("alice@example.com"))
+			})
+
+			It("同じemailのユーザーは作成できない", func() {
+				createUser("alice", "")
+
+				err := repos.Auth.CreateUser(ctx, model.User{Name: "other", Email: "alice@example.com", Password: "password"})
+
+				Expect(err).NotTo(BeNil())
+			})
+
+			It("存在しないユーザーはuser not foundを返す", func() {
+				_, err := repos.Auth.FindUser(ctx, "missing@example.com")
+				Expect(err).To(MatchError("user not found"))
+
+				_, err = repos.Auth.FindUserById(ctx, 1<<30)
+				Expect(err).To(MatchError("user not found"))
+			})
+
+			It("名前が一致するユーザーだけを返す", func() {
+				alice := createUser("alice", "")
+				createUser("bob", "")
+				carol := createUser("carol", "")
+
+				users, err := repos.Auth.FindUsersByNames(ctx, []string{"alice", "carol", "missing"})
+
+				Expect(err).To(BeNil())
+				Expect(*users).To(HaveLen(2))
+				Expect([]uint{(*users)[0].ID, (*users)[1].ID}).To(ConsistOf(alice.ID, carol.ID))
+			})
+		})
+
+		Describe("IThreadRepository", func() {
+			var owner *model.User
+
+			BeforeEach(func() {
+				owner = createUser("owner", "")
+			})
+
+			It("作成したスレッドを取得できる", func() {
+				thread := createThread(model.Thread{UserID: owner.ID})
+
+				found, err := repos.Threads.FindById(ctx, thread.ID)
+
+				Expect(err).To(BeNil())
+				Expect(found.Title).To(Equal("テストタイトル"))
+				Expect(found.UserID).To(Equal(owner.ID))
+				Expect(found.Visibility).To(Equal(model.VisibilityPublic))
+			})
+
+			It("存在しないスレッドはthread not foundを返す", func() {
+				_, err := repos.Threads.FindById(ctx, 1<<30)
+				Expect(err).To(MatchError("thread not found"))
+
+				_, err = repos.Threads.FindByIdForUpdate(ctx, 1<<30)
+				Expect(err).To(MatchError("thread not found"))
+
+				_, err = repos.Threads.FindVisibleById(ctx, 1<<30, owner.ID)
+				Expect(err).To(MatchError("thread not found"))
+
+				Expect(repos.Threads.Delete(ctx, 1<<30, owner.ID)).To(MatchError("thread not found"))
+			})
+
+			It("更新した内容が保存される", func() {
+				thread := createThread(model.Thread{UserID: owner.ID})
+				thread.Title = "更新後のタイトル"
+				thread.Locked = true
+
+				_, err := repos.Threads.Update(ctx, *thread)
+				Expect(err).To(BeNil())
+
+				found, _ := repos.Threads.FindById(ctx, thread.ID)
+				Expect(found.Title).To(Equal("更新後のタイトル"))
+				Expect(found.Locked).To(BeTrue())
+			})
+
+			Context("削除した場合", func() {
+				It("論理削除され、取得できなくなる", func() {
+					thread := createThread(model.Thread{UserID: owner.ID})
+
+					Expect(repos.Threads.Delete(ctx, thread.ID, owner.ID)).To(Succeed())
+
+					_, err := repos.Threads.FindById(ctx, thread.ID)
+					Expect(err).To(MatchError("thread not found"))
+					_, err = repos.Threads.FindVisibleById(ctx, thread.ID, owner.ID)
+					Expect(err).To(MatchError("thread not found"))
+					Expect(repos.Threads.Delete(ctx, thread.ID, owner.ID)).To(MatchError("thread not found"))
+
+					threadList, err := repos.Threads.FindAll(ctx, 10, 0, owner.ID)
+					Expect(err).To(BeNil())
+					Expect(threadList.Total).To(BeZero())
+				})
+
+				It("コメントは削除されないが、スレッドのコメント一覧には含まれない", func() {
+					thread := createThread(model.Thread{UserID: owner.ID})
+					comment := createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID})
+
+					Expect(repos.Threads.Delete(ctx, thread.ID, owner.ID)).To(Succeed())
+
+					_, err := repos.Comments.FindById(ctx, comment.ID, thread.ID)
+					Expect(err).To(BeNil())
+
+					commentList, err := repos.Comments.FindByThreadId(ctx, thread.ID, dto.CommentListQuery{Limit: 10}, owner.ID)
+					Expect(err).To(BeNil())
+					Expect(commentList.Total).To(BeZero())
+				})
+			})
+
+			Describe("FindAll", func() {
+				It("ピン留めを先頭に新しい順で返し、非表示のスレッドは除く", func() {
+					first := createThread(model.Thread{UserID: owner.ID})
+					pinned := createThread(model.Thread{UserID: owner.ID, Pinned: true})
+					createThread(model.Thread{UserID: owner.ID, Hidden: true})
+					last := createThread(model.Thread{UserID: owner.ID})
+
+					threadList, err := repos.Threads.FindAll(ctx, 10, 0, owner.ID)
+
+					Expect(err).To(BeNil())
+					Expect(threadList.Total).To(Equal(int64(3)))
+					Expect(threadIds(threadList)).To(Equal([]uint{pinned.ID, last.ID, first.ID}))
+				})
+
+				It("offsetをページ番号としてlimit件ずつ返す", func() {
+					var threads []*model.Thread
+					for i := 0; i < 5; i++ {
+						threads = append(threads, createThread(model.Thread{UserID: owner.ID}))
+					}
+
+					threadList, err := repos.Threads.FindAll(ctx, 2, 1, owner.ID)
+
+					Expect(err).To(BeNil())
+					Expect(threadList.Total).To(Equal(int64(5)))
+					Expect(threadIds(threadList)).To(Equal([]uint{threads[2].ID, threads[1].ID}))
+				})
+
+				It("削除・非表示でないコメントを含める", func() {
+					thread := createThread(model.Thread{UserID: owner.ID})
+					visible := createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID})
+					createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID, Hidden: true})
+					deleted := createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID})
+					Expect(repos.Comments.Delete(ctx, deleted.ID, thread.ID, owner.ID)).To(Succeed())
+
+					threadList, err := repos.Threads.FindAll(ctx, 10, 0, owner.ID)
+
+					Expect(err).To(BeNil())
+					Expect(threadList.Threads[0].Comments).To(HaveLen(1))
+					Expect(threadList.Threads[0].Comments[0].ID).To(Equal(visible.ID))
+				})
+			})
+
+			Describe("公開範囲", func() {
+				var public, members, private *model.Thread
+				var member, outsider, moderator *model.User
+
+				BeforeEach(func() {
+					member = createUser("member", "")
+					outsider = createUser("outsider", "")
+					moderator = createUser("moderator", model.RoleModerator)
+
+					public = createThread(model.Thread{UserID: owner.ID, Visibility: model.VisibilityPublic})
+					members = createThread(model.Thread{UserID: owner.ID, Visibility: model.VisibilityMembers})
+					private = createThread(model.Thread{UserID: owner.ID, Visibility: model.VisibilityPrivate})
+					Expect(repos.Threads.ReplaceMembers(ctx, private.ID, []uint{member.ID})).To(Succeed())
+				})
+
+				It("未ログインの場合は公開のスレッドだけを返す", func() {
+					threadList, err := repos.Threads.FindAll(ctx, 10, 0, 0)
+
+					Expect(err).To(BeNil())
+					Expect(threadIds(threadList)).To(Equal([]uint{public.ID}))
+
+					_, err = repos.Threads.FindVisibleById(ctx, members.ID, 0)
+					Expect(err).To(MatchError("thread not found"))
+				})
+
+				It("招待されていないユーザーには非公開のスレッドを返さない", func() {
+					threadList, err := repos.Threads.FindAll(ctx, 10, 0, outsider.ID)
+
+					Expect(err).To(BeNil())
+					Expect(threadIds(threadList)).To(Equal([]uint{members.ID, public.ID}))
+
+					_, err = repos.Threads.FindVisibleById(ctx, private.ID, outsider.ID)
+					Expect(err).To(MatchError("thread not found"))
+				})
+
+				It("作成者・招待されたユーザー・モデレーターは非公開のスレッドを閲覧できる", func() {
+					for _, userId := range []uint{owner.ID, member.ID, moderator.ID} {
+						threadList, err := repos.Threads.FindAll(ctx, 10, 0, userId)
+						Expect(err).To(BeNil())
+						Expect(threadIds(threadList)).To(Equal([]uint{private.ID, members.ID, public.ID}))
+
+						thread, err := repos.Threads.FindVisibleById(ctx, private.ID, userId)
+						Expect(err).To(BeNil())
+						Expect(thread.Members).To(HaveLen(1))
+						Expect(thread.Members[0].UserID).To(Equal(member.ID))
+					}
+				})
+
+				It("招待を取り消すと閲覧できなくなる", func() {
+					Expect(repos.Threads.ReplaceMembers(ctx, private.ID, []uint{})).To(Succeed())
+
+					_, err := repos.Threads.FindVisibleById(ctx, private.ID, member.ID)
+
+					Expect(err).To(MatchError("thread not found"))
+				})
+			})
+
+			It("FindByUserIdは作成者のスレッドを新しい順に返し、非表示のスレッドは除く", func() {
+				other := createUser("other", "")
+				first := createThread(model.Thread{UserID: owner.ID})
+				createThread(model.Thread{UserID: other.ID})
+				createThread(model.Thread{UserID: owner.ID, Hidden: true})
+				last := createThread(model.Thread{UserID: owner.ID})
+
+				threadList, err := repos.Threads.FindByUserId(ctx, owner.ID, 10, 0)
+
+				Expect(err).To(BeNil())
+				Expect(threadList.Total).To(Equal(int64(2)))
+				Expect(threadIds(threadList)).To(Equal([]uint{last.ID, first.ID}))
+			})
+
+			It("FindParticipatingはコメントしたスレッドを最終更新日時の新しい順に返す", func() {
+				commenter := createUser("commenter", "")
+				now := time.Now()
+				older := createThread(model.Thread{UserID: owner.ID})
+				newer := createThread(model.Thread{UserID: owner.ID})
+				createThread(model.Thread{UserID: owner.ID})
+				deletedComment := createThread(model.Thread{UserID: owner.ID})
+
+				createComment(model.Comment{UserID: commenter.ID, ThreadID: older.ID})
+				createComment(model.Comment{UserID: commenter.ID, ThreadID: newer.ID})
+				comment := createComment(model.Comment{UserID: commenter.ID, ThreadID: deletedComment.ID})
+				Expect(repos.Comments.Delete(ctx, comment.ID, deletedComment.ID, commenter.ID)).To(Succeed())
+				Expect(repos.Threads.UpdateLastActivity(ctx, older.ID, now.Add(-time.Hour))).To(Succeed())
+				Expect(repos.Threads.UpdateLastActivity(ctx, newer.ID, now)).To(Succeed())
+
+				threadList, err := repos.Threads.FindParticipating(ctx, commenter.ID, 10, 0)
+
+				Expect(err).To(BeNil())
+				Expect(threadList.Total).To(Equal(int64(2)))
+				Expect(threadIds(threadList)).To(Equal([]uint{newer.ID, older.ID}))
+			})
+
+			It("ArchiveInactiveは一定期間書き込みのないスレッドをピン留めを除いてアーカイブする", func() {
+				now := time.Now()
+				inactive := createThread(model.Thread{UserID: owner.ID})
+				pinned := createThread(model.Thread{UserID: owner.ID, Pinned: true})
+				active := createThread(model.Thread{UserID: owner.ID})
+				Expect(repos.Threads.UpdateLastActivity(ctx, inactive.ID, now.Add(-48*time.Hour))).To(Succeed())
+				Expect(repos.Threads.UpdateLastActivity(ctx, pinned.ID, now.Add(-48*time.Hour))).To(Succeed())
+				Expect(repos.Threads.UpdateLastActivity(ctx, active.ID, now)).To(Succeed())
+
+				archived, err := repos.Threads.ArchiveInactive(ctx, now.Add(-24*time.Hour))
+
+				Expect(err).To(BeNil())
+				Expect(archived).To(Equal(int64(1)))
+				for id, expected := range map[uint]bool{inactive.ID: true, pinned.ID: false, active.ID: false} {
+					thread, _ := repos.Threads.FindById(ctx, id)
+					Expect(thread.Archived).To(Equal(expected))
+				}
+			})
+		})
+
+		Describe("ICommentRepository", func() {
+			var owner *model.User
+			var thread *model.Thread
+
+			BeforeEach(func() {
+				owner = createUser("owner", "")
+				thread = createThread(model.Thread{UserID: owner.ID})
+			})
+
+			It("作成したコメントを取得できる", func() {
+				comment := createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID})
+
+				found, err := repos.Comments.FindById(ctx, comment.ID, thread.ID)
+
+				Expect(err).To(BeNil())
+				Expect(found.Body).To(Equal("コメント本文"))
+				Expect(found.UserID).To(Equal(owner.ID))
+
+				locked, err := repos.Comments.FindByIdForUpdate(ctx, comment.ID, thread.ID)
+				Expect(err).To(BeNil())
+				Expect(locked.Body).To(Equal("コメント本文"))
+			})
+
+			It("存在しないコメントや別のスレッドのコメントはcomment not foundを返す", func() {
+				other := createThread(model.Thread{UserID: owner.ID})
+				comment := createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID})
+
+				_, err := repos.Comments.FindById(ctx, comment.ID, other.ID)
+				Expect(err).To(MatchError("comment not found"))
+
+				_, err = repos.Comments.FindById(ctx, 1<<30, thread.ID)
+				Expect(err).To(MatchError("comment not found"))
+
+				_, err = repos.Comments.FindByIdForUpdate(ctx, comment.ID, other.ID)
+				Expect(err).To(MatchError("comment not found"))
+
+				Expect(repos.Comments.Delete(ctx, comment.ID, other.ID, owner.ID)).To(MatchError("comment not found"))
+			})
+
+			It("更新した内容が保存される", func() {
+				comment := createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID})
+				comment.Body = "更新後の本文"
+				comment.Hidden = true
+
+				_, err := repos.Comments.Update(ctx, *comment)
+				Expect(err).To(BeNil())
+
+				found, _ := repos.Comments.FindById(ctx, comment.ID, thread.ID)
+				Expect(found.Body).To(Equal("更新後の本文"))
+				Expect(found.Hidden).To(BeTrue())
+			})
+
+			It("削除したコメントは論理削除され、取得できなくなる", func() {
+				comment := createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID})
+
+				Expect(repos.Comments.Delete(ctx, comment.ID, thread.ID, owner.ID)).To(Succeed())
+
+				_, err := repos.Comments.FindById(ctx, comment.ID, thread.ID)
+				Expect(err).To(MatchError("comment not found"))
+				Expect(repos.Comments.Delete(ctx, comment.ID, thread.ID, owner.ID)).To(MatchError("comment not found"))
+
+				comments, err := repos.Comments.FindByIds(ctx, []uint{comment.ID}, thread.ID)
+				Expect(err).To(BeNil())
+				Expect(*comments).To(BeEmpty())
+			})
+
+			Describe("FindByThreadId", func() {
+				It("非表示のコメントを除いて指定した順に返す", func() {
+					first := createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID})
+					createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID, Hidden: true})
+					last := createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID})
+
+					asc, err := repos.Comments.FindByThreadId(ctx, thread.ID, dto.CommentListQuery{Limit: 10, Order: dto.OrderAsc}, owner.ID)
+					Expect(err).To(BeNil())
+					Expect(asc.Total).To(Equal(int64(2)))
+					Expect(commentIds(asc)).To(Equal([]uint{first.ID, last.ID}))
+
+					desc, err := repos.Comments.FindByThreadId(ctx, thread.ID, dto.CommentListQuery{Limit: 10, Order: dto.OrderDesc}, owner.ID)
+					Expect(err).To(BeNil())
+					Expect(commentIds(desc)).To(Equal([]uint{last.ID, first.ID}))
+				})
+
+				It("offsetをページ番号としてlimit件ずつ返す", func() {
+					var comments []*model.Comment
+					for i := 0; i < 5; i++ {
+						comments = append(comments, createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID}))
+					}
+
+					commentList, err := repos.Comments.FindByThreadId(ctx, thread.ID, dto.CommentListQuery{Limit: 2, Offset: 2}, owner.ID)
+
+					Expect(err).To(BeNil())
+					Expect(commentList.Total).To(Equal(int64(5)))
+					Expect(commentIds(commentList)).To(Equal([]uint{comments[4].ID}))
+				})
+
+				It("閲覧できないスレッドのコメントは返さない", func() {
+					private := createThread(model.Thread{UserID: owner.ID, Visibility: model.VisibilityPrivate})
+					createComment(model.Comment{UserID: owner.ID, ThreadID: private.ID})
+					outsider := createUser("outsider", "")
+
+					commentList, err := repos.Comments.FindByThreadId(ctx, private.ID, dto.CommentListQuery{Limit: 10}, outsider.ID)
+
+					Expect(err).To(BeNil())
+					Expect(commentList.Total).To(BeZero())
+				})
+			})
+
+			Describe("FindByUserId", func() {
+				var author, viewer, moderator *model.User
+				var named, anonymous *model.Comment
+
+				BeforeEach(func() {
+					author = createUser("author", "")
+					viewer = createUser("viewer", "")
+					moderator = createUser("moderator", model.RoleModerator)
+
+					named = createComment(model.Comment{UserID: author.ID, ThreadID: thread.ID})
+					anonymous = createComment(model.Comment{UserID: author.ID, ThreadID: thread.ID, Anonymous: true})
+					createComment(model.Comment{UserID: viewer.ID, ThreadID: thread.ID})
+					hiddenThread := createThread(model.Thread{UserID: owner.ID, Hidden: true})
+					createComment(model.Comment{UserID: author.ID, ThreadID: hiddenThread.ID})
+				})
+
+				It("他のユーザーには匿名のコメントと非表示のスレッドのコメントを返さない", func() {
+					commentList, err := repos.Comments.FindByUserId(ctx, author.ID, dto.CommentListQuery{Limit: 10}, viewer.ID)
+
+					Expect(err).To(BeNil())
+					Expect(commentList.Total).To(Equal(int64(1)))
+					Expect(commentIds(commentList)).To(Equal([]uint{named.ID}))
+				})
+
+				It("本人とモデレーターには匿名のコメントも返す", func() {
+					for _, userId := range []uint{author.ID, moderator.ID} {
+						commentList, err := repos.Comments.FindByUserId(ctx, author.ID, dto.CommentListQuery{Limit: 10}, userId)
+
+						Expect(err).To(BeNil())
+						Expect(commentIds(commentList)).To(Equal([]uint{named.ID, anonymous.ID}))
+					}
+				})
+			})
+
+			It("FindByIdsは同じスレッドのコメントだけを返す", func() {
+				other := createThread(model.Thread{UserID: owner.ID})
+				first := createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID})
+				second := createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID})
+				otherComment := createComment(model.Comment{UserID: owner.ID, ThreadID: other.ID})
+
+				comments, err := repos.Comments.FindByIds(ctx, []uint{first.ID, second.ID, otherComment.ID}, thread.ID)
+
+				Expect(err).To(BeNil())
+				Expect(*comments).To(HaveLen(2))
+				Expect([]uint{(*comments)[0].ID, (*comments)[1].ID}).To(ConsistOf(first.ID, second.ID))
+			})
+
+			It("ReplaceLinksで保存した言及と参照を読み込み、置き換えた場合は以前のものを削除する", func() {
+				mentioned := createUser("mentioned", "")
+				referenced := createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID})
+				comment := createComment(model.Comment{UserID: owner.ID, ThreadID: thread.ID})
+
+				Expect(repos.Comments.ReplaceLinks(ctx, comment.ID,
+					[]model.CommentMention{{CommentID: comment.ID, UserID: mentioned.ID, Name: mentioned.Name}},
+					[]model.CommentReference{{CommentID: comment.ID, ReferencedCommentID: referenced.ID}},
+				)).To(Succeed())
+
+				found, err := repos.Comments.FindById(ctx, comment.ID, thread.ID)
+				Expect(err).To(BeNil())
+				Expect(found.Mentions).To(HaveLen(1))
+				Expect(found.Mentions[0].UserID).To(Equal(mentioned.ID))
+				Expect(found.References).To(HaveLen(1))
+				Expect(found.References[0].ReferencedCommentID).To(Equal(referenced.ID))
+
+				referencedFound, _ := repos.Comments.FindById(ctx, referenced.ID, thread.ID)
+				Expect(referencedFound.ReferencedBy).To(HaveLen(1))
+				Expect(referencedFound.ReferencedBy[0].CommentID).To(Equal(comment.ID))
+
+				Expect(repos.Comments.ReplaceLinks(ctx, comment.ID, nil, nil)).To(Succeed())
+
+				found, _ = repos.Comments.FindById(ctx, comment.ID, thread.ID)
+				Expect(found.Mentions).To(BeEmpty())
+				Expect(found.References).To(BeEmpty())
+			})
+		})
+	})
+}
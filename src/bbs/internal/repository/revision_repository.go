@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"bbs/internal/model"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type RevisionRepository struct {
+	db *gorm.DB
+}
+
+func NewRevisionRepository(db *gorm.DB) IRevisionRepository {
+	return &RevisionRepository{db: db}
+}
+
+// 版の番号は件数から決めるため、同じスレッドの更新はスレッドの行をロックしてから呼び出す。
+// 同時に作成された場合も(thread_id, revision)の一意制約により一方が失敗する
+func (r *RevisionRepository) CreateThreadRevision(ctx context.Context, newRevision model.ThreadRevision) (*model.ThreadRevision, error) {
+	var count int64
+	result := r.db.WithContext(ctx).Model(&model.ThreadRevision{}).Where("thread_id = ?", newRevision.ThreadID).Count(&count)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	newRevision.Revision = int(count) + 1
+
+	result = r.db.WithContext(ctx).Create(&newRevision)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newRevision, nil
+}
+
+func (r *RevisionRepository) FindThreadRevisions(ctx context.Context, threadId uint) (*[]model.ThreadRevision, error) {
+	var revisions []model.ThreadRevision
+	result := r.db.WithContext(ctx).Where("thread_id = ?", threadId).Order("revision asc").Find(&revisions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &revisions, nil
+}
+
+func (r *RevisionRepository) FindThreadRevision(ctx context.Context, id uint, threadId uint) (*model.ThreadRevision, error) {
+	var revision model.ThreadRevision
+	result := r.db.WithContext(ctx).First(&revision, "id = ? AND thread_id = ?", id, threadId)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("revision not found")
+		}
+		return nil, result.Error
+	}
+	return &revision, nil
+}
+
+// CreateThreadRevisionと同様に、コメントの行をロックしてから呼び出す
+func (r *RevisionRepository) CreateCommentRevision(ctx context.Context, newRevision model.CommentRevision) (*model.CommentRevision, error) {
+	var count int64
+	result := r.db.WithContext(ctx).Model(&model.CommentRevision{}).Where("comment_id = ?", newRevision.CommentID).Count(&count)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	newRevision.Revision = int(count) + 1
+
+	result = r.db.WithContext(ctx).Create(&newRevision)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newRevision, nil
+}
+
+func (r *RevisionRepository) FindCommentRevisions(ctx context.Context, commentId uint) (*[]model.CommentRevision, error) {
+	var revisions []model.CommentRevision
+	result := r.db.WithContext(ctx).Where("comment_id = ?", commentId).Order("revision asc").Find(&revisions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &revisions, nil
+}
+
+func (r *RevisionRepository) FindCommentRevision(ctx context.Context, id uint, commentId uint) (*model.CommentRevision, error) {
+	var revision model.CommentRevision
+	result := r.db.WithContext(ctx).First(&revision, "id = ? AND comment_id = ?", id, commentId)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("revision not found")
+		}
+		return nil, result.Error
+	}
+	return &revision, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"bbs/internal/model"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SanctionRepository struct {
+	db *gorm.DB
+}
+
+func NewSanctionRepository(db *gorm.DB) ISanctionRepository {
+	return &SanctionRepository{db: db}
+}
+
+func (r *SanctionRepository) Create(ctx context.Context, newSanction model.Sanction) (*model.Sanction, error) {
+	result := r.db.WithContext(ctx).Create(&newSanction)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newSanction, nil
+}
+
+func (r *SanctionRepository) Update(ctx context.Context, updateSanction model.Sanction) (*model.Sanction, error) {
+	result := r.db.WithContext(ctx).Save(&updateSanction)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &updateSanction, nil
+}
+
+func (r *SanctionRepository) FindByUserId(ctx context.Context, userId uint) (*[]model.Sanction, error) {
+	var sanctions []model.Sanction
+	result := r.db.WithContext(ctx).Where("user_id = ?", userId).Order("id desc").Find(&sanctions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &sanctions, nil
+}
+
+func (r *SanctionRepository) FindById(ctx context.Context, id uint, userId uint) (*model.Sanction, error) {
+	var sanction model.Sanction
+	result := r.db.WithContext(ctx).First(&sanction, "id = ? AND user_id = ?", id, userId)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("sanction not found")
+		}
+		return nil, result.Error
+	}
+	return &sanction, nil
+}
+
+// 有効な制裁のうち最も重いもの(追放、期限の遅い停止の順)を返す。なければnilを返す
+func (r *SanctionRepository) FindActive(ctx context.Context, userId uint, now time.Time) (*model.Sanction, error) {
+	var sanctions []model.Sanction
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Find(&sanctions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var active *model.Sanction
+	for i := range sanctions {
+		sanction := &sanctions[i]
+		if active == nil || sanction.ExpiresAt == nil || (active.ExpiresAt != nil && sanction.ExpiresAt.After(*active.ExpiresAt)) {
+			active = sanction
+		}
+	}
+	return active, nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"bbs/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SigningKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewSigningKeyRepository(db *gorm.DB) ISigningKeyRepository {
+	return &SigningKeyRepository{db: db}
+}
+
+func (r *SigningKeyRepository) Create(ctx context.Context, newKey model.SigningKey) (*model.SigningKey, error) {
+	result := r.db.WithContext(ctx).Create(&newKey)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newKey, nil
+}
+
+// 検証に使える鍵を新しい順に返す
+func (r *SigningKeyRepository) FindActive(ctx context.Context, now time.Time) ([]model.SigningKey, error) {
+	var keys []model.SigningKey
+	result := r.db.WithContext(ctx).Where("expires_at > ?", now).Order("created_at desc").Order("id desc").Find(&keys)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return keys, nil
+}
+
+// 期限が切れた鍵で署名されたトークンは検証できないため、鍵ごと削除する
+func (r *SigningKeyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&model.SigningKey{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SubscriptionRepository struct {
+	db *gorm.DB
+}
+
+func NewSubscriptionRepository(db *gorm.DB) ISubscriptionRepository {
+	return &SubscriptionRepository{db: db}
+}
+
+// 購読済みの場合は既読位置のみ更新する
+func (r *SubscriptionRepository) Subscribe(ctx context.Context, userId uint, threadId uint, lastReadCommentId uint) (*model.Subscription, error) {
+	subscription := model.Subscription{UserID: userId, ThreadID: threadId, LastReadCommentID: lastReadCommentId}
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "thread_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_read_comment_id", "updated_at"}),
+	}).Create(&subscription)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var saved model.Subscription
+	if err := r.db.WithContext(ctx).First(&saved, "user_id = ? AND thread_id = ?", userId, threadId).Error; err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+func (r *SubscriptionRepository) Unsubscribe(ctx context.Context, userId uint, threadId uint) error {
+	result := r.db.WithContext(ctx).Where("user_id = ? AND thread_id = ?", userId, threadId).Delete(&model.Subscription{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("subscription not found")
+	}
+	return nil
+}
+
+// 非表示にされたスレッドや閲覧できなくなったスレッドは除き、最終更新日時の新しい順に返す
+func (r *SubscriptionRepository) FindByUserId(ctx context.Context, userId uint, limit int, offset int) (*dto.SubscriptionListOutput, error) {
+	var subscriptionList dto.SubscriptionListOutput
+
+	query := r.db.WithContext(ctx).Model(&model.Subscription{}).
+		Joins("JOIN threads ON threads.id = subscriptions.thread_id").
+		Where("subscriptions.user_id = ?", userId).
+		Where("threads.id IN (?)", visibleThreadIds(r.db, userId).Where("threads.hidden = ?", false))
+	if err := query.Session(&gorm.Session{}).Count(&subscriptionList.Total).Error; err != nil {
+		return nil, err
+	}
+
+	var subscriptions []model.Subscription
+	result := query.Session(&gorm.Session{}).
+		Order("threads.last_activity_at desc").Order("subscriptions.id desc").
+		Limit(limit).Offset(offset * limit).
+		Find(&subscriptions)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	// ページ内のスレッドと未読数はそれぞれ1回の問い合わせでまとめて読み込む
+	threadIds := make([]uint, len(subscriptions))
+	for i, subscription := range subscriptions {
+		threadIds[i] = subscription.ThreadID
+	}
+
+	var threads []model.Thread
+	if err := r.db.WithContext(ctx).Find(&threads, "id IN ?", threadIds).Error; err != nil {
+		return nil, err
+	}
+	threadsById := make(map[uint]model.Thread, len(threads))
+	for _, thread := range threads {
+		threadsById[thread.ID] = thread
+	}
+
+	var unreadCounts []struct {
+		ThreadID    uint
+		UnreadCount int64
+	}
+	result = r.db.WithContext(ctx).Model(&model.Comment{}).
+		Select("comments.thread_id, COUNT(*) AS unread_count").
+		Joins("JOIN subscriptions ON subscriptions.thread_id = comments.thread_id AND subscriptions.user_id = ?", userId).
+		Where("comments.thread_id IN ? AND comments.id > subscriptions.last_read_comment_id AND comments.hidden = ?", threadIds, false).
+		Group("comments.thread_id").
+		Scan(&unreadCounts)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	unreadCountsByThreadId := make(map[uint]int64, len(unreadCounts))
+	for _, count := range unreadCounts {
+		unreadCountsByThreadId[count.ThreadID] = count.UnreadCount
+	}
+
+	subscriptionList.Subscriptions = make([]dto.SubscriptionOutput, len(subscriptions))
+	for i, subscription := range subscriptions {
+		subscriptionList.Subscriptions[i] = dto.SubscriptionOutput{
+			ThreadID:          subscription.ThreadID,
+			LastReadCommentID: subscription.LastReadCommentID,
+			SubscribedAt:      subscription.CreatedAt,
+			Thread:            threadsById[subscription.ThreadID],
+			UnreadCount:       unreadCountsByThreadId[subscription.ThreadID],
+		}
+	}
+
+	return &subscriptionList, nil
+}
+
+// スレッドの最新のコメントのID。コメントがない場合は0
+func (r *SubscriptionRepository) LatestCommentId(ctx context.Context, threadId uint) (uint, error) {
+	var latest uint
+	result := r.db.WithContext(ctx).Model(&model.Comment{}).Where("thread_id = ?", threadId).Select("COALESCE(MAX(id), 0)").Scan(&latest)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return latest, nil
+}
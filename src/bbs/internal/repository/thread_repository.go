@@ -3,9 +3,12 @@ package repository
 import (
 	"bbs/internal/dto"
 	"bbs/internal/model"
+	"context"
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ThreadRepository struct {
@@ -16,50 +19,112 @@ func NewThreadRepository(db *gorm.DB) IThreadRepository {
 	return &ThreadRepository{db: db}
 }
 
-func (r *ThreadRepository) Create(newThread model.Thread) (*model.Thread, error) {
-	result := r.db.Create(&newThread)
+func (r *ThreadRepository) Create(ctx context.Context, newThread model.Thread) (*model.Thread, error) {
+	// DBの既定値(CURRENT_TIMESTAMP)はDBによってタイムゾーンや形式が異なり、比較や並べ替えがずれるためアプリ側で設定する
+	if newThread.LastActivityAt.IsZero() {
+		newThread.LastActivityAt = time.Now()
+	}
+	result := r.db.WithContext(ctx).Omit(clause.Associations).Create(&newThread)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return &newThread, nil
 }
 
-func (r *ThreadRepository) Update(updateThread model.Thread) (*model.Thread, error) {
-	result := r.db.Save(&updateThread)
+func (r *ThreadRepository) Update(ctx context.Context, updateThread model.Thread) (*model.Thread, error) {
+	result := r.db.WithContext(ctx).Omit(clause.Associations).Save(&updateThread)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return &updateThread, nil
 }
 
-func (r *ThreadRepository) Delete(threadId uint, userId uint) error {
-	deleteThread, err := r.FindById(threadId)
+func (r *ThreadRepository) Delete(ctx context.Context, threadId uint, userId uint) error {
+	deleteThread, err := r.FindById(ctx, threadId)
 	if err != nil {
 		return err
 	}
-	result := r.db.Delete(&deleteThread)
+	result := r.db.WithContext(ctx).Delete(&deleteThread)
 	if result.Error != nil {
 		return result.Error
 	}
 	return nil
 }
 
-func (r *ThreadRepository) FindAll(limit int, offset int) (*dto.ThreadListOutput, error) {
+func (r *ThreadRepository) FindAll(ctx context.Context, limit int, offset int, userId uint) (*dto.ThreadListOutput, error) {
+	var threadList dto.ThreadListOutput
+
+	// 全レコード数(非表示のスレッドと閲覧できないスレッドは除く)
+	r.db.WithContext(ctx).Model(&model.Thread{}).Scopes(visibleTo(userId)).Where("hidden = ?", false).Count(&threadList.Total)
+
+	result := r.db.WithContext(ctx).Scopes(visibleTo(userId)).Where("hidden = ?", false).Limit(limit).Offset(offset*limit).Order("pinned desc").Order("ID desc").Preload("Comments", "hidden = ?", false).Find(&threadList.Threads)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &threadList, nil
+}
+
+func (r *ThreadRepository) FindById(ctx context.Context, threadId uint) (*model.Thread, error) {
+	var thread model.Thread
+	result := r.db.WithContext(ctx).Preload("Attachments").First(&thread, "id = ?", threadId)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("thread not found")
+		}
+		return nil, result.Error
+	}
+	return &thread, nil
+}
+
+// ロック中は他のトランザクションから削除・更新されない
+func (r *ThreadRepository) FindByIdForUpdate(ctx context.Context, threadId uint) (*model.Thread, error) {
+	var thread model.Thread
+	result := r.db.WithContext(ctx).Scopes(forUpdate(false)).First(&thread, "id = ?", threadId)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("thread not found")
+		}
+		return nil, result.Error
+	}
+	return &thread, nil
+}
+
+func (r *ThreadRepository) FindByUserId(ctx context.Context, userId uint, limit int, offset int) (*dto.ThreadListOutput, error) {
+	var threadList dto.ThreadListOutput
+
+	query := r.db.WithContext(ctx).Model(&model.Thread{}).Where("user_id = ? AND hidden = ?", userId, false)
+	if err := query.Session(&gorm.Session{}).Count(&threadList.Total).Error; err != nil {
+		return nil, err
+	}
+
+	result := query.Session(&gorm.Session{}).Limit(limit).Offset(offset*limit).Order("ID desc").Preload("Comments", "hidden = ?", false).Find(&threadList.Threads)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &threadList, nil
+}
+
+// コメントしたスレッドを最終更新日時の新しい順に返す。招待を取り消されたなど閲覧できなくなったスレッドは除く
+func (r *ThreadRepository) FindParticipating(ctx context.Context, userId uint, limit int, offset int) (*dto.ThreadListOutput, error) {
 	var threadList dto.ThreadListOutput
 
-	// 全レコード数
-	r.db.Model(&model.Thread{}).Count(&threadList.Total)
+	commented := r.db.WithContext(ctx).Model(&model.Comment{}).Select("thread_id").Where("user_id = ?", userId)
+	query := r.db.WithContext(ctx).Model(&model.Thread{}).Scopes(visibleTo(userId)).Where("threads.id IN (?) AND threads.hidden = ?", commented, false)
+	if err := query.Session(&gorm.Session{}).Count(&threadList.Total).Error; err != nil {
+		return nil, err
+	}
 
-	result := r.db.Limit(limit).Offset(offset * limit).Order("ID desc").Preload("Comments").Find(&threadList.Threads)
+	result := query.Session(&gorm.Session{}).Limit(limit).Offset(offset*limit).Order("last_activity_at desc").Order("ID desc").Preload("Comments", "hidden = ?", false).Find(&threadList.Threads)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 	return &threadList, nil
 }
 
-func (r *ThreadRepository) FindById(threadId uint) (*model.Thread, error) {
+// 閲覧できないスレッドは存在しないものとして扱う
+func (r *ThreadRepository) FindVisibleById(ctx context.Context, threadId uint, userId uint) (*model.Thread, error) {
 	var thread model.Thread
-	result := r.db.First(&thread, "id = ?", threadId)
+	result := r.db.WithContext(ctx).Scopes(visibleTo(userId)).Preload("Attachments").Preload("Members").First(&thread, "threads.id = ?", threadId)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("thread not found")
@@ -68,3 +133,38 @@ func (r *ThreadRepository) FindById(threadId uint) (*model.Thread, error) {
 	}
 	return &thread, nil
 }
+
+func (r *ThreadRepository) ReplaceMembers(ctx context.Context, threadId uint, userIds []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("thread_id = ?", threadId).Delete(&model.ThreadMember{}).Error; err != nil {
+			return err
+		}
+		if len(userIds) == 0 {
+			return nil
+		}
+		members := make([]model.ThreadMember, len(userIds))
+		for i, userId := range userIds {
+			members[i] = model.ThreadMember{ThreadID: threadId, UserID: userId}
+		}
+		return tx.Create(&members).Error
+	})
+}
+
+func (r *ThreadRepository) UpdateLastActivity(ctx context.Context, threadId uint, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&model.Thread{}).Where("id = ?", threadId).Update("last_activity_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (r *ThreadRepository) ArchiveInactive(ctx context.Context, before time.Time) (int64, error) {
+	// ピン留めされたスレッドは放置されていてもアーカイブしない
+	result := r.db.WithContext(ctx).Model(&model.Thread{}).
+		Where("archived = ? AND pinned = ? AND last_activity_at < ?", false, false, before).
+		Update("archived", true)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"bbs/internal/model"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TwoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) ITwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// 登録をやり直す場合は秘密鍵を置き換える
+func (r *TwoFactorRepository) UpdateSecret(ctx context.Context, userId uint, secret string) error {
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ? AND totp_enabled = ?", userId, false).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("two-factor authentication is already enabled")
+	}
+	return nil
+}
+
+// 有効化と同時にリカバリーコードを発行する
+func (r *TwoFactorRepository) Enable(ctx context.Context, userId uint, step int64, codeHashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.User{}).Where("id = ? AND totp_enabled = ?", userId, false).
+			Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("two-factor authentication is already enabled")
+		}
+		return replaceRecoveryCodes(tx, userId, codeHashes)
+	})
+}
+
+func (r *TwoFactorRepository) Disable(ctx context.Context, userId uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.User{}).Where("id = ?", userId).
+			Updates(map[string]interface{}{"totp_secret": "", "totp_enabled": false, "totp_last_step": 0, "totp_failed_attempts": 0, "totp_locked_until": nil})
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.Where("user_id = ?", userId).Delete(&model.RecoveryCode{}).Error
+	})
+}
+
+// 記録済みのステップより新しい場合だけ更新する。同時に同じコードが送られても一方しか成功しない
+func (r *TwoFactorRepository) UseStep(ctx context.Context, userId uint, step int64) error {
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ? AND totp_last_step < ?", userId, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("code already used")
+	}
+	return nil
+}
+
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userId uint, codeHash string, now time.Time) error {
+	result := r.db.WithContext(ctx).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("recovery code not found")
+	}
+	return nil
+}
+
+func (r *TwoFactorRepository) CountUnusedRecoveryCodes(ctx context.Context, userId uint) (int64, error) {
+	var count int64
+	result := r.db.WithContext(ctx).Model(&model.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userId).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+// 失敗した回数を加算し、上限に達した場合はロックして回数を数え直す
+func (r *TwoFactorRepository) RecordFailure(ctx context.Context, userId uint, maxAttempts int, lockedUntil time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Where("id = ?", userId).
+			Update("totp_failed_attempts", gorm.Expr("totp_failed_attempts + 1")).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.User{}).Where("id = ? AND totp_failed_attempts >= ?", userId, maxAttempts).
+			Updates(map[string]interface{}{"totp_failed_attempts": 0, "totp_locked_until": lockedUntil}).Error
+	})
+}
+
+func (r *TwoFactorRepository) ResetFailures(ctx context.Context, userId uint) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userId).
+		Updates(map[string]interface{}{"totp_failed_attempts": 0, "totp_locked_until": nil}).Error
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userId uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userId).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	codes := make([]model.RecoveryCode, len(codeHashes))
+	for i, codeHash := range codeHashes {
+		codes[i] = model.RecoveryCode{UserID: userId, CodeHash: codeHash}
+	}
+	return tx.Create(&codes).Error
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// 1つのトランザクションの中で使うリポジトリ
+type Repositories struct {
+	Threads       IThreadRepository
+	Comments      ICommentRepository
+	Revisions     IRevisionRepository
+	Subscriptions ISubscriptionRepository
+	Polls         IPollRepository
+	Attachments   IAttachmentRepository
+	Reports       IReportRepository
+	Webhooks      IWebhookRepository
+	Jobs          IJobRepository
+}
+
+type txKey struct{}
+
+type UnitOfWork struct {
+	db *gorm.DB
+}
+
+func NewUnitOfWork(db *gorm.DB) IUnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+// fnの中でreposを使った操作を1つのトランザクションで実行し、fnがエラーを返した場合はロールバックする。
+// トランザクションはfnに渡すctxで引き継ぎ、fnの中から呼ばれた場合は同じトランザクションを使う
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx, newRepositories(tx))
+	}
+
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx), newRepositories(tx))
+	})
+}
+
+func newRepositories(tx *gorm.DB) Repositories {
+	return Repositories{
+		Threads:       NewThreadRepository(tx),
+		Comments:      NewCommentRepository(tx),
+		Revisions:     NewRevisionRepository(tx),
+		Subscriptions: NewSubscriptionRepository(tx),
+		Polls:         NewPollRepository(tx),
+		Attachments:   NewAttachmentRepository(tx),
+		Reports:       NewReportRepository(tx),
+		Webhooks:      NewWebhookRepository(tx),
+		Jobs:          NewJobRepository(tx),
+	}
+}
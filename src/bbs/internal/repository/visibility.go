@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"bbs/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// userIdのユーザーが閲覧できるスレッドに絞り込む。userIdが0の場合は未ログインとして扱う
+func visibleTo(userId uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if userId == 0 {
+			return db.Where("threads.visibility = ?", model.VisibilityPublic)
+		}
+		return db.Where(
+			db.Session(&gorm.Session{NewDB: true}).
+				Where("threads.visibility IN ?", []string{model.VisibilityPublic, model.VisibilityMembers}).
+				Or("threads.user_id = ?", userId).
+				Or("threads.id IN (?)", db.Session(&gorm.Session{NewDB: true}).Model(&model.ThreadMember{}).Select("thread_id").Where("user_id = ?", userId)).
+				Or("EXISTS (?)", moderatorSubquery(db, userId)),
+		)
+	}
+}
+
+// 匿名の投稿を投稿者で絞り込むと投稿者がわかってしまうため、本人とモデレーター以外には匿名の投稿を除く
+func anonymousHiddenFrom(userId uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if userId == 0 {
+			return db.Where("comments.anonymous = ?", false)
+		}
+		return db.Where(
+			db.Session(&gorm.Session{NewDB: true}).
+				Where("comments.anonymous = ?", false).
+				Or("comments.user_id = ?", userId).
+				Or("EXISTS (?)", moderatorSubquery(db, userId)),
+		)
+	}
+}
+
+func moderatorSubquery(db *gorm.DB, userId uint) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Model(&model.User{}).Select("1").Where("users.id = ? AND users.role IN ?", userId, []string{model.RoleModerator, model.RoleAdmin})
+}
+
+// userIdのユーザーが閲覧できるスレッドのIDのサブクエリ
+func visibleThreadIds(db *gorm.DB, userId uint) *gorm.DB {
+	return db.Model(&model.Thread{}).Select("threads.id").Scopes(visibleTo(userId))
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) IWebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, newWebhook model.Webhook) (*model.Webhook, error) {
+	result := r.db.WithContext(ctx).Create(&newWebhook)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newWebhook, nil
+}
+
+func (r *WebhookRepository) FindAll(ctx context.Context) (*[]model.Webhook, error) {
+	var webhooks []model.Webhook
+	result := r.db.WithContext(ctx).Order("id").Find(&webhooks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &webhooks, nil
+}
+
+func (r *WebhookRepository) FindById(ctx context.Context, id uint) (*model.Webhook, error) {
+	var webhook model.Webhook
+	result := r.db.WithContext(ctx).First(&webhook, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("webhook not found")
+		}
+		return nil, result.Error
+	}
+	return &webhook, nil
+}
+
+// 配信記録も合わせて削除する
+func (r *WebhookRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&model.Webhook{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("webhook not found")
+		}
+		return tx.Where("webhook_id = ?", id).Delete(&model.WebhookDelivery{}).Error
+	})
+}
+
+// 全体の通知先と、指定したスレッドの通知先。イベントの絞り込みは呼び出し側で行う
+func (r *WebhookRepository) FindActiveByThreadId(ctx context.Context, threadId uint) (*[]model.Webhook, error) {
+	var webhooks []model.Webhook
+	result := r.db.WithContext(ctx).Where("active = ? AND (thread_id IS NULL OR thread_id = ?)", true, threadId).Find(&webhooks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &webhooks, nil
+}
+
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, newDelivery model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	result := r.db.WithContext(ctx).Create(&newDelivery)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &newDelivery, nil
+}
+
+func (r *WebhookRepository) CreateDeliveries(ctx context.Context, newDeliveries []model.WebhookDelivery) error {
+	if len(newDeliveries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&newDeliveries).Error
+}
+
+func (r *WebhookRepository) FindDeliveries(ctx context.Context, webhookId uint, limit int, offset int) (*dto.WebhookDeliveryListOutput, error) {
+	var output dto.WebhookDeliveryListOutput
+
+	query := r.db.WithContext(ctx).Model(&model.WebhookDelivery{}).Where("webhook_id = ?", webhookId)
+	if result := query.Count(&output.Total); result.Error != nil {
+		return nil, result.Error
+	}
+
+	result := query.Order("id desc").Limit(limit).Offset(offset * limit).Find(&output.Deliveries)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &output, nil
+}
+
+func (r *WebhookRepository) FindDelivery(ctx context.Context, id uint, webhookId uint) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	result := r.db.WithContext(ctx).First(&delivery, "id = ? AND webhook_id = ?", id, webhookId)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("webhook delivery not found")
+		}
+		return nil, result.Error
+	}
+	return &delivery, nil
+}
+
+// 送信予定時刻を過ぎた未完了の配信。古いものから返す
+func (r *WebhookRepository) FindDueDeliveries(ctx context.Context, now time.Time, limit int) (*[]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	result := r.db.WithContext(ctx).Preload("Webhook").
+		Where("status = ? AND next_attempt_at <= ?", model.WebhookDeliveryStatusPending, now).
+		Order("next_attempt_at").Limit(limit).
+		Find(&deliveries)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &deliveries, nil
+}
+
+// 送信中に他の配信処理が同じ配信を取得しないよう、送信予定時刻を先に延ばしておく。
+// 他の配信処理が先に取得していた場合はfalseを返す
+func (r *WebhookRepository) ClaimDelivery(ctx context.Context, id uint, now time.Time, leaseUntil time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", id, model.WebhookDeliveryStatusPending, now).
+		Update("next_attempt_at", leaseUntil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery model.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Model(&delivery).Select("Status", "Attempts", "NextAttemptAt", "ResponseStatus", "ResponseBody", "Error", "DeliveredAt").Updates(&delivery).Error
+}
@@ -0,0 +1,28 @@
+package route
+
+import (
+	"bbs/internal/controller"
+	"bbs/internal/middleware"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"bbs/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetAttachmentRoute(r *gin.Engine, db *gorm.DB, attachmentService service.IAttachmentService, tokenService service.ITokenService) {
+	authRepository := repository.NewAuthRepository(db)
+	authService := service.NewAuthService(authRepository, repository.NewSanctionRepository(db), repository.NewAPITokenRepository(db), tokenService)
+
+	attachmentRouter := r.Group("/attachments", middleware.OptionalAuthMiddleware(authService))
+	// 添付ファイルはスレッドとコメントのどちらにも使うため、いずれかの更新スコープがあればアップロードできる
+	attachmentRouterWithAuth := r.Group("/attachments", middleware.AuthMiddleware(authService, model.ScopeWriteThreads, model.ScopeWriteComments))
+
+	attachmentController := controller.NewAttachmentController(attachmentService)
+
+	attachmentRouter.GET("/:attachmentId", attachmentController.Download)
+	attachmentRouter.GET("/:attachmentId/thumbnail", attachmentController.Thumbnail)
+	attachmentRouterWithAuth.POST("", attachmentController.Upload)
+	attachmentRouterWithAuth.DELETE("/:attachmentId", attachmentController.Delete)
+}
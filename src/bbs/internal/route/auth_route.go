@@ -2,20 +2,65 @@ package route
 
 import (
 	"bbs/internal/controller"
+	"bbs/internal/middleware"
+	"bbs/internal/oidc"
 	"bbs/internal/repository"
 	"bbs/internal/service"
+	"log"
+	"os"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func SetAuthRoute(r *gin.Engine, db *gorm.DB) {
+const defaultOIDCConfig = "configs/oidc.json"
+
+func SetAuthRoute(r *gin.Engine, db *gorm.DB, tokenService service.ITokenService) {
 	authRouter := r.Group("/auth")
 
 	authRepository := repository.NewAuthRepository(db)
-	authService := service.NewAuthService(authRepository)
+	sanctionRepository := repository.NewSanctionRepository(db)
+	apiTokenRepository := repository.NewAPITokenRepository(db)
+	authService := service.NewAuthService(authRepository, sanctionRepository, apiTokenRepository, tokenService)
 	authController := controller.NewAuthContorller(authService)
 
+	oidcService := service.NewOIDCService(oidcRegistry(), repository.NewIdentityRepository(db), authRepository, sanctionRepository, tokenService)
+	oidcController := controller.NewOIDCController(oidcService)
+
+	twoFactorService := service.NewTwoFactorService(repository.NewTwoFactorRepository(db), authRepository, sanctionRepository, tokenService)
+	twoFactorController := controller.NewTwoFactorController(twoFactorService)
+	twoFactorRouterWithAuth := r.Group("/auth/2fa", middleware.AuthMiddleware(authService))
+
+	apiTokenController := controller.NewAPITokenController(service.NewAPITokenService(apiTokenRepository))
+	apiTokenRouterWithAuth := r.Group("/me/tokens", middleware.AuthMiddleware(authService))
+
 	authRouter.POST("/signup", authController.Signup)
 	authRouter.POST("/login", authController.Login)
+	authRouter.POST("/login/2fa", twoFactorController.Login)
+	authRouter.GET("/oidc/:provider/authorize", oidcController.Authorize)
+	authRouter.POST("/oidc/:provider/callback", oidcController.Callback)
+	twoFactorRouterWithAuth.GET("", twoFactorController.FindStatus)
+	twoFactorRouterWithAuth.POST("/enroll", twoFactorController.Enroll)
+	twoFactorRouterWithAuth.POST("/enable", twoFactorController.Enable)
+	twoFactorRouterWithAuth.POST("/disable", twoFactorController.Disable)
+	apiTokenRouterWithAuth.POST("", apiTokenController.Create)
+	apiTokenRouterWithAuth.GET("", apiTokenController.FindMine)
+	apiTokenRouterWithAuth.DELETE("/:tokenId", apiTokenController.Revoke)
+	// 他のサービスがトークンを検証できるよう公開鍵を公開する
+	r.GET("/.well-known/jwks.json", authController.JWKS)
+}
+
+// OIDC_CONFIGが未設定の場合はconfigs/oidc.jsonを読み込む。読み込めない場合はOIDCでのログインを無効にする
+func oidcRegistry() *oidc.Registry {
+	path := os.Getenv("OIDC_CONFIG")
+	if path == "" {
+		path = defaultOIDCConfig
+	}
+
+	config, err := oidc.LoadConfig(path)
+	if err != nil {
+		log.Printf("failed to load oidc config %s: %v", path, err)
+		config = &oidc.Config{}
+	}
+	return oidc.NewRegistry(config)
 }
@@ -0,0 +1,28 @@
+package route
+
+import (
+	"bbs/internal/controller"
+	"bbs/internal/middleware"
+	"bbs/internal/repository"
+	"bbs/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetBookmarkRoute(r *gin.Engine, db *gorm.DB, threadService service.IThreadService, commentService service.ICommentService, tokenService service.ITokenService) {
+	authRepository := repository.NewAuthRepository(db)
+	authService := service.NewAuthService(authRepository, repository.NewSanctionRepository(db), repository.NewAPITokenRepository(db), tokenService)
+
+	bookmarkService := service.NewBookmarkService(repository.NewBookmarkRepository(db), threadService, commentService)
+	bookmarkController := controller.NewBookmarkController(bookmarkService)
+
+	bookmarkRouterWithAuth := r.Group("/threads/:threadId", middleware.AuthMiddleware(authService))
+	meRouter := r.Group("/me", middleware.AuthMiddleware(authService))
+
+	bookmarkRouterWithAuth.PUT("/bookmark", bookmarkController.Save)
+	bookmarkRouterWithAuth.DELETE("/bookmark", bookmarkController.Delete)
+	bookmarkRouterWithAuth.PUT("/comments/:commentId/bookmark", bookmarkController.Save)
+	bookmarkRouterWithAuth.DELETE("/comments/:commentId/bookmark", bookmarkController.Delete)
+	meRouter.GET("/bookmarks", bookmarkController.FindMine)
+}
@@ -3,6 +3,7 @@ package route
 import (
 	"bbs/internal/controller"
 	"bbs/internal/middleware"
+	"bbs/internal/model"
 	"bbs/internal/repository"
 	"bbs/internal/service"
 
@@ -10,20 +11,25 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetCommentRoute(r *gin.Engine, db *gorm.DB) {
+func SetCommentRoute(r *gin.Engine, db *gorm.DB, commentService service.ICommentService, tokenService service.ITokenService) {
 	authRepository := repository.NewAuthRepository(db)
-	authService := service.NewAuthService(authRepository)
+	authService := service.NewAuthService(authRepository, repository.NewSanctionRepository(db), repository.NewAPITokenRepository(db), tokenService)
 
-	threadRepository := repository.NewThreadRepository(db)
-
-	commentRepository := repository.NewCommentRepository(db)
-	commentService := service.NewCommentService(commentRepository, threadRepository)
 	commentController := controller.NewCommentController(commentService)
 
-	commentRouterWithAuth := r.Group("/threads/:threadId/comments", middleware.AuthMiddleware(authService))
+	commentRouter := r.Group("/threads/:threadId/comments", middleware.OptionalAuthMiddleware(authService))
+	commentRouterWithAuth := r.Group("/threads/:threadId/comments", middleware.AuthMiddleware(authService, model.ScopeWriteComments))
+	commentRouterForModerator := r.Group("/threads/:threadId/comments", middleware.AuthMiddleware(authService), middleware.ModeratorMiddleware())
+	userCommentRouter := r.Group("/users/:userId/comments", middleware.OptionalAuthMiddleware(authService))
+	meRouter := r.Group("/me", middleware.AuthMiddleware(authService))
 
-	commentRouterWithAuth.GET("", commentController.FindByThreadId)
-	commentRouterWithAuth.GET("/:commentId", commentController.FindById)
+	commentRouter.GET("", commentController.FindByThreadId)
+	commentRouter.GET("/:commentId", commentController.FindById)
+	commentRouter.GET("/:commentId/revisions", commentController.FindRevisions)
 	commentRouterWithAuth.POST("", commentController.Create)
 	commentRouterWithAuth.PUT("/:commentId", commentController.Update)
+	commentRouterWithAuth.DELETE("/:commentId", commentController.Delete)
+	commentRouterForModerator.POST("/:commentId/revisions/:revisionId/revert", commentController.RevertRevision)
+	userCommentRouter.GET("", commentController.FindByUserId)
+	meRouter.GET("/comments", commentController.FindMine)
 }
@@ -0,0 +1,23 @@
+package route
+
+import (
+	"bbs/internal/controller"
+	"bbs/internal/middleware"
+	"bbs/internal/repository"
+	"bbs/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetJobRoute(r *gin.Engine, db *gorm.DB, jobService service.IJobService, tokenService service.ITokenService) {
+	authRepository := repository.NewAuthRepository(db)
+	authService := service.NewAuthService(authRepository, repository.NewSanctionRepository(db), repository.NewAPITokenRepository(db), tokenService)
+
+	jobController := controller.NewJobController(jobService)
+
+	adminRouter := r.Group("/admin/jobs", middleware.AuthMiddleware(authService), middleware.AdminMiddleware())
+
+	adminRouter.GET("", jobController.FindAll)
+	adminRouter.POST("/:jobId/retry", jobController.Retry)
+}
@@ -0,0 +1,24 @@
+package route
+
+import (
+	"bbs/internal/controller"
+	"bbs/internal/middleware"
+	"bbs/internal/repository"
+	"bbs/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetPollRoute(r *gin.Engine, db *gorm.DB, threadService service.IThreadService, tokenService service.ITokenService) {
+	authRepository := repository.NewAuthRepository(db)
+	authService := service.NewAuthService(authRepository, repository.NewSanctionRepository(db), repository.NewAPITokenRepository(db), tokenService)
+
+	pollService := service.NewPollService(repository.NewPollRepository(db), threadService)
+	pollController := controller.NewPollController(pollService)
+
+	pollRouterWithAuth := r.Group("/threads/:threadId/poll", middleware.AuthMiddleware(authService))
+
+	pollRouterWithAuth.POST("/votes", pollController.Vote)
+	pollRouterWithAuth.DELETE("/votes", pollController.Unvote)
+}
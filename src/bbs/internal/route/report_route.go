@@ -0,0 +1,28 @@
+package route
+
+import (
+	"bbs/internal/controller"
+	"bbs/internal/middleware"
+	"bbs/internal/repository"
+	"bbs/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetReportRoute(r *gin.Engine, db *gorm.DB, threadService service.IThreadService, commentService service.ICommentService, tokenService service.ITokenService) {
+	authRepository := repository.NewAuthRepository(db)
+	authService := service.NewAuthService(authRepository, repository.NewSanctionRepository(db), repository.NewAPITokenRepository(db), tokenService)
+
+	reportService := service.NewReportService(repository.NewReportRepository(db), threadService, commentService)
+	reportController := controller.NewReportController(reportService)
+
+	reportRouterWithAuth := r.Group("/threads/:threadId", middleware.AuthMiddleware(authService))
+	moderationRouter := r.Group("/moderation/reports", middleware.AuthMiddleware(authService), middleware.ModeratorMiddleware())
+
+	reportRouterWithAuth.POST("/reports", reportController.Create)
+	reportRouterWithAuth.POST("/comments/:commentId/reports", reportController.Create)
+	moderationRouter.GET("", reportController.FindQueue)
+	moderationRouter.PUT("/threads/:threadId", reportController.Resolve)
+	moderationRouter.PUT("/threads/:threadId/comments/:commentId", reportController.Resolve)
+}
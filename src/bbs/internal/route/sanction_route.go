@@ -0,0 +1,26 @@
+package route
+
+import (
+	"bbs/internal/controller"
+	"bbs/internal/middleware"
+	"bbs/internal/repository"
+	"bbs/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetSanctionRoute(r *gin.Engine, db *gorm.DB, tokenService service.ITokenService) {
+	authRepository := repository.NewAuthRepository(db)
+	sanctionRepository := repository.NewSanctionRepository(db)
+	authService := service.NewAuthService(authRepository, sanctionRepository, repository.NewAPITokenRepository(db), tokenService)
+
+	sanctionService := service.NewSanctionService(sanctionRepository, authRepository)
+	sanctionController := controller.NewSanctionController(sanctionService)
+
+	adminRouter := r.Group("/admin/users/:userId/sanctions", middleware.AuthMiddleware(authService), middleware.AdminMiddleware())
+
+	adminRouter.GET("", sanctionController.FindByUserId)
+	adminRouter.POST("", sanctionController.Create)
+	adminRouter.DELETE("/:sanctionId", sanctionController.Revoke)
+}
@@ -0,0 +1,26 @@
+package route
+
+import (
+	"bbs/internal/controller"
+	"bbs/internal/middleware"
+	"bbs/internal/repository"
+	"bbs/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetSubscriptionRoute(r *gin.Engine, db *gorm.DB, threadService service.IThreadService, tokenService service.ITokenService) {
+	authRepository := repository.NewAuthRepository(db)
+	authService := service.NewAuthService(authRepository, repository.NewSanctionRepository(db), repository.NewAPITokenRepository(db), tokenService)
+
+	subscriptionService := service.NewSubscriptionService(repository.NewSubscriptionRepository(db), threadService)
+	subscriptionController := controller.NewSubscriptionController(subscriptionService)
+
+	subscriptionRouterWithAuth := r.Group("/threads/:threadId/subscription", middleware.AuthMiddleware(authService))
+	meRouter := r.Group("/me", middleware.AuthMiddleware(authService))
+
+	subscriptionRouterWithAuth.POST("", subscriptionController.Subscribe)
+	subscriptionRouterWithAuth.DELETE("", subscriptionController.Unsubscribe)
+	meRouter.GET("/subscriptions", subscriptionController.FindMine)
+}
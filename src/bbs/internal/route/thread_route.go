@@ -3,6 +3,7 @@ package route
 import (
 	"bbs/internal/controller"
 	"bbs/internal/middleware"
+	"bbs/internal/model"
 	"bbs/internal/repository"
 	"bbs/internal/service"
 
@@ -10,21 +11,26 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetThreadRoute(r *gin.Engine, db *gorm.DB) {
-	threadRouter := r.Group("/threads")
-
+func SetThreadRoute(r *gin.Engine, db *gorm.DB, threadService service.IThreadService, tokenService service.ITokenService) {
 	authRepository := repository.NewAuthRepository(db)
-	authService := service.NewAuthService(authRepository)
+	authService := service.NewAuthService(authRepository, repository.NewSanctionRepository(db), repository.NewAPITokenRepository(db), tokenService)
 
-	threadRouterWithAuth := r.Group("/threads", middleware.AuthMiddleware(authService))
+	threadRouter := r.Group("/threads", middleware.OptionalAuthMiddleware(authService))
+	threadRouterWithAuth := r.Group("/threads", middleware.AuthMiddleware(authService, model.ScopeWriteThreads))
+	threadRouterForModerator := r.Group("/threads", middleware.AuthMiddleware(authService), middleware.ModeratorMiddleware())
+	meRouter := r.Group("/me", middleware.AuthMiddleware(authService))
 
-	threadRepository := repository.NewThreadRepository(db)
-	threadService := service.NewThreadService(threadRepository)
 	threadController := controller.NewThreadController(threadService)
 
 	threadRouter.GET("", threadController.FindAll)
 	threadRouter.GET("/:threadId", threadController.FindById)
+	threadRouter.GET("/:threadId/revisions", threadController.FindRevisions)
 	threadRouterWithAuth.POST("", threadController.Create)
 	threadRouterWithAuth.PUT("/:threadId", threadController.Update)
+	threadRouterWithAuth.PUT("/:threadId/visibility", threadController.UpdateVisibility)
 	threadRouterWithAuth.DELETE("/:threadId", threadController.Delete)
+	threadRouterForModerator.PUT("/:threadId/state", threadController.UpdateState)
+	threadRouterForModerator.POST("/:threadId/revisions/:revisionId/revert", threadController.RevertRevision)
+	meRouter.GET("/threads", threadController.FindMine)
+	meRouter.GET("/participating", threadController.FindParticipating)
 }
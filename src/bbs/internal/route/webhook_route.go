@@ -0,0 +1,26 @@
+package route
+
+import (
+	"bbs/internal/controller"
+	"bbs/internal/middleware"
+	"bbs/internal/repository"
+	"bbs/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetWebhookRoute(r *gin.Engine, db *gorm.DB, webhookService service.IWebhookService, tokenService service.ITokenService) {
+	authRepository := repository.NewAuthRepository(db)
+	authService := service.NewAuthService(authRepository, repository.NewSanctionRepository(db), repository.NewAPITokenRepository(db), tokenService)
+
+	webhookController := controller.NewWebhookController(webhookService)
+
+	adminRouter := r.Group("/admin/webhooks", middleware.AuthMiddleware(authService), middleware.AdminMiddleware())
+
+	adminRouter.GET("", webhookController.FindAll)
+	adminRouter.POST("", webhookController.Create)
+	adminRouter.DELETE("/:webhookId", webhookController.Delete)
+	adminRouter.GET("/:webhookId/deliveries", webhookController.FindDeliveries)
+	adminRouter.POST("/:webhookId/deliveries/:deliveryId/redeliver", webhookController.Redeliver)
+}
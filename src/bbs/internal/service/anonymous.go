@@ -0,0 +1,77 @@
+package service
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"os"
+	"strconv"
+	"time"
+)
+
+const posterIDLength = 8
+
+// 匿名の投稿に表示する投稿者ID。同じユーザーでも同じスレッド・同じ日でなければ別のIDになる
+func posterID(userId uint, threadId uint, postedAt time.Time) string {
+	// 鍵が空だと誰でもIDを計算できてしまうため、起動時にinfra.Initで設定を必須にしている
+	secret := os.Getenv("ANONYMOUS_ID_SECRET")
+	if secret == "" {
+		panic("ANONYMOUS_ID_SECRET is not set")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatUint(uint64(userId), 10) + ":" + strconv.FormatUint(uint64(threadId), 10) + ":" + postedAt.Local().Format(time.DateOnly)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:posterIDLength]
+}
+
+// 匿名の投稿には投稿者IDを設定し、モデレーター以外には実際のユーザーを伏せる。viewerは未ログインの場合nil
+func AnonymizeThread(thread *model.Thread, viewer *model.User) {
+	if thread.Anonymous {
+		thread.PosterID = posterID(thread.UserID, thread.ID, thread.CreatedAt)
+		if !canSeeAuthor(viewer) {
+			thread.UserID = 0
+			hideAttachmentOwners(thread.Attachments)
+		}
+	}
+	AnonymizeComments(thread.Comments, viewer)
+}
+
+func AnonymizeComments(comments []model.Comment, viewer *model.User) {
+	for i := range comments {
+		AnonymizeComment(&comments[i], viewer)
+	}
+}
+
+func AnonymizeComment(comment *model.Comment, viewer *model.User) {
+	if !comment.Anonymous {
+		return
+	}
+	comment.PosterID = posterID(comment.UserID, comment.ThreadID, comment.CreatedAt)
+	if !canSeeAuthor(viewer) {
+		comment.UserID = 0
+		hideAttachmentOwners(comment.Attachments)
+	}
+}
+
+func AnonymizeRevisions(revisions []dto.RevisionOutput, viewer *model.User) {
+	if canSeeAuthor(viewer) {
+		return
+	}
+	for i := range revisions {
+		if revisions[i].Anonymous {
+			revisions[i].EditedBy = 0
+		}
+	}
+}
+
+func canSeeAuthor(viewer *model.User) bool {
+	return viewer != nil && viewer.IsModerator()
+}
+
+func hideAttachmentOwners(attachments []model.Attachment) {
+	for i := range attachments {
+		attachments[i].UserID = 0
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+const (
+	// JWTと区別できるよう個人アクセストークンには接頭辞を付ける
+	apiTokenPrefix = "bbs_pat_"
+	// 一覧で見分けるために保存する先頭部分の長さ
+	apiTokenDisplayLength = len(apiTokenPrefix) + 4
+	// リクエストごとに書き込まないよう、最終使用日時はこの間隔でだけ更新する
+	apiTokenLastUsedInterval = time.Minute
+)
+
+type APITokenService struct {
+	repository repository.IAPITokenRepository
+}
+
+func NewAPITokenService(repository repository.IAPITokenRepository) IAPITokenService {
+	return &APITokenService{repository: repository}
+}
+
+func (s *APITokenService) Create(ctx context.Context, createAPITokenInput dto.CreateAPITokenInput, userId uint) (*dto.CreateAPITokenOutput, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	tokenString := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	newToken := model.APIToken{
+		UserID:    userId,
+		Name:      createAPITokenInput.Name,
+		Prefix:    tokenString[:apiTokenDisplayLength],
+		TokenHash: hashAPIToken(tokenString),
+		Scopes:    createAPITokenInput.Scopes,
+	}
+	if createAPITokenInput.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, createAPITokenInput.ExpiresInDays)
+		newToken.ExpiresAt = &expiresAt
+	}
+
+	token, err := s.repository.Create(ctx, newToken)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.CreateAPITokenOutput{APIToken: token, Token: tokenString}, nil
+}
+
+func (s *APITokenService) FindByUserId(ctx context.Context, userId uint) (*[]model.APIToken, error) {
+	return s.repository.FindByUserId(ctx, userId)
+}
+
+func (s *APITokenService) Revoke(ctx context.Context, id uint, userId uint) error {
+	return s.repository.Revoke(ctx, id, userId, time.Now())
+}
+
+func isAPIToken(tokenString string) bool {
+	return strings.HasPrefix(tokenString, apiTokenPrefix)
+}
+
+// トークンは十分に長いランダムな値のためハッシュにはSHA-256を使う
+func hashAPIToken(tokenString string) string {
+	sum := sha256.Sum256([]byte(tokenString))
+	return hex.EncodeToString(sum[:])
+}
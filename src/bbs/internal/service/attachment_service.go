@@ -0,0 +1,256 @@
+package service
+
+import (
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"bbs/internal/storage"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const defaultAttachmentMaxSize = 10 << 20
+
+var defaultAttachmentAllowedTypes = []string{
+	"image/jpeg",
+	"image/png",
+	"image/gif",
+	"image/webp",
+	"text/plain",
+	"application/pdf",
+	"application/zip",
+}
+
+type AttachmentService struct {
+	repository repository.IAttachmentRepository
+	storage    storage.Storage
+}
+
+func NewAttachmentService(repository repository.IAttachmentRepository, storage storage.Storage) IAttachmentService {
+	return &AttachmentService{repository: repository, storage: storage}
+}
+
+func (s *AttachmentService) Upload(ctx context.Context, fileName string, body io.Reader, userId uint) (*model.Attachment, error) {
+	maxSize := attachmentMaxSize()
+
+	data, err := io.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, errors.New("file is too large")
+	}
+
+	// クライアントが申告したContent-Typeは信用せず、内容から判定する
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil || !isAllowedAttachmentType(contentType) {
+		return nil, errors.New("file type is not allowed")
+	}
+
+	key, err := newStorageKey(userId, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.storage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return nil, err
+	}
+
+	newAttachment := model.Attachment{
+		UserID:      userId,
+		FileName:    filepath.Base(fileName),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		StorageKey:  key,
+	}
+
+	if thumbnail, thumbnailType, err := createThumbnail(data, contentType); err == nil {
+		thumbnailKey := key + ".thumb"
+		if err := s.storage.Put(ctx, thumbnailKey, bytes.NewReader(thumbnail), int64(len(thumbnail)), thumbnailType); err != nil {
+			s.storage.Delete(context.WithoutCancel(ctx), key)
+			return nil, err
+		}
+		newAttachment.ThumbnailKey = thumbnailKey
+		newAttachment.HasThumbnail = true
+	}
+
+	attachment, err := s.repository.Create(ctx, newAttachment)
+	if err != nil {
+		s.deleteFiles(context.WithoutCancel(ctx), newAttachment)
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (s *AttachmentService) FindById(ctx context.Context, id uint) (*model.Attachment, error) {
+	return s.repository.FindById(ctx, id)
+}
+
+// userIdは閲覧するユーザーで、未ログインの場合は0
+func (s *AttachmentService) Open(ctx context.Context, id uint, userId uint) (*model.Attachment, io.ReadCloser, error) {
+	attachment, err := s.repository.FindVisibleById(ctx, id, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := s.storage.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return attachment, file, nil
+}
+
+func (s *AttachmentService) OpenThumbnail(ctx context.Context, id uint, userId uint) (*model.Attachment, io.ReadCloser, error) {
+	attachment, err := s.repository.FindVisibleById(ctx, id, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !attachment.HasThumbnail {
+		return nil, nil, errors.New("thumbnail not found")
+	}
+
+	file, err := s.storage.Get(ctx, attachment.ThumbnailKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return attachment, file, nil
+}
+
+// 未紐づけで本人がアップロードした添付ファイルかを確認する
+func (s *AttachmentService) CheckLinkable(ctx context.Context, ids []uint, userId uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	count, err := s.repository.CountUnlinked(ctx, ids, userId)
+	if err != nil {
+		return err
+	}
+	if count != int64(len(uniqueIds(ids))) {
+		return errors.New("attachment not found")
+	}
+
+	return nil
+}
+
+func (s *AttachmentService) Delete(ctx context.Context, id uint, userId uint) error {
+	attachment, err := s.repository.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if attachment.UserID != userId {
+		return errors.New("user is not attachment owner")
+	}
+
+	return s.delete(ctx, *attachment)
+}
+
+func (s *AttachmentService) DeleteByThreadId(ctx context.Context, threadId uint) error {
+	attachments, err := s.repository.FindByThreadId(ctx, threadId)
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range *attachments {
+		if err := s.delete(ctx, attachment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *AttachmentService) DeleteByCommentId(ctx context.Context, commentId uint) error {
+	attachments, err := s.repository.FindByCommentId(ctx, commentId)
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range *attachments {
+		if err := s.delete(ctx, attachment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *AttachmentService) delete(ctx context.Context, attachment model.Attachment) error {
+	if err := s.repository.Delete(ctx, attachment.ID); err != nil {
+		return err
+	}
+
+	return s.deleteFiles(ctx, attachment)
+}
+
+func (s *AttachmentService) deleteFiles(ctx context.Context, attachment model.Attachment) error {
+	if err := s.storage.Delete(ctx, attachment.StorageKey); err != nil {
+		return err
+	}
+
+	if attachment.HasThumbnail {
+		return s.storage.Delete(ctx, attachment.ThumbnailKey)
+	}
+
+	return nil
+}
+
+// ATTACHMENT_MAX_SIZE(バイト)が未設定の場合は10MB
+func attachmentMaxSize() int64 {
+	size, err := strconv.ParseInt(os.Getenv("ATTACHMENT_MAX_SIZE"), 10, 64)
+	if err != nil || size <= 0 {
+		return defaultAttachmentMaxSize
+	}
+	return size
+}
+
+// ATTACHMENT_ALLOWED_TYPESはカンマ区切りのMIMEタイプ
+func isAllowedAttachmentType(contentType string) bool {
+	allowedTypes := defaultAttachmentAllowedTypes
+	if env := os.Getenv("ATTACHMENT_ALLOWED_TYPES"); env != "" {
+		allowedTypes = strings.Split(env, ",")
+	}
+
+	for _, allowedType := range allowedTypes {
+		if strings.TrimSpace(allowedType) == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+func newStorageKey(userId uint, fileName string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("attachments/%d/%s%s", userId, hex.EncodeToString(random), strings.ToLower(filepath.Ext(fileName))), nil
+}
+
+func uniqueIds(ids []uint) []uint {
+	seen := map[uint]bool{}
+	unique := []uint{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
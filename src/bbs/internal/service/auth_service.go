@@ -1,25 +1,33 @@
 package service
 
 import (
+	"bbs/internal/dto"
 	"bbs/internal/model"
 	"bbs/internal/repository"
-	"fmt"
-	"os"
+	"context"
+	"errors"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthService struct {
-	repository repository.IAuthRepository
+	repository         repository.IAuthRepository
+	sanctionRepository repository.ISanctionRepository
+	apiTokenRepository repository.IAPITokenRepository
+	tokenService       ITokenService
 }
 
-func NewAuthService(repository repository.IAuthRepository) IAuthService {
-	return &AuthService{repository: repository}
+func NewAuthService(repository repository.IAuthRepository, sanctionRepository repository.ISanctionRepository, apiTokenRepository repository.IAPITokenRepository, tokenService ITokenService) IAuthService {
+	return &AuthService{
+		repository:         repository,
+		sanctionRepository: sanctionRepository,
+		apiTokenRepository: apiTokenRepository,
+		tokenService:       tokenService,
+	}
 }
 
-func (s *AuthService) Signup(name string, email string, password string) error {
+func (s *AuthService) Signup(ctx context.Context, name string, email string, password string) error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
@@ -31,11 +39,11 @@ func (s *AuthService) Signup(name string, email string, password string) error {
 		Password: string(hashedPassword),
 	}
 
-	return s.repository.CreateUser(user)
+	return s.repository.CreateUser(ctx, user)
 }
 
-func (s *AuthService) Login(email string, password string) (*string, error) {
-	foundUser, err := s.repository.FindUser(email)
+func (s *AuthService) Login(ctx context.Context, email string, password string) (*dto.LoginOutput, error) {
+	foundUser, err := s.repository.FindUser(ctx, email)
 	if err != nil {
 		return nil, err
 	}
@@ -45,51 +53,89 @@ func (s *AuthService) Login(email string, password string) (*string, error) {
 		return nil, err
 	}
 
-	token, err := createToken(foundUser.ID, foundUser.Email)
-	if err != nil {
+	// 停止中のユーザーはログインはできるが認証が必要な操作はできない。追放されたユーザーはログインもできない
+	if err := checkSanction(ctx, s.sanctionRepository, foundUser.ID); err != nil && !errors.Is(err, ErrUserSuspended) {
 		return nil, err
 	}
 
-	return token, nil
+	return issueLoginToken(ctx, s.tokenService, foundUser)
 }
 
-func createToken(userId uint, email string) (*string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":   userId,
-		"email": email,
-		"exp":   time.Now().Add(time.Hour).Unix(),
-	})
+// JWTと個人アクセストークンのどちらも受け付ける。個人アクセストークンの場合はスコープの確認に使うトークンも返す
+func (s *AuthService) GetUserFromToken(ctx context.Context, tokenString string) (*model.User, *model.APIToken, error) {
+	if isAPIToken(tokenString) {
+		return s.getUserFromAPIToken(ctx, tokenString)
+	}
 
-	tokenString, err := token.SignedString([]byte(os.Getenv("SECRET_KEY")))
+	claims, err := s.tokenService.Parse(ctx, tokenString)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	userId, err := claims.UserID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.repository.FindUserById(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkSanction(ctx, s.sanctionRepository, user.ID); err != nil {
+		return nil, nil, err
 	}
 
-	return &tokenString, nil
+	return user, nil, nil
 }
 
-func (s *AuthService) GetUserFromToken(tokenString string) (*model.User, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("SECRET_KEY")), nil
-	})
+func (s *AuthService) getUserFromAPIToken(ctx context.Context, tokenString string) (*model.User, *model.APIToken, error) {
+	apiToken, err := s.apiTokenRepository.FindByHash(ctx, hashAPIToken(tokenString))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if !apiToken.IsActive(now) {
+		return nil, nil, errors.New("api token is expired or revoked")
 	}
 
-	var user *model.User
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		if float64(time.Now().Unix()) > claims["exp"].(float64) {
-			return nil, jwt.ErrTokenExpired
+	user, err := s.repository.FindUserById(ctx, apiToken.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkSanction(ctx, s.sanctionRepository, user.ID); err != nil {
+		return nil, nil, err
+	}
+
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) >= apiTokenLastUsedInterval {
+		if err := s.apiTokenRepository.UpdateLastUsedAt(ctx, apiToken.ID, now); err != nil {
+			return nil, nil, err
 		}
-		user, err = s.repository.FindUser(claims["email"].(string))
+		apiToken.LastUsedAt = &now
+	}
+
+	return user, apiToken, nil
+}
+
+func (s *AuthService) JWKS(ctx context.Context) (*dto.JWKSOutput, error) {
+	return s.tokenService.JWKS(ctx)
+}
+
+// 二段階認証が有効なユーザーにはコードの検証に使うチャレンジトークンだけを発行する
+func issueLoginToken(ctx context.Context, tokenService ITokenService, user *model.User) (*dto.LoginOutput, error) {
+	if user.TOTPEnabled {
+		challengeToken, err := tokenService.IssueChallenge(ctx, user.ID, user.Email)
 		if err != nil {
 			return nil, err
 		}
+		return &dto.LoginOutput{TwoFactorRequired: true, ChallengeToken: challengeToken}, nil
 	}
 
-	return user, nil
-
+	token, err := tokenService.Issue(ctx, user.ID, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.LoginOutput{Token: token}, nil
 }
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"context"
+)
+
+type BookmarkService struct {
+	repository     repository.IBookmarkRepository
+	threadService  IThreadService
+	commentService ICommentService
+}
+
+func NewBookmarkService(repository repository.IBookmarkRepository, threadService IThreadService, commentService ICommentService) IBookmarkService {
+	return &BookmarkService{
+		repository:     repository,
+		threadService:  threadService,
+		commentService: commentService,
+	}
+}
+
+// commentIdがnilの場合はスレッドへのブックマーク。ブックマーク済みの場合はメモを更新する
+func (s *BookmarkService) Save(ctx context.Context, saveBookmarkInput dto.SaveBookmarkInput, threadId uint, commentId *uint, userId uint) (*model.Bookmark, error) {
+	if err := s.checkTarget(ctx, threadId, commentId, userId); err != nil {
+		return nil, err
+	}
+
+	bookmark := model.Bookmark{UserID: userId, ThreadID: threadId, CommentID: commentId, Note: saveBookmarkInput.Note}
+
+	return s.repository.Save(ctx, bookmark)
+}
+
+// 対象が削除された後でもブックマークは削除できる
+func (s *BookmarkService) Delete(ctx context.Context, threadId uint, commentId *uint, userId uint) error {
+	return s.repository.Delete(ctx, userId, threadId, commentId)
+}
+
+func (s *BookmarkService) FindByUserId(ctx context.Context, userId uint, limit int, offset int) (*dto.BookmarkListOutput, error) {
+	return s.repository.FindByUserId(ctx, userId, limit, offset)
+}
+
+func (s *BookmarkService) checkTarget(ctx context.Context, threadId uint, commentId *uint, userId uint) error {
+	if commentId == nil {
+		_, err := s.threadService.FindById(ctx, threadId, userId)
+		return err
+	}
+	_, err := s.commentService.FindById(ctx, *commentId, threadId, userId)
+	return err
+}
+
+// 閲覧しているユーザーがブックマークしているスレッドにBookmarkedを設定する。未ログインの場合は何もしない
+func markBookmarkedThreads(ctx context.Context, bookmarkRepository repository.IBookmarkRepository, threads []model.Thread, userId uint) error {
+	if userId == 0 || len(threads) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(threads))
+	for i, thread := range threads {
+		ids[i] = thread.ID
+	}
+	bookmarkedIds, err := bookmarkRepository.FindBookmarkedThreadIds(ctx, userId, ids)
+	if err != nil {
+		return err
+	}
+
+	bookmarked := make(map[uint]bool, len(bookmarkedIds))
+	for _, id := range bookmarkedIds {
+		bookmarked[id] = true
+	}
+	for i := range threads {
+		threads[i].Bookmarked = bookmarked[threads[i].ID]
+	}
+	return nil
+}
+
+// 閲覧しているユーザーがブックマークしているコメントにBookmarkedを設定する。未ログインの場合は何もしない
+func markBookmarkedComments(ctx context.Context, bookmarkRepository repository.IBookmarkRepository, comments []model.Comment, userId uint) error {
+	if userId == 0 || len(comments) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+	bookmarkedIds, err := bookmarkRepository.FindBookmarkedCommentIds(ctx, userId, ids)
+	if err != nil {
+		return err
+	}
+
+	bookmarked := make(map[uint]bool, len(bookmarkedIds))
+	for _, id := range bookmarkedIds {
+		bookmarked[id] = true
+	}
+	for i := range comments {
+		comments[i].Bookmarked = bookmarked[comments[i].ID]
+	}
+	return nil
+}
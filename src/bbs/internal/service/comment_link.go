@@ -0,0 +1,42 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// メールアドレスの@に反応しないよう、直前が文字・数字の場合は言及として扱わない
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([\p{L}\p{N}_-]+)`)
+
+// 全角の＞＞も掲示板のアンカーとして扱う
+var referencePattern = regexp.MustCompile(`(?:>>|＞＞)(\d+)`)
+
+// 本文中の@usernameを重複なく出現順に返す
+func parseMentions(body string) []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		name := match[1]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// 本文中の>>123を重複なく出現順に返す
+func parseReferences(body string) []uint {
+	seen := map[uint]bool{}
+	ids := []uint{}
+	for _, match := range referencePattern.FindAllStringSubmatch(body, -1) {
+		id, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || id == 0 || seen[uint(id)] {
+			continue
+		}
+		seen[uint(id)] = true
+		ids = append(ids, uint(id))
+	}
+	return ids
+}
@@ -1,58 +1,428 @@
 package service
 
 import (
+	"bbs/internal/contentfilter"
 	"bbs/internal/dto"
+	"bbs/internal/markdown"
 	"bbs/internal/model"
 	"bbs/internal/repository"
+	"context"
 	"errors"
+	"time"
 )
 
 type CommentService struct {
-	repository       repository.ICommentRepository
-	threadRepository repository.IThreadRepository
+	repository             repository.ICommentRepository
+	threadRepository       repository.IThreadRepository
+	revisionRepository     repository.IRevisionRepository
+	subscriptionRepository repository.ISubscriptionRepository
+	bookmarkRepository     repository.IBookmarkRepository
+	authRepository         repository.IAuthRepository
+	attachmentService      IAttachmentService
+	contentCheckService    IContentCheckService
+	webhookService         IWebhookService
+	unitOfWork             repository.IUnitOfWork
 }
 
-func NewCommentService(repository repository.ICommentRepository, threadRepository repository.IThreadRepository) ICommentService {
-	return &CommentService{repository: repository, threadRepository: threadRepository}
+func NewCommentService(repository repository.ICommentRepository, threadRepository repository.IThreadRepository, revisionRepository repository.IRevisionRepository, subscriptionRepository repository.ISubscriptionRepository, bookmarkRepository repository.IBookmarkRepository, authRepository repository.IAuthRepository, attachmentService IAttachmentService, contentCheckService IContentCheckService, webhookService IWebhookService, unitOfWork repository.IUnitOfWork) ICommentService {
+	return &CommentService{
+		repository:             repository,
+		threadRepository:       threadRepository,
+		revisionRepository:     revisionRepository,
+		subscriptionRepository: subscriptionRepository,
+		bookmarkRepository:     bookmarkRepository,
+		authRepository:         authRepository,
+		attachmentService:      attachmentService,
+		contentCheckService:    contentCheckService,
+		webhookService:         webhookService,
+		unitOfWork:             unitOfWork,
+	}
 }
 
-func (s *CommentService) Create(createCommentInput dto.CreateComment, threadId uint, userId uint) (*model.Comment, error) {
-	if _, err := s.threadRepository.FindById(threadId); err != nil {
+func (s *CommentService) Create(ctx context.Context, createCommentInput dto.CreateComment, threadId uint, userId uint) (*model.Comment, error) {
+	thread, err := s.findVisibleThread(ctx, threadId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkCommentable(thread); err != nil {
+		return nil, err
+	}
+
+	if err := s.attachmentService.CheckLinkable(ctx, createCommentInput.AttachmentIDs, userId); err != nil {
+		return nil, err
+	}
+
+	post, err := s.contentCheckService.Check(ctx, userId, "", createCommentInput.Body)
+	if err != nil {
 		return nil, err
 	}
 
-	newComment := model.Comment{
-		Body:     createCommentInput.Body,
-		ThreadID: threadId,
-		UserID:   userId,
+	bodyHTML, err := markdown.Render(post.Body)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.repository.Create(newComment)
+	var comment *model.Comment
+	// スレッドをロックしてから作成し、確認後に削除されたスレッドへコメントが残らないようにする
+	err = s.unitOfWork.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		lockedThread, err := repos.Threads.FindByIdForUpdate(ctx, threadId)
+		if err != nil {
+			return err
+		}
+
+		if err := checkCommentable(lockedThread); err != nil {
+			return err
+		}
+
+		newComment := model.Comment{
+			Body:      post.Body,
+			BodyHTML:  bodyHTML,
+			ThreadID:  threadId,
+			UserID:    userId,
+			Anonymous: lockedThread.Anonymous,
+		}
+
+		comment, err = repos.Comments.Create(ctx, newComment)
+		if err != nil {
+			return err
+		}
+
+		if err := repos.Threads.UpdateLastActivity(ctx, threadId, comment.CreatedAt); err != nil {
+			return err
+		}
+
+		// コメントしたユーザーは自動的に購読し、自分のコメントまでを既読とする
+		if _, err := repos.Subscriptions.Subscribe(ctx, userId, threadId, comment.ID); err != nil {
+			return err
+		}
+
+		if err := createFlagReport(ctx, repos.Reports, post, threadId, &comment.ID); err != nil {
+			return err
+		}
+
+		if len(createCommentInput.AttachmentIDs) > 0 {
+			if err := repos.Attachments.Link(ctx, createCommentInput.AttachmentIDs, userId, nil, &comment.ID); err != nil {
+				return err
+			}
+		}
+
+		comment, err = s.saveLinks(ctx, repos, *comment)
+		if err != nil {
+			return err
+		}
+
+		return publishWebhook(ctx, repos, model.WebhookEventCommentCreated, lockedThread, webhookComment(comment))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
 }
 
-func (s *CommentService) FindByThreadId(threadId uint, userId uint) (*[]model.Comment, error) {
-	return s.repository.FindByThreadId(threadId, userId)
+func checkCommentable(thread *model.Thread) error {
+	if thread.Locked {
+		return errors.New("thread is locked")
+	}
+
+	if isArchived(thread) {
+		return errors.New("thread is archived")
+	}
+
+	return nil
 }
 
-func (s *CommentService) FindById(id uint, threadId uint) (*model.Comment, error) {
-	return s.repository.FindById(id, threadId)
+// userIdは閲覧するユーザーで、未ログインの場合は0
+func (s *CommentService) FindByThreadId(ctx context.Context, threadId uint, query dto.CommentListQuery, userId uint) (*dto.CommentListOutput, error) {
+	if _, err := s.findVisibleThread(ctx, threadId, userId); err != nil {
+		return nil, err
+	}
+	commentList, err := s.repository.FindByThreadId(ctx, threadId, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := markBookmarkedComments(ctx, s.bookmarkRepository, commentList.Comments, userId); err != nil {
+		return nil, err
+	}
+	return commentList, nil
+}
+
+func (s *CommentService) FindByUserId(ctx context.Context, authorId uint, query dto.CommentListQuery, userId uint) (*dto.CommentListOutput, error) {
+	if _, err := s.authRepository.FindUserById(ctx, authorId); err != nil {
+		return nil, err
+	}
+	commentList, err := s.repository.FindByUserId(ctx, authorId, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := markBookmarkedComments(ctx, s.bookmarkRepository, commentList.Comments, userId); err != nil {
+		return nil, err
+	}
+	return commentList, nil
+}
+
+// userIdは閲覧するユーザーで、未ログインの場合は0
+func (s *CommentService) FindById(ctx context.Context, id uint, threadId uint, userId uint) (*model.Comment, error) {
+	if _, err := s.findVisibleThread(ctx, threadId, userId); err != nil {
+		return nil, err
+	}
+
+	comment, err := s.repository.FindById(ctx, id, threadId)
+	if err != nil {
+		return nil, err
+	}
+
+	// モデレーターによって非表示にされたコメントは存在しないものとして扱う
+	if comment.Hidden {
+		return nil, errors.New("comment not found")
+	}
+
+	comments := []model.Comment{*comment}
+	if err := markBookmarkedComments(ctx, s.bookmarkRepository, comments, userId); err != nil {
+		return nil, err
+	}
+
+	return &comments[0], nil
 }
 
-func (s *CommentService) Update(updateComment dto.UpdateComment, id uint, threadId uint, userId uint) (*model.Comment, error) {
-	targetComment, err := s.repository.FindById(id, threadId)
+// 非表示にされたスレッドや閲覧できないスレッドは存在しないものとして扱う
+func (s *CommentService) findVisibleThread(ctx context.Context, threadId uint, userId uint) (*model.Thread, error) {
+	thread, err := s.threadRepository.FindVisibleById(ctx, threadId, userId)
 	if err != nil {
 		return nil, err
 	}
+	if thread.Hidden {
+		return nil, errors.New("thread not found")
+	}
+	return thread, nil
+}
+
+// autoの扱いはThreadService.SetHiddenと同じ
+func (s *CommentService) SetHidden(ctx context.Context, id uint, threadId uint, hidden bool, auto bool) (*model.Comment, error) {
+	targetComment, err := s.repository.FindById(ctx, id, threadId)
+	if err != nil {
+		return nil, err
+	}
+
+	if auto && (targetComment.Hidden == hidden || (!hidden && !targetComment.AutoHidden)) {
+		return targetComment, nil
+	}
+	targetComment.Hidden = hidden
+	targetComment.AutoHidden = auto && hidden
+
+	return s.repository.Update(ctx, *targetComment)
+}
+
+func (s *CommentService) Update(ctx context.Context, updateComment dto.UpdateComment, id uint, threadId uint, userId uint) (*model.Comment, error) {
+	thread, err := s.findVisibleThread(ctx, threadId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.contentCheckService.CheckEdit(ctx, userId, "", updateComment.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.updateWithRevision(ctx, id, threadId, userId, post, func(targetComment *model.Comment) error {
+		if targetComment.UserID != userId {
+			return errors.New("user is not comment owner")
+		}
+
+		if isArchived(thread) {
+			return errors.New("thread is archived")
+		}
+
+		targetComment.Body = post.Body
+		return nil
+	})
+}
+
+func (s *CommentService) FindRevisions(ctx context.Context, id uint, threadId uint, userId uint) (*[]dto.RevisionOutput, error) {
+	comment, err := s.FindById(ctx, id, threadId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.revisionRepository.FindCommentRevisions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]dto.RevisionOutput, len(*revisions))
+	for i, revision := range *revisions {
+		// 各版の差分は次の版との比較で、最後の版は現在の内容と比較する
+		nextBody := comment.Body
+		if i+1 < len(*revisions) {
+			nextBody = (*revisions)[i+1].Body
+		}
+		outputs[i] = dto.RevisionOutput{
+			ID:        revision.ID,
+			Revision:  revision.Revision,
+			Body:      revision.Body,
+			EditedBy:  revision.EditedBy,
+			CreatedAt: revision.CreatedAt,
+			Diff:      diffLines(revision.Body, nextBody),
+			Anonymous: comment.Anonymous,
+		}
+	}
+
+	return &outputs, nil
+}
+
+func (s *CommentService) RevertRevision(ctx context.Context, id uint, threadId uint, revisionId uint, userId uint) (*model.Comment, error) {
+	revision, err := s.revisionRepository.FindCommentRevision(ctx, revisionId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.updateWithRevision(ctx, id, threadId, userId, nil, func(targetComment *model.Comment) error {
+		targetComment.Body = revision.Body
+		return nil
+	})
+}
+
+// ThreadService.updateWithRevisionと同様に、コメントをロックして読み込んでから版の作成と更新を行う
+func (s *CommentService) updateWithRevision(ctx context.Context, id uint, threadId uint, userId uint, post *contentfilter.Post, apply func(targetComment *model.Comment) error) (*model.Comment, error) {
+	var updated *model.Comment
+	changed := false
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		targetComment, err := repos.Comments.FindByIdForUpdate(ctx, id, threadId)
+		if err != nil {
+			return err
+		}
+
+		before := *targetComment
+		if err := apply(targetComment); err != nil {
+			return err
+		}
+		if before.Body == targetComment.Body {
+			updated, err = repos.Comments.Update(ctx, *targetComment)
+			return err
+		}
+		changed = true
+
+		bodyHTML, err := markdown.Render(targetComment.Body)
+		if err != nil {
+			return err
+		}
+		targetComment.BodyHTML = bodyHTML
+
+		editedAt := time.Now()
+		targetComment.Edited = true
+		targetComment.EditedAt = &editedAt
+
+		revision := model.CommentRevision{
+			CommentID: before.ID,
+			Body:      before.Body,
+			EditedBy:  userId,
+		}
+		if _, err := repos.Revisions.CreateCommentRevision(ctx, revision); err != nil {
+			return err
+		}
+
+		if post != nil {
+			if err := createFlagReport(ctx, repos.Reports, post, threadId, &before.ID); err != nil {
+				return err
+			}
+		}
+
+		if updated, err = repos.Comments.Update(ctx, *targetComment); err != nil {
+			return err
+		}
+
+		// 本文が変わった場合は言及・参照も更新する
+		updated, err = s.saveLinks(ctx, repos, *updated)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !changed {
+		return s.repository.FindById(ctx, updated.ID, updated.ThreadID)
+	}
+	return updated, nil
+}
+
+// 本文中の@usernameと>>123を解決して保存し、言及・参照を含めたコメントを返す。コメントと同じトランザクションで保存するためreposを受け取る
+func (s *CommentService) saveLinks(ctx context.Context, repos repository.Repositories, comment model.Comment) (*model.Comment, error) {
+	mentions := []model.CommentMention{}
+	if names := parseMentions(comment.Body); len(names) > 0 {
+		users, err := s.authRepository.FindUsersByNames(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+		// ユーザー名は一意ではないため、同じ名前のユーザーが複数いる場合は誰への言及か決められないので扱わない
+		counts := map[string]int{}
+		for _, user := range *users {
+			counts[user.Name]++
+		}
+		for _, user := range *users {
+			if counts[user.Name] == 1 {
+				mentions = append(mentions, model.CommentMention{CommentID: comment.ID, UserID: user.ID, Name: user.Name})
+			}
+		}
+	}
+
+	references := []model.CommentReference{}
+	if ids := parseReferences(comment.Body); len(ids) > 0 {
+		// 同じスレッドに存在するコメントのみ参照として扱う
+		comments, err := repos.Comments.FindByIds(ctx, ids, comment.ThreadID)
+		if err != nil {
+			return nil, err
+		}
+		for _, referenced := range *comments {
+			if referenced.ID == comment.ID {
+				continue
+			}
+			references = append(references, model.CommentReference{CommentID: comment.ID, ReferencedCommentID: referenced.ID})
+		}
+	}
+
+	if err := repos.Comments.ReplaceLinks(ctx, comment.ID, mentions, references); err != nil {
+		return nil, err
+	}
+
+	return repos.Comments.FindById(ctx, comment.ID, comment.ThreadID)
+}
+
+func (s *CommentService) Delete(ctx context.Context, id uint, threadId uint, userId uint) error {
+	targetComment, err := s.repository.FindById(ctx, id, threadId)
+	if err != nil {
+		return err
+	}
 
 	if targetComment.UserID != userId {
-		return nil, errors.New("user is not comment owner")
+		return errors.New("user is not comment owner")
 	}
 
-	targetComment.Body = updateComment.Body
+	return s.delete(ctx, *targetComment)
+}
 
-	return s.repository.Update(*targetComment)
+// モデレーターによる削除のため所有者の確認は行わない
+func (s *CommentService) DeleteByModerator(ctx context.Context, id uint, threadId uint) error {
+	targetComment, err := s.repository.FindById(ctx, id, threadId)
+	if err != nil {
+		return err
+	}
+
+	return s.delete(ctx, *targetComment)
 }
 
-func (s *CommentService) Delete(id uint, threadId uint, userId uint) error {
-	return s.repository.Delete(id, threadId, userId)
+func (s *CommentService) delete(ctx context.Context, comment model.Comment) error {
+	if err := s.repository.Delete(ctx, comment.ID, comment.ThreadID, comment.UserID); err != nil {
+		return err
+	}
+
+	if err := s.attachmentService.DeleteByCommentId(ctx, comment.ID); err != nil {
+		return err
+	}
+
+	thread, err := s.threadRepository.FindById(ctx, comment.ThreadID)
+	if err != nil {
+		return err
+	}
+	return s.webhookService.Publish(ctx, model.WebhookEventCommentDeleted, thread, dto.WebhookDeletedData{ID: comment.ID, ThreadID: comment.ThreadID})
 }
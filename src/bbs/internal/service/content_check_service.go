@@ -0,0 +1,74 @@
+package service
+
+import (
+	"bbs/internal/contentfilter"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"context"
+	"os"
+	"strings"
+)
+
+const defaultContentFilterConfig = "configs/content_filter.json"
+
+type ContentCheckService struct {
+	loader *contentfilter.Loader
+}
+
+func NewContentCheckService(postRepository repository.IPostRepository) IContentCheckService {
+	return &ContentCheckService{loader: contentfilter.NewLoader(postRepository)}
+}
+
+// ルールに従って投稿内容を確認する。拒否された場合はcontentfilter.ErrRejectedを返す
+func (s *ContentCheckService) Check(ctx context.Context, userId uint, title string, body string) (*contentfilter.Post, error) {
+	pipeline, err := s.loader.Pipeline(contentFilterConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	post := contentfilter.Post{UserID: userId, Title: title, Body: body}
+	if err := pipeline.Check(ctx, &post); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// 編集後の内容を確認する。重複投稿の確認は行わない
+func (s *ContentCheckService) CheckEdit(ctx context.Context, userId uint, title string, body string) (*contentfilter.Post, error) {
+	pipeline, err := s.loader.Pipeline(contentFilterConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	post := contentfilter.Post{UserID: userId, Title: title, Body: body, Edit: true}
+	if err := pipeline.Check(ctx, &post); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// 要確認とされた投稿をモデレーションキューに載せる。投稿と同じトランザクションで作成するためRepositories.Reportsを受け取る
+func createFlagReport(ctx context.Context, reportRepository repository.IReportRepository, post *contentfilter.Post, threadId uint, commentId *uint) error {
+	if !post.Flagged() {
+		return nil
+	}
+
+	newReport := model.Report{
+		ReporterID: model.SystemReporterID,
+		ThreadID:   threadId,
+		CommentID:  commentId,
+		Reason:     strings.Join(post.Flags, ", "),
+		Status:     model.ReportStatusOpen,
+	}
+	_, err := reportRepository.Create(ctx, newReport)
+	return err
+}
+
+// CONTENT_FILTER_CONFIGが未設定の場合はconfigs/content_filter.jsonを読み込む
+func contentFilterConfig() string {
+	path := os.Getenv("CONTENT_FILTER_CONFIG")
+	if path == "" {
+		return defaultContentFilterConfig
+	}
+	return path
+}
@@ -0,0 +1,85 @@
+package service
+
+import "strings"
+
+// 最長共通部分列の表の要素数の上限。超える場合はメモリを使いすぎないよう、変更部分をすべて置き換えたものとして扱う
+const maxDiffCells = 1_000_000
+
+// 行単位の差分を返す。各行の先頭は共通行が" "、削除行が"-"、追加行が"+"
+func diffLines(before string, after string) []string {
+	a := strings.Split(before, "\n")
+	b := strings.Split(after, "\n")
+
+	// 前後の共通する行は表を作らずに共通行とする
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	diff := make([]string, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		diff = append(diff, " "+line)
+	}
+	diff = append(diff, diffChangedLines(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		diff = append(diff, " "+line)
+	}
+
+	return diff
+}
+
+func diffChangedLines(a []string, b []string) []string {
+	diff := make([]string, 0, len(a)+len(b))
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for _, line := range a {
+			diff = append(diff, "-"+line)
+		}
+		for _, line := range b {
+			diff = append(diff, "+"+line)
+		}
+		return diff
+	}
+
+	// lcs[i][j]はa[i:]とb[j:]の最長共通部分列の長さ
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, " "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, "-"+a[i])
+			i++
+		default:
+			diff = append(diff, "+"+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, "-"+a[i])
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, "+"+b[j])
+	}
+
+	return diff
+}
@@ -1,5 +0,0 @@
-package service
-
-var (
-	CreateToken = createToken
-)
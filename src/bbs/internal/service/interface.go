@@ -1,28 +1,140 @@
 package service
 
 import (
+	"bbs/internal/contentfilter"
 	"bbs/internal/dto"
 	"bbs/internal/model"
+	"context"
+	"io"
+	"time"
 )
 
 type IAuthService interface {
-	Signup(name string, email string, password string) error
-	Login(email string, password string) (*string, error)
-	GetUserFromToken(tokenString string) (*model.User, error)
+	Signup(ctx context.Context, name string, email string, password string) error
+	Login(ctx context.Context, email string, password string) (*dto.LoginOutput, error)
+	GetUserFromToken(ctx context.Context, tokenString string) (*model.User, *model.APIToken, error)
+	JWKS(ctx context.Context) (*dto.JWKSOutput, error)
 }
 
 type ICommentService interface {
-	Create(createCommentInput dto.CreateComment, threadId uint, userId uint) (*model.Comment, error)
-	FindByThreadId(threadId uint, userId uint) (*[]model.Comment, error)
-	FindById(id uint, threadId uint) (*model.Comment, error)
-	Update(updateComment dto.UpdateComment, id uint, threadId uint, userId uint) (*model.Comment, error)
-	Delete(id uint, threadId uint, userId uint) error
+	Create(ctx context.Context, createCommentInput dto.CreateComment, threadId uint, userId uint) (*model.Comment, error)
+	FindByThreadId(ctx context.Context, threadId uint, query dto.CommentListQuery, userId uint) (*dto.CommentListOutput, error)
+	FindByUserId(ctx context.Context, authorId uint, query dto.CommentListQuery, userId uint) (*dto.CommentListOutput, error)
+	FindById(ctx context.Context, id uint, threadId uint, userId uint) (*model.Comment, error)
+	Update(ctx context.Context, updateComment dto.UpdateComment, id uint, threadId uint, userId uint) (*model.Comment, error)
+	Delete(ctx context.Context, id uint, threadId uint, userId uint) error
+	DeleteByModerator(ctx context.Context, id uint, threadId uint) error
+	SetHidden(ctx context.Context, id uint, threadId uint, hidden bool, auto bool) (*model.Comment, error)
+	FindRevisions(ctx context.Context, id uint, threadId uint, userId uint) (*[]dto.RevisionOutput, error)
+	RevertRevision(ctx context.Context, id uint, threadId uint, revisionId uint, userId uint) (*model.Comment, error)
 }
 
 type IThreadService interface {
-	Create(createThreadInput dto.CreateThreadInput, userId uint) (*model.Thread, error)
-	Update(threadId uint, updateThreadInput dto.UpdateThreadInput, userId uint) (*model.Thread, error)
-	Delete(threadId uint, userId uint) error
-	FindAll(limit int, offset int) (*dto.ThreadListOutput, error)
-	FindById(threadId uint) (*model.Thread, error)
+	Create(ctx context.Context, createThreadInput dto.CreateThreadInput, userId uint) (*model.Thread, error)
+	Update(ctx context.Context, threadId uint, updateThreadInput dto.UpdateThreadInput, userId uint) (*model.Thread, error)
+	UpdateState(ctx context.Context, threadId uint, updateThreadStateInput dto.UpdateThreadStateInput) (*model.Thread, error)
+	Delete(ctx context.Context, threadId uint, userId uint) error
+	DeleteByModerator(ctx context.Context, threadId uint) error
+	SetHidden(ctx context.Context, threadId uint, hidden bool, auto bool) (*model.Thread, error)
+	UpdateVisibility(ctx context.Context, threadId uint, updateThreadVisibilityInput dto.UpdateThreadVisibilityInput, userId uint) (*model.Thread, error)
+	FindAll(ctx context.Context, limit int, offset int, userId uint) (*dto.ThreadListOutput, error)
+	FindByUserId(ctx context.Context, userId uint, limit int, offset int) (*dto.ThreadListOutput, error)
+	FindParticipating(ctx context.Context, userId uint, limit int, offset int) (*dto.ThreadListOutput, error)
+	FindById(ctx context.Context, threadId uint, userId uint) (*model.Thread, error)
+	FindRevisions(ctx context.Context, threadId uint, userId uint) (*[]dto.RevisionOutput, error)
+	RevertRevision(ctx context.Context, threadId uint, revisionId uint, userId uint) (*model.Thread, error)
+	ArchiveInactive(ctx context.Context) (int64, error)
+}
+
+type IAttachmentService interface {
+	Upload(ctx context.Context, fileName string, body io.Reader, userId uint) (*model.Attachment, error)
+	FindById(ctx context.Context, id uint) (*model.Attachment, error)
+	Open(ctx context.Context, id uint, userId uint) (*model.Attachment, io.ReadCloser, error)
+	OpenThumbnail(ctx context.Context, id uint, userId uint) (*model.Attachment, io.ReadCloser, error)
+	CheckLinkable(ctx context.Context, ids []uint, userId uint) error
+	Delete(ctx context.Context, id uint, userId uint) error
+	DeleteByThreadId(ctx context.Context, threadId uint) error
+	DeleteByCommentId(ctx context.Context, commentId uint) error
+}
+
+type IReportService interface {
+	Create(ctx context.Context, createReportInput dto.CreateReportInput, threadId uint, commentId *uint, userId uint) (*model.Report, error)
+	FindQueue(ctx context.Context, limit int, offset int) (*dto.ModerationQueueOutput, error)
+	Resolve(ctx context.Context, resolveReportInput dto.ResolveReportInput, threadId uint, commentId *uint, moderatorId uint) error
+}
+
+type IContentCheckService interface {
+	Check(ctx context.Context, userId uint, title string, body string) (*contentfilter.Post, error)
+	CheckEdit(ctx context.Context, userId uint, title string, body string) (*contentfilter.Post, error)
+}
+
+type ISanctionService interface {
+	Create(ctx context.Context, createSanctionInput dto.CreateSanctionInput, userId uint, adminId uint) (*model.Sanction, error)
+	FindByUserId(ctx context.Context, userId uint) (*[]model.Sanction, error)
+	Revoke(ctx context.Context, id uint, userId uint, adminId uint) (*model.Sanction, error)
+}
+
+type ISubscriptionService interface {
+	Subscribe(ctx context.Context, threadId uint, subscribeInput dto.SubscribeInput, userId uint) (*model.Subscription, error)
+	Unsubscribe(ctx context.Context, threadId uint, userId uint) error
+	FindByUserId(ctx context.Context, userId uint, limit int, offset int) (*dto.SubscriptionListOutput, error)
+}
+
+type IBookmarkService interface {
+	Save(ctx context.Context, saveBookmarkInput dto.SaveBookmarkInput, threadId uint, commentId *uint, userId uint) (*model.Bookmark, error)
+	Delete(ctx context.Context, threadId uint, commentId *uint, userId uint) error
+	FindByUserId(ctx context.Context, userId uint, limit int, offset int) (*dto.BookmarkListOutput, error)
+}
+
+type IPollService interface {
+	Vote(ctx context.Context, threadId uint, votePollInput dto.VotePollInput, userId uint) (*model.Poll, error)
+	Unvote(ctx context.Context, threadId uint, userId uint) (*model.Poll, error)
+}
+
+type IOIDCService interface {
+	Authorize(ctx context.Context, providerName string) (*dto.OIDCAuthorizeOutput, error)
+	Callback(ctx context.Context, providerName string, callbackInput dto.OIDCCallbackInput) (*dto.LoginOutput, error)
+}
+
+type ITokenService interface {
+	Issue(ctx context.Context, userId uint, email string) (*string, error)
+	IssueChallenge(ctx context.Context, userId uint, email string) (*string, error)
+	Parse(ctx context.Context, tokenString string) (*TokenClaims, error)
+	ParseChallenge(ctx context.Context, tokenString string) (*TokenClaims, error)
+	JWKS(ctx context.Context) (*dto.JWKSOutput, error)
+	RotateKeys(ctx context.Context) (int64, error)
+}
+
+type ITwoFactorService interface {
+	Enroll(ctx context.Context, userId uint) (*dto.TwoFactorEnrollOutput, error)
+	Enable(ctx context.Context, codeInput dto.TwoFactorCodeInput, userId uint) (*dto.RecoveryCodesOutput, error)
+	Disable(ctx context.Context, codeInput dto.TwoFactorCodeInput, userId uint) error
+	FindStatus(ctx context.Context, userId uint) (*dto.TwoFactorStatusOutput, error)
+	Login(ctx context.Context, loginInput dto.TwoFactorLoginInput) (*dto.LoginOutput, error)
+}
+
+type IAPITokenService interface {
+	Create(ctx context.Context, createAPITokenInput dto.CreateAPITokenInput, userId uint) (*dto.CreateAPITokenOutput, error)
+	FindByUserId(ctx context.Context, userId uint) (*[]model.APIToken, error)
+	Revoke(ctx context.Context, id uint, userId uint) error
+}
+
+type IWebhookService interface {
+	Create(ctx context.Context, createWebhookInput dto.CreateWebhookInput, adminId uint) (*dto.CreateWebhookOutput, error)
+	FindAll(ctx context.Context) (*[]model.Webhook, error)
+	Delete(ctx context.Context, id uint) error
+	FindDeliveries(ctx context.Context, webhookId uint, limit int, offset int) (*dto.WebhookDeliveryListOutput, error)
+	Redeliver(ctx context.Context, webhookId uint, deliveryId uint) (*model.WebhookDelivery, error)
+	Publish(ctx context.Context, event string, thread *model.Thread, data interface{}) error
+}
+
+type IWebhookDispatcher interface {
+	DeliverPending(ctx context.Context) (int, error)
+}
+
+type IJobService interface {
+	Enqueue(ctx context.Context, jobType string, payload interface{}) (*model.Job, error)
+	EnqueueAt(ctx context.Context, jobType string, payload interface{}, runAt time.Time) (*model.Job, error)
+	FindAll(ctx context.Context, status string, limit int, offset int) (*dto.JobListOutput, error)
+	Retry(ctx context.Context, id uint) (*model.Job, error)
 }
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const defaultJobMaxAttempts = 5
+
+type JobService struct {
+	repository repository.IJobRepository
+}
+
+func NewJobService(repository repository.IJobRepository) IJobService {
+	return &JobService{repository: repository}
+}
+
+// すぐに実行するジョブを登録する。payloadはJSONにしてハンドラーに渡す
+func (s *JobService) Enqueue(ctx context.Context, jobType string, payload interface{}) (*model.Job, error) {
+	return s.EnqueueAt(ctx, jobType, payload, time.Now())
+}
+
+func (s *JobService) EnqueueAt(ctx context.Context, jobType string, payload interface{}, runAt time.Time) (*model.Job, error) {
+	return enqueueJob(ctx, s.repository, jobType, payload, runAt)
+}
+
+// 他の書き込みと同じトランザクションで登録できるよう、Repositories.Jobsも受け取る
+func enqueueJob(ctx context.Context, jobRepository repository.IJobRepository, jobType string, payload interface{}, runAt time.Time) (*model.Job, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return jobRepository.Enqueue(ctx, model.Job{
+		Type:        jobType,
+		Payload:     string(encoded),
+		Status:      model.JobStatusPending,
+		RunAt:       runAt,
+		MaxAttempts: defaultJobMaxAttempts,
+	})
+}
+
+func (s *JobService) FindAll(ctx context.Context, status string, limit int, offset int) (*dto.JobListOutput, error) {
+	return s.repository.FindAll(ctx, status, limit, offset)
+}
+
+func (s *JobService) Retry(ctx context.Context, id uint) (*model.Job, error) {
+	return s.repository.Revive(ctx, id, time.Now())
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/oidc"
+	"bbs/internal/repository"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// 認可リクエストの有効期限
+const OIDCStateTTL = 10 * time.Minute
+
+var ErrOIDCAuthentication = errors.New("oidc authentication failed")
+
+type OIDCService struct {
+	registry           *oidc.Registry
+	repository         repository.IIdentityRepository
+	authRepository     repository.IAuthRepository
+	sanctionRepository repository.ISanctionRepository
+	tokenService       ITokenService
+}
+
+func NewOIDCService(registry *oidc.Registry, repository repository.IIdentityRepository, authRepository repository.IAuthRepository, sanctionRepository repository.ISanctionRepository, tokenService ITokenService) IOIDCService {
+	return &OIDCService{
+		registry:           registry,
+		repository:         repository,
+		authRepository:     authRepository,
+		sanctionRepository: sanctionRepository,
+		tokenService:       tokenService,
+	}
+}
+
+// stateとnonceとPKCEのcode_verifierを発行し、プロバイダの認可URLを返す
+func (s *OIDCService) Authorize(ctx context.Context, providerName string) (*dto.OIDCAuthorizeOutput, error) {
+	provider, err := s.registry.Provider(ctx, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.repository.DeleteExpiredLoginStates(ctx, now); err != nil {
+		return nil, err
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	codeVerifier := oauth2.GenerateVerifier()
+
+	loginState := model.OIDCLoginState{
+		State:        state,
+		Provider:     provider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    now.Add(OIDCStateTTL),
+	}
+	if err := s.repository.CreateLoginState(ctx, loginState); err != nil {
+		return nil, err
+	}
+
+	return &dto.OIDCAuthorizeOutput{
+		AuthorizationURL: provider.AuthCodeURL(state, nonce, codeVerifier),
+		State:            state,
+	}, nil
+}
+
+// 認可コードをIDトークンに交換し、対応するユーザーのトークンを発行する
+func (s *OIDCService) Callback(ctx context.Context, providerName string, callbackInput dto.OIDCCallbackInput) (*dto.LoginOutput, error) {
+	provider, err := s.registry.Provider(ctx, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	loginState, err := s.repository.ConsumeLoginState(ctx, callbackInput.State, provider.Name(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := provider.Exchange(ctx, callbackInput.Code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCAuthentication, err)
+	}
+
+	user, err := s.findOrCreateUser(ctx, provider.Name(), claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// パスワードでのログインと同じく、追放されたユーザーはログインできない
+	if err := checkSanction(ctx, s.sanctionRepository, user.ID); err != nil && !errors.Is(err, ErrUserSuspended) {
+		return nil, err
+	}
+
+	return issueLoginToken(ctx, s.tokenService, user)
+}
+
+// 紐づけ済みのアカウントがなければ、確認済みのメールアドレスが一致するユーザーに紐づける。
+// 一致するユーザーもいなければ新規に登録する
+func (s *OIDCService) findOrCreateUser(ctx context.Context, providerName string, claims *oidc.Claims) (*model.User, error) {
+	identity, err := s.repository.FindIdentity(ctx, providerName, claims.Subject)
+	if err == nil {
+		return s.authRepository.FindUserById(ctx, identity.UserID)
+	}
+	if err.Error() != "identity not found" {
+		return nil, err
+	}
+
+	// 未確認のメールアドレスで紐づけると他人のアカウントを乗っ取れてしまう
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errors.New("email is not verified")
+	}
+
+	newIdentity := model.UserIdentity{
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+
+	user, err := s.authRepository.FindUser(ctx, claims.Email)
+	if err == nil {
+		newIdentity.UserID = user.ID
+		if _, err := s.repository.CreateIdentity(ctx, newIdentity); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	if err.Error() != "user not found" {
+		return nil, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	// パスワードは空のため、パスワードでのログインはできない
+	newUser := model.User{Name: name, Email: claims.Email}
+	return s.repository.CreateUserWithIdentity(ctx, newUser, newIdentity)
+}
+
+func randomToken() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random), nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"context"
+	"errors"
+	"time"
+)
+
+type PollService struct {
+	repository    repository.IPollRepository
+	threadService IThreadService
+}
+
+func NewPollService(repository repository.IPollRepository, threadService IThreadService) IPollService {
+	return &PollService{repository: repository, threadService: threadService}
+}
+
+func (s *PollService) Vote(ctx context.Context, threadId uint, votePollInput dto.VotePollInput, userId uint) (*model.Poll, error) {
+	poll, err := s.findOpenPoll(ctx, threadId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	optionIds := uniqueIds(votePollInput.OptionIDs)
+	if !poll.MultipleChoice && len(optionIds) > 1 {
+		return nil, errors.New("poll allows only one choice")
+	}
+
+	validOptions := make(map[uint]bool, len(poll.Options))
+	for _, option := range poll.Options {
+		validOptions[option.ID] = true
+	}
+	vote := model.PollVote{PollID: poll.ID, UserID: userId}
+	for _, optionId := range optionIds {
+		if !validOptions[optionId] {
+			return nil, errors.New("invalid poll option")
+		}
+		vote.Choices = append(vote.Choices, model.PollVoteChoice{OptionID: optionId})
+	}
+
+	if len(poll.MyChoices) > 0 {
+		return nil, errors.New("already voted")
+	}
+	if err := s.repository.CreateVote(ctx, vote); err != nil {
+		return nil, err
+	}
+
+	return s.repository.FindByThreadId(ctx, threadId, userId)
+}
+
+// 締め切り前であれば投票を取り消して投票し直せる
+func (s *PollService) Unvote(ctx context.Context, threadId uint, userId uint) (*model.Poll, error) {
+	poll, err := s.findOpenPoll(ctx, threadId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repository.DeleteVote(ctx, poll.ID, userId); err != nil {
+		return nil, err
+	}
+
+	return s.repository.FindByThreadId(ctx, threadId, userId)
+}
+
+func (s *PollService) findOpenPoll(ctx context.Context, threadId uint, userId uint) (*model.Poll, error) {
+	thread, err := s.threadService.FindById(ctx, threadId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if thread.Poll == nil {
+		return nil, errors.New("poll not found")
+	}
+
+	if thread.Locked {
+		return nil, errors.New("thread is locked")
+	}
+
+	if isArchived(thread) {
+		return nil, errors.New("thread is archived")
+	}
+
+	if thread.Poll.IsClosed(time.Now()) {
+		return nil, errors.New("poll is closed")
+	}
+
+	return thread.Poll, nil
+}
+
+func newPoll(threadId uint, createPollInput dto.CreatePollInput) model.Poll {
+	poll := model.Poll{
+		ThreadID:       threadId,
+		Question:       createPollInput.Question,
+		MultipleChoice: createPollInput.MultipleChoice,
+		PublicVotes:    createPollInput.PublicVotes,
+		ClosesAt:       createPollInput.ClosesAt,
+	}
+	for i, label := range createPollInput.Options {
+		poll.Options = append(poll.Options, model.PollOption{Label: label, Position: i})
+	}
+	return poll
+}
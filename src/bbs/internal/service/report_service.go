@@ -0,0 +1,155 @@
+package service
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+)
+
+const defaultReportHideThreshold = 5
+
+type ReportService struct {
+	repository     repository.IReportRepository
+	threadService  IThreadService
+	commentService ICommentService
+}
+
+func NewReportService(repository repository.IReportRepository, threadService IThreadService, commentService ICommentService) IReportService {
+	return &ReportService{repository: repository, threadService: threadService, commentService: commentService}
+}
+
+func (s *ReportService) Create(ctx context.Context, createReportInput dto.CreateReportInput, threadId uint, commentId *uint, userId uint) (*model.Report, error) {
+	if err := s.checkTarget(ctx, threadId, commentId, userId); err != nil {
+		return nil, err
+	}
+
+	count, err := s.repository.CountByReporter(ctx, userId, threadId, commentId)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("already reported")
+	}
+
+	newReport := model.Report{
+		ReporterID: userId,
+		ThreadID:   threadId,
+		CommentID:  commentId,
+		Reason:     createReportInput.Reason,
+		Status:     model.ReportStatusOpen,
+	}
+
+	report, err := s.repository.Create(ctx, newReport)
+	if err != nil {
+		return nil, err
+	}
+
+	// 通報数がしきい値に達したらモデレーターの対応を待たずに非表示にする
+	threshold := reportHideThreshold()
+	if threshold == 0 {
+		return report, nil
+	}
+
+	openCount, err := s.repository.CountOpen(ctx, threadId, commentId)
+	if err != nil {
+		return nil, err
+	}
+	if openCount >= threshold {
+		if err := s.setHidden(ctx, threadId, commentId, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+func (s *ReportService) FindQueue(ctx context.Context, limit int, offset int) (*dto.ModerationQueueOutput, error) {
+	return s.repository.FindQueue(ctx, limit, offset)
+}
+
+func (s *ReportService) Resolve(ctx context.Context, resolveReportInput dto.ResolveReportInput, threadId uint, commentId *uint, moderatorId uint) error {
+	openCount, err := s.repository.CountOpen(ctx, threadId, commentId)
+	if err != nil {
+		return err
+	}
+	if openCount == 0 {
+		return errors.New("report not found")
+	}
+
+	var status string
+	switch resolveReportInput.Action {
+	case "dismiss":
+		status = model.ReportStatusDismissed
+		// 通報数で自動的に非表示になっていた場合だけ元に戻す
+		if err := s.undoAutoHide(ctx, threadId, commentId); err != nil {
+			return err
+		}
+	case "hide":
+		status = model.ReportStatusHidden
+		if err := s.setHidden(ctx, threadId, commentId, false); err != nil {
+			return err
+		}
+	case "delete":
+		status = model.ReportStatusDeleted
+		if err := s.delete(ctx, threadId, commentId); err != nil {
+			return err
+		}
+	default:
+		return errors.New("invalid action")
+	}
+
+	_, err = s.repository.Resolve(ctx, threadId, commentId, status, moderatorId)
+	return err
+}
+
+// 通報者が閲覧できない対象は通報できない
+func (s *ReportService) checkTarget(ctx context.Context, threadId uint, commentId *uint, userId uint) error {
+	if commentId == nil {
+		_, err := s.threadService.FindById(ctx, threadId, userId)
+		return err
+	}
+	_, err := s.commentService.FindById(ctx, *commentId, threadId, userId)
+	return err
+}
+
+func (s *ReportService) setHidden(ctx context.Context, threadId uint, commentId *uint, auto bool) error {
+	if commentId == nil {
+		_, err := s.threadService.SetHidden(ctx, threadId, true, auto)
+		return err
+	}
+	_, err := s.commentService.SetHidden(ctx, *commentId, threadId, true, auto)
+	return err
+}
+
+func (s *ReportService) undoAutoHide(ctx context.Context, threadId uint, commentId *uint) error {
+	if commentId == nil {
+		_, err := s.threadService.SetHidden(ctx, threadId, false, true)
+		return err
+	}
+	_, err := s.commentService.SetHidden(ctx, *commentId, threadId, false, true)
+	return err
+}
+
+func (s *ReportService) delete(ctx context.Context, threadId uint, commentId *uint) error {
+	if commentId == nil {
+		return s.threadService.DeleteByModerator(ctx, threadId)
+	}
+	return s.commentService.DeleteByModerator(ctx, *commentId, threadId)
+}
+
+// REPORT_HIDE_THRESHOLDが未設定の場合は5件、0の場合は自動で非表示にしない
+func reportHideThreshold() int64 {
+	env := os.Getenv("REPORT_HIDE_THRESHOLD")
+	if env == "" {
+		return defaultReportHideThreshold
+	}
+	threshold, err := strconv.ParseInt(env, 10, 64)
+	if err != nil || threshold < 0 {
+		return defaultReportHideThreshold
+	}
+	return threshold
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrUserSuspended = errors.New("user is suspended")
+	ErrUserBanned    = errors.New("user is banned")
+)
+
+type SanctionService struct {
+	repository     repository.ISanctionRepository
+	authRepository repository.IAuthRepository
+}
+
+func NewSanctionService(repository repository.ISanctionRepository, authRepository repository.IAuthRepository) ISanctionService {
+	return &SanctionService{repository: repository, authRepository: authRepository}
+}
+
+func (s *SanctionService) Create(ctx context.Context, createSanctionInput dto.CreateSanctionInput, userId uint, adminId uint) (*model.Sanction, error) {
+	targetUser, err := s.authRepository.FindUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if targetUser.IsAdmin() {
+		return nil, errors.New("cannot sanction admin")
+	}
+
+	newSanction := model.Sanction{
+		UserID:   userId,
+		Type:     createSanctionInput.Type,
+		Reason:   createSanctionInput.Reason,
+		IssuedBy: adminId,
+	}
+
+	switch createSanctionInput.Type {
+	case model.SanctionTypeSuspension:
+		if createSanctionInput.DurationHours <= 0 {
+			return nil, errors.New("duration is required")
+		}
+		expiresAt := time.Now().Add(time.Duration(createSanctionInput.DurationHours) * time.Hour)
+		newSanction.ExpiresAt = &expiresAt
+	case model.SanctionTypeBan:
+	default:
+		return nil, errors.New("invalid sanction type")
+	}
+
+	return s.repository.Create(ctx, newSanction)
+}
+
+func (s *SanctionService) FindByUserId(ctx context.Context, userId uint) (*[]model.Sanction, error) {
+	if _, err := s.authRepository.FindUserById(ctx, userId); err != nil {
+		return nil, err
+	}
+	return s.repository.FindByUserId(ctx, userId)
+}
+
+func (s *SanctionService) Revoke(ctx context.Context, id uint, userId uint, adminId uint) (*model.Sanction, error) {
+	sanction, err := s.repository.FindById(ctx, id, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !sanction.IsActive(now) {
+		return nil, errors.New("sanction is not active")
+	}
+
+	sanction.RevokedAt = &now
+	sanction.RevokedBy = &adminId
+
+	return s.repository.Update(ctx, *sanction)
+}
+
+// 有効な制裁があれば、理由と期限を含むエラーを返す
+func checkSanction(ctx context.Context, repository repository.ISanctionRepository, userId uint) error {
+	sanction, err := repository.FindActive(ctx, userId, time.Now())
+	if err != nil {
+		return err
+	}
+	if sanction == nil {
+		return nil
+	}
+	return sanctionError(sanction)
+}
+
+func sanctionError(sanction *model.Sanction) error {
+	if sanction.Type == model.SanctionTypeBan {
+		return fmt.Errorf("%w: %s", ErrUserBanned, sanction.Reason)
+	}
+	return fmt.Errorf("%w until %s: %s", ErrUserSuspended, sanction.ExpiresAt.Format(time.RFC3339), sanction.Reason)
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 暗号化した秘密鍵の先頭に付ける
+const encryptedSigningKeyPrefix = "aes256gcm:"
+
+// DBを読めても署名鍵を取り出してトークンを偽造できないよう、JWT_KEY_ENCRYPTION_KEYでAES-256-GCMで暗号化して保存する
+func encryptSigningKey(privateKeyPEM []byte) (string, error) {
+	aead, err := signingKeyCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, privateKeyPEM, nil)
+	return encryptedSigningKeyPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// 平文で保存された鍵は、DBを読めた誰かが差し替えた可能性があるため使わない
+func decryptSigningKey(stored string) ([]byte, error) {
+	if !strings.HasPrefix(stored, encryptedSigningKeyPrefix) {
+		return nil, errors.New("signing key is not encrypted")
+	}
+
+	aead, err := signingKeyCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, encryptedSigningKeyPrefix))
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted signing key is too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+}
+
+// JWT_KEY_ENCRYPTION_KEYは32バイトの鍵を16進数で表したもの(openssl rand -hex 32で生成できる)
+func signingKeyCipher() (cipher.AEAD, error) {
+	encoded := os.Getenv("JWT_KEY_ENCRYPTION_KEY")
+	if encoded == "" {
+		return nil, errors.New("JWT_KEY_ENCRYPTION_KEY is required")
+	}
+
+	key, err := hex.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("JWT_KEY_ENCRYPTION_KEY must be 32 bytes in hex")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
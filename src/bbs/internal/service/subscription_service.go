@@ -0,0 +1,45 @@
+package service
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"context"
+)
+
+type SubscriptionService struct {
+	repository    repository.ISubscriptionRepository
+	threadService IThreadService
+}
+
+func NewSubscriptionService(repository repository.ISubscriptionRepository, threadService IThreadService) ISubscriptionService {
+	return &SubscriptionService{repository: repository, threadService: threadService}
+}
+
+// 購読済みの場合は既読位置の更新として扱う
+func (s *SubscriptionService) Subscribe(ctx context.Context, threadId uint, subscribeInput dto.SubscribeInput, userId uint) (*model.Subscription, error) {
+	if _, err := s.threadService.FindById(ctx, threadId, userId); err != nil {
+		return nil, err
+	}
+
+	var lastReadCommentId uint
+	if subscribeInput.LastReadCommentID != nil {
+		lastReadCommentId = *subscribeInput.LastReadCommentID
+	} else {
+		latest, err := s.repository.LatestCommentId(ctx, threadId)
+		if err != nil {
+			return nil, err
+		}
+		lastReadCommentId = latest
+	}
+
+	return s.repository.Subscribe(ctx, userId, threadId, lastReadCommentId)
+}
+
+func (s *SubscriptionService) Unsubscribe(ctx context.Context, threadId uint, userId uint) error {
+	return s.repository.Unsubscribe(ctx, userId, threadId)
+}
+
+func (s *SubscriptionService) FindByUserId(ctx context.Context, userId uint, limit int, offset int) (*dto.SubscriptionListOutput, error) {
+	return s.repository.FindByUserId(ctx, userId, limit, offset)
+}
@@ -1,52 +1,195 @@
 package service
 
 import (
+	"bbs/internal/contentfilter"
 	"bbs/internal/dto"
+	"bbs/internal/markdown"
 	"bbs/internal/model"
 	"bbs/internal/repository"
+	"context"
 	"errors"
+	"os"
+	"strconv"
+	"time"
 )
 
 type ThreadService struct {
-	repository repository.IThreadRepository
+	repository             repository.IThreadRepository
+	revisionRepository     repository.IRevisionRepository
+	subscriptionRepository repository.ISubscriptionRepository
+	bookmarkRepository     repository.IBookmarkRepository
+	pollRepository         repository.IPollRepository
+	attachmentService      IAttachmentService
+	contentCheckService    IContentCheckService
+	webhookService         IWebhookService
+	unitOfWork             repository.IUnitOfWork
 }
 
-func NewThreadService(repository repository.IThreadRepository) IThreadService {
-	return &ThreadService{repository: repository}
+func NewThreadService(repository repository.IThreadRepository, revisionRepository repository.IRevisionRepository, subscriptionRepository repository.ISubscriptionRepository, bookmarkRepository repository.IBookmarkRepository, pollRepository repository.IPollRepository, attachmentService IAttachmentService, contentCheckService IContentCheckService, webhookService IWebhookService, unitOfWork repository.IUnitOfWork) IThreadService {
+	return &ThreadService{
+		repository:             repository,
+		revisionRepository:     revisionRepository,
+		subscriptionRepository: subscriptionRepository,
+		bookmarkRepository:     bookmarkRepository,
+		pollRepository:         pollRepository,
+		attachmentService:      attachmentService,
+		contentCheckService:    contentCheckService,
+		webhookService:         webhookService,
+		unitOfWork:             unitOfWork,
+	}
 }
 
-func (s *ThreadService) Create(createThreadInput dto.CreateThreadInput, userId uint) (*model.Thread, error) {
+func (s *ThreadService) Create(ctx context.Context, createThreadInput dto.CreateThreadInput, userId uint) (*model.Thread, error) {
+	if err := s.attachmentService.CheckLinkable(ctx, createThreadInput.AttachmentIDs, userId); err != nil {
+		return nil, err
+	}
+
+	if createThreadInput.Poll != nil && createThreadInput.Poll.ClosesAt != nil && !createThreadInput.Poll.ClosesAt.After(time.Now()) {
+		return nil, errors.New("poll close time must be in the future")
+	}
+
+	post, err := s.contentCheckService.Check(ctx, userId, createThreadInput.Title, createThreadInput.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyHTML, err := markdown.Render(post.Body)
+	if err != nil {
+		return nil, err
+	}
+
 	newThread := model.Thread{
-		Title:  createThreadInput.Title,
-		Body:   createThreadInput.Body,
-		UserID: userId,
+		Title:          post.Title,
+		Body:           post.Body,
+		BodyHTML:       bodyHTML,
+		UserID:         userId,
+		Anonymous:      createThreadInput.Anonymous,
+		Visibility:     createThreadInput.Visibility,
+		LastActivityAt: time.Now(),
 	}
-	return s.repository.Create(newThread)
-}
+	if newThread.Visibility == "" {
+		newThread.Visibility = model.VisibilityPublic
+	}
+
+	var thread *model.Thread
+	// 途中で失敗した場合に投票や添付ファイルのないスレッドが残らないよう、1つのトランザクションで作成する
+	err = s.unitOfWork.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		thread, err = repos.Threads.Create(ctx, newThread)
+		if err != nil {
+			return err
+		}
+
+		if err := createFlagReport(ctx, repos.Reports, post, thread.ID, nil); err != nil {
+			return err
+		}
+
+		// 作成者は自動的にスレッドを購読する
+		if _, err := repos.Subscriptions.Subscribe(ctx, userId, thread.ID, 0); err != nil {
+			return err
+		}
+
+		if createThreadInput.Poll != nil {
+			if _, err := repos.Polls.Create(ctx, newPoll(thread.ID, *createThreadInput.Poll)); err != nil {
+				return err
+			}
+		}
 
-func (s *ThreadService) Update(threadId uint, updateThreadInput dto.UpdateThreadInput, userId uint) (*model.Thread, error) {
-	targetThread, err := s.FindById(threadId)
+		if thread.Visibility == model.VisibilityPrivate {
+			if err := repos.Threads.ReplaceMembers(ctx, thread.ID, invitedUserIds(createThreadInput.InvitedUserIDs, userId)); err != nil {
+				return err
+			}
+		}
+
+		if len(createThreadInput.AttachmentIDs) > 0 {
+			if err := repos.Attachments.Link(ctx, createThreadInput.AttachmentIDs, userId, &thread.ID, nil); err != nil {
+				return err
+			}
+		}
+
+		// 添付ファイルや投票を含めた内容を送るため、作成したスレッドを読み込み直す
+		created, err := repos.Threads.FindById(ctx, thread.ID)
+		if err != nil {
+			return err
+		}
+		if createThreadInput.Poll != nil {
+			if created.Poll, err = repos.Polls.FindByThreadId(ctx, thread.ID, 0); err != nil {
+				return err
+			}
+		}
+		return publishWebhook(ctx, repos, model.WebhookEventThreadCreated, created, webhookThread(created))
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if targetThread.UserID != userId {
-		return nil, errors.New("user is not thread owner")
+	if thread.Visibility != model.VisibilityPrivate && len(createThreadInput.AttachmentIDs) == 0 && createThreadInput.Poll == nil {
+		return thread, nil
 	}
 
+	return s.FindById(ctx, thread.ID, userId)
+}
+
+func (s *ThreadService) Update(ctx context.Context, threadId uint, updateThreadInput dto.UpdateThreadInput, userId uint) (*model.Thread, error) {
+	// 変更しない項目は作成時に確認済みのため、指定された項目だけを確認する
+	var title, body string
 	if updateThreadInput.Title != nil {
-		targetThread.Title = *updateThreadInput.Title
+		title = *updateThreadInput.Title
 	}
-
 	if updateThreadInput.Body != nil {
-		targetThread.Body = *updateThreadInput.Body
+		body = *updateThreadInput.Body
+	}
+	post, err := s.contentCheckService.CheckEdit(ctx, userId, title, body)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.repository.Update(*targetThread)
+	return s.updateWithRevision(ctx, threadId, userId, post, func(targetThread *model.Thread) error {
+		if targetThread.UserID != userId {
+			return errors.New("user is not thread owner")
+		}
+
+		if isArchived(targetThread) {
+			return errors.New("thread is archived")
+		}
+
+		if updateThreadInput.Title != nil {
+			targetThread.Title = post.Title
+		}
+
+		if updateThreadInput.Body != nil {
+			targetThread.Body = post.Body
+		}
+		return nil
+	})
 }
 
-func (s *ThreadService) Delete(threadId uint, userId uint) error {
-	targetThread, err := s.FindById(threadId)
+func (s *ThreadService) UpdateState(ctx context.Context, threadId uint, updateThreadStateInput dto.UpdateThreadStateInput) (*model.Thread, error) {
+	targetThread, err := s.repository.FindById(ctx, threadId)
+	if err != nil {
+		return nil, err
+	}
+
+	if updateThreadStateInput.Pinned != nil {
+		targetThread.Pinned = *updateThreadStateInput.Pinned
+	}
+
+	if updateThreadStateInput.Locked != nil {
+		targetThread.Locked = *updateThreadStateInput.Locked
+	}
+
+	if updateThreadStateInput.Archived != nil {
+		targetThread.Archived = *updateThreadStateInput.Archived
+		// アーカイブ解除直後に再び自動アーカイブされないよう最終更新日時を更新する
+		if !targetThread.Archived {
+			targetThread.LastActivityAt = time.Now()
+		}
+	}
+
+	return s.repository.Update(ctx, *targetThread)
+}
+
+func (s *ThreadService) Delete(ctx context.Context, threadId uint, userId uint) error {
+	targetThread, err := s.repository.FindById(ctx, threadId)
 	if err != nil {
 		return err
 	}
@@ -55,13 +198,272 @@ func (s *ThreadService) Delete(threadId uint, userId uint) error {
 		return errors.New("user is not thread owner")
 	}
 
-	return s.repository.Delete(threadId, userId)
+	return s.delete(ctx, *targetThread)
+}
+
+// モデレーターによる削除のため所有者の確認は行わない
+func (s *ThreadService) DeleteByModerator(ctx context.Context, threadId uint) error {
+	targetThread, err := s.repository.FindById(ctx, threadId)
+	if err != nil {
+		return err
+	}
+
+	return s.delete(ctx, *targetThread)
+}
+
+func (s *ThreadService) delete(ctx context.Context, thread model.Thread) error {
+	if err := s.repository.Delete(ctx, thread.ID, thread.UserID); err != nil {
+		return err
+	}
+
+	if err := s.attachmentService.DeleteByThreadId(ctx, thread.ID); err != nil {
+		return err
+	}
+
+	return s.webhookService.Publish(ctx, model.WebhookEventThreadDeleted, &thread, dto.WebhookDeletedData{ID: thread.ID, ThreadID: thread.ID})
 }
 
-func (s *ThreadService) FindAll(limit int, offset int) (*dto.ThreadListOutput, error) {
-	return s.repository.FindAll(limit, offset)
+func (s *ThreadService) UpdateVisibility(ctx context.Context, threadId uint, updateThreadVisibilityInput dto.UpdateThreadVisibilityInput, userId uint) (*model.Thread, error) {
+	targetThread, err := s.repository.FindById(ctx, threadId)
+	if err != nil {
+		return nil, err
+	}
+
+	if targetThread.UserID != userId {
+		return nil, errors.New("user is not thread owner")
+	}
+
+	targetThread.Visibility = updateThreadVisibilityInput.Visibility
+	if _, err := s.repository.Update(ctx, *targetThread); err != nil {
+		return nil, err
+	}
+
+	// 招待の指定がなければ招待済みのユーザーはそのまま残す
+	if updateThreadVisibilityInput.InvitedUserIDs != nil {
+		if err := s.repository.ReplaceMembers(ctx, threadId, invitedUserIds(updateThreadVisibilityInput.InvitedUserIDs, userId)); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.FindById(ctx, threadId, userId)
+}
+
+func (s *ThreadService) FindAll(ctx context.Context, limit int, offset int, userId uint) (*dto.ThreadListOutput, error) {
+	threadList, err := s.repository.FindAll(ctx, limit, offset, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := markBookmarkedThreads(ctx, s.bookmarkRepository, threadList.Threads, userId); err != nil {
+		return nil, err
+	}
+	return threadList, nil
 }
 
-func (s *ThreadService) FindById(threadId uint) (*model.Thread, error) {
-	return s.repository.FindById(threadId)
+func (s *ThreadService) FindByUserId(ctx context.Context, userId uint, limit int, offset int) (*dto.ThreadListOutput, error) {
+	threadList, err := s.repository.FindByUserId(ctx, userId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err := markBookmarkedThreads(ctx, s.bookmarkRepository, threadList.Threads, userId); err != nil {
+		return nil, err
+	}
+	return threadList, nil
+}
+
+func (s *ThreadService) FindParticipating(ctx context.Context, userId uint, limit int, offset int) (*dto.ThreadListOutput, error) {
+	threadList, err := s.repository.FindParticipating(ctx, userId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err := markBookmarkedThreads(ctx, s.bookmarkRepository, threadList.Threads, userId); err != nil {
+		return nil, err
+	}
+	return threadList, nil
+}
+
+// userIdは閲覧するユーザーで、未ログインの場合は0
+func (s *ThreadService) FindById(ctx context.Context, threadId uint, userId uint) (*model.Thread, error) {
+	thread, err := s.repository.FindVisibleById(ctx, threadId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// モデレーターによって非表示にされたスレッドは存在しないものとして扱う
+	if thread.Hidden {
+		return nil, errors.New("thread not found")
+	}
+
+	threads := []model.Thread{*thread}
+	if err := markBookmarkedThreads(ctx, s.bookmarkRepository, threads, userId); err != nil {
+		return nil, err
+	}
+
+	poll, err := s.pollRepository.FindByThreadId(ctx, threadId, userId)
+	if err != nil && err.Error() != "poll not found" {
+		return nil, err
+	}
+	threads[0].Poll = poll
+
+	return &threads[0], nil
+}
+
+// autoがtrueの場合は通報数による自動の非表示として扱う。
+// 既に非表示のものは自動の非表示にせず、元に戻すのは自動で非表示にしたものだけにする
+func (s *ThreadService) SetHidden(ctx context.Context, threadId uint, hidden bool, auto bool) (*model.Thread, error) {
+	targetThread, err := s.repository.FindById(ctx, threadId)
+	if err != nil {
+		return nil, err
+	}
+
+	if auto && (targetThread.Hidden == hidden || (!hidden && !targetThread.AutoHidden)) {
+		return targetThread, nil
+	}
+	targetThread.Hidden = hidden
+	targetThread.AutoHidden = auto && hidden
+
+	return s.repository.Update(ctx, *targetThread)
+}
+
+func (s *ThreadService) FindRevisions(ctx context.Context, threadId uint, userId uint) (*[]dto.RevisionOutput, error) {
+	thread, err := s.FindById(ctx, threadId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.revisionRepository.FindThreadRevisions(ctx, threadId)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]dto.RevisionOutput, len(*revisions))
+	for i, revision := range *revisions {
+		// 各版の差分は次の版との比較で、最後の版は現在の内容と比較する
+		nextBody := thread.Body
+		if i+1 < len(*revisions) {
+			nextBody = (*revisions)[i+1].Body
+		}
+		outputs[i] = dto.RevisionOutput{
+			ID:        revision.ID,
+			Revision:  revision.Revision,
+			Title:     revision.Title,
+			Body:      revision.Body,
+			EditedBy:  revision.EditedBy,
+			CreatedAt: revision.CreatedAt,
+			Diff:      diffLines(revision.Body, nextBody),
+			Anonymous: thread.Anonymous,
+		}
+	}
+
+	return &outputs, nil
+}
+
+func (s *ThreadService) RevertRevision(ctx context.Context, threadId uint, revisionId uint, userId uint) (*model.Thread, error) {
+	revision, err := s.revisionRepository.FindThreadRevision(ctx, revisionId, threadId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.updateWithRevision(ctx, threadId, userId, nil, func(targetThread *model.Thread) error {
+		targetThread.Title = revision.Title
+		targetThread.Body = revision.Body
+		return nil
+	})
+}
+
+// 内容が変わる場合は更新前の内容を版として残してから更新する。
+// 同時に更新されても版の番号が重複しないよう、スレッドをロックして読み込み、版の作成と更新まで同じトランザクションで行う
+// postは編集内容を確認した結果で、要確認とされた場合はモデレーションキューに載せる。版を戻す場合はnil
+func (s *ThreadService) updateWithRevision(ctx context.Context, threadId uint, userId uint, post *contentfilter.Post, apply func(targetThread *model.Thread) error) (*model.Thread, error) {
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		targetThread, err := repos.Threads.FindByIdForUpdate(ctx, threadId)
+		if err != nil {
+			return err
+		}
+
+		before := *targetThread
+		if err := apply(targetThread); err != nil {
+			return err
+		}
+		if before.Title == targetThread.Title && before.Body == targetThread.Body {
+			_, err := repos.Threads.Update(ctx, *targetThread)
+			return err
+		}
+
+		bodyHTML, err := markdown.Render(targetThread.Body)
+		if err != nil {
+			return err
+		}
+		targetThread.BodyHTML = bodyHTML
+
+		editedAt := time.Now()
+		targetThread.Edited = true
+		targetThread.EditedAt = &editedAt
+
+		revision := model.ThreadRevision{
+			ThreadID: before.ID,
+			Title:    before.Title,
+			Body:     before.Body,
+			EditedBy: userId,
+		}
+		if _, err := repos.Revisions.CreateThreadRevision(ctx, revision); err != nil {
+			return err
+		}
+
+		if post != nil {
+			if err := createFlagReport(ctx, repos.Reports, post, threadId, nil); err != nil {
+				return err
+			}
+		}
+
+		_, err = repos.Threads.Update(ctx, *targetThread)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.FindById(ctx, threadId)
+}
+
+func (s *ThreadService) ArchiveInactive(ctx context.Context) (int64, error) {
+	archiveAfter := threadArchiveAfter()
+	if archiveAfter == 0 {
+		return 0, nil
+	}
+	return s.repository.ArchiveInactive(ctx, time.Now().Add(-archiveAfter))
+}
+
+// THREAD_ARCHIVE_DAYSが未設定または0以下の場合は自動アーカイブしない
+func threadArchiveAfter() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("THREAD_ARCHIVE_DAYS"))
+	if err != nil || days <= 0 {
+		return 0
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// 自動アーカイブのバッチが走る前でも、放置期間を過ぎたスレッドは読み取り専用として扱う
+func isArchived(thread *model.Thread) bool {
+	if thread.Archived {
+		return true
+	}
+	archiveAfter := threadArchiveAfter()
+	if archiveAfter == 0 || thread.Pinned || thread.LastActivityAt.IsZero() {
+		return false
+	}
+	return thread.LastActivityAt.Before(time.Now().Add(-archiveAfter))
+}
+
+// 作成者自身と重複を除いた招待ユーザー
+func invitedUserIds(ids []uint, ownerId uint) []uint {
+	seen := map[uint]bool{ownerId: true}
+	var result []uint
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
 }
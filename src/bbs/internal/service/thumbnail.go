@@ -0,0 +1,82 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const thumbnailMaxSize = 320
+
+// デコードすると画素数に比例したメモリを確保するため、これを超える画像はサムネイルを作成しない
+const thumbnailMaxPixels = 40_000_000
+
+// 画像を長辺がthumbnailMaxSize以下になるよう縮小する。透過を含みうる形式はPNG、それ以外はJPEGで返す
+func createThumbnail(data []byte, contentType string) ([]byte, string, error) {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+	default:
+		return nil, "", errors.New("not an image")
+	}
+
+	// ヘッダーだけを読んで寸法を確認してから本体をデコードする
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > thumbnailMaxPixels {
+		return nil, "", errors.New("image is too large")
+	}
+
+	var src image.Image
+	switch contentType {
+	case "image/jpeg", "image/png", "image/webp":
+		src, _, err = image.Decode(bytes.NewReader(data))
+	case "image/gif":
+		src, err = gif.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > thumbnailMaxSize || height > thumbnailMaxSize {
+		if width >= height {
+			height = max(height*thumbnailMaxSize/width, 1)
+			width = thumbnailMaxSize
+		} else {
+			width = max(width*thumbnailMaxSize/height, 1)
+			height = thumbnailMaxSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	thumbnailType := ThumbnailContentType(contentType)
+	if thumbnailType == "image/jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return buf.Bytes(), thumbnailType, nil
+}
+
+func ThumbnailContentType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
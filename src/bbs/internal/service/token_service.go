@@ -0,0 +1,403 @@
+package service
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	tokenLifetime = time.Hour
+	// 二段階認証のコード入力を待つ間だけ使えればよい
+	challengeTokenLifetime = 5 * time.Minute
+
+	defaultTokenIssuer            = "bbs"
+	defaultTokenAudience          = "bbs"
+	defaultSigningKeyRotationDays = 30
+	defaultSigningAlgorithm       = model.SigningAlgorithmRS256
+	signingKeyCacheTTL            = time.Minute
+	// 未知のkidによる読み込み直しの最短間隔。存在しないkidを大量に送られてもDBへの問い合わせは増えない
+	signingKeyReloadInterval = 5 * time.Second
+	rsaSigningKeyBits        = 2048
+)
+
+type TokenClaims struct {
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// UserIDはsubから読み取ったユーザーのID
+func (c *TokenClaims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(c.Subject, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid subject: %w", err)
+	}
+	return uint(id), nil
+}
+
+type signingKey struct {
+	keyID      string
+	algorithm  string
+	privateKey crypto.Signer
+	createdAt  time.Time
+}
+
+// 他のインスタンスがローテーションした鍵も使えるよう、鍵はDBに保存し一定時間ごとに読み込み直す
+type TokenService struct {
+	repository repository.ISigningKeyRepository
+	mu         sync.Mutex
+	keys       []signingKey
+	loadedAt   time.Time
+}
+
+func NewTokenService(repository repository.ISigningKeyRepository) ITokenService {
+	return &TokenService{repository: repository}
+}
+
+func (s *TokenService) Issue(ctx context.Context, userId uint, email string) (*string, error) {
+	return s.issue(ctx, userId, email, tokenAudience(), tokenLifetime)
+}
+
+// 二段階認証のチャレンジトークン。audienceが異なるため通常のトークンとしては使えない
+func (s *TokenService) IssueChallenge(ctx context.Context, userId uint, email string) (*string, error) {
+	return s.issue(ctx, userId, email, challengeAudience(), challengeTokenLifetime)
+}
+
+// 署名・有効期限・発行者・audienceを検証する
+func (s *TokenService) Parse(ctx context.Context, tokenString string) (*TokenClaims, error) {
+	return s.parse(ctx, tokenString, tokenAudience())
+}
+
+func (s *TokenService) ParseChallenge(ctx context.Context, tokenString string) (*TokenClaims, error) {
+	return s.parse(ctx, tokenString, challengeAudience())
+}
+
+func (s *TokenService) issue(ctx context.Context, userId uint, email string, audience string, lifetime time.Duration) (*string, error) {
+	now := time.Now()
+	key, err := s.currentKey(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := TokenClaims{
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer(),
+			Subject:   strconv.FormatUint(uint64(userId), 10),
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(lifetime)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.algorithm), claims)
+	token.Header["kid"] = key.keyID
+
+	tokenString, err := token.SignedString(key.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenString, nil
+}
+
+func (s *TokenService) parse(ctx context.Context, tokenString string, audience string) (*TokenClaims, error) {
+	var claims TokenClaims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		return s.verificationKey(ctx, token)
+	},
+		jwt.WithValidMethods([]string{model.SigningAlgorithmRS256, model.SigningAlgorithmEdDSA}),
+		jwt.WithIssuer(tokenIssuer()),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (s *TokenService) JWKS(ctx context.Context) (*dto.JWKSOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadIfStale(ctx, time.Now()); err != nil {
+		return nil, err
+	}
+
+	output := dto.JWKSOutput{Keys: make([]dto.JWK, 0, len(s.keys))}
+	for _, key := range s.keys {
+		jwk := dto.JWK{Alg: key.algorithm, Use: "sig", Kid: key.keyID}
+		switch publicKey := key.privateKey.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		}
+		output.Keys = append(output.Keys, jwk)
+	}
+	return &output, nil
+}
+
+// 署名に使う鍵。ローテーション期間を過ぎている場合や設定のアルゴリズムと異なる場合は新しい鍵を作成する
+func (s *TokenService) currentKey(ctx context.Context, now time.Time) (*signingKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadIfStale(ctx, now); err != nil {
+		return nil, err
+	}
+
+	if !s.rotationDue(now) {
+		current := s.keys[0]
+		return &current, nil
+	}
+
+	key, err := s.rotate(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// トークンを発行しなくても鍵が古くなり続けないよう、ワーカーから定期的に呼び出す。
+// ローテーション期間を過ぎていれば新しい鍵を作成し、期限が切れた鍵を削除して削除した件数を返す
+func (s *TokenService) RotateKeys(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 他のインスタンスがローテーションしたばかりの場合に重ねて作成しないよう、キャッシュを使わずに読み込む
+	now := time.Now()
+	if err := s.load(ctx, now); err != nil {
+		return 0, err
+	}
+	if s.rotationDue(now) {
+		if _, err := s.rotate(ctx, now); err != nil {
+			return 0, err
+		}
+	}
+	return s.repository.DeleteExpired(ctx, now)
+}
+
+// 鍵がない場合、ローテーション期間を過ぎている場合、設定のアルゴリズムと異なる場合にtrue
+func (s *TokenService) rotationDue(now time.Time) bool {
+	if len(s.keys) == 0 {
+		return true
+	}
+	current := s.keys[0]
+	return current.algorithm != signingAlgorithm() || !current.createdAt.Add(signingKeyRotationPeriod()).After(now)
+}
+
+func (s *TokenService) verificationKey(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	keyID, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, errors.New("kid is missing")
+	}
+
+	key, err := s.findKey(ctx, keyID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// 鍵と異なるアルゴリズムで署名されたトークンは受け付けない
+	if token.Method.Alg() != key.algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Method.Alg())
+	}
+	return key.privateKey.Public(), nil
+}
+
+func (s *TokenService) findKey(ctx context.Context, keyID string, now time.Time) (*signingKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadIfStale(ctx, now); err != nil {
+		return nil, err
+	}
+	if key := s.cachedKey(keyID); key != nil {
+		return key, nil
+	}
+
+	// 他のインスタンスが作成したばかりの鍵の場合があるため読み込み直す
+	if now.Sub(s.loadedAt) < signingKeyReloadInterval {
+		return nil, errors.New("signing key not found")
+	}
+	if err := s.load(ctx, now); err != nil {
+		return nil, err
+	}
+	if key := s.cachedKey(keyID); key != nil {
+		return key, nil
+	}
+	return nil, errors.New("signing key not found")
+}
+
+func (s *TokenService) cachedKey(keyID string) *signingKey {
+	for _, key := range s.keys {
+		if key.keyID == keyID {
+			return &key
+		}
+	}
+	return nil
+}
+
+func (s *TokenService) loadIfStale(ctx context.Context, now time.Time) error {
+	if s.keys != nil && now.Sub(s.loadedAt) < signingKeyCacheTTL {
+		return nil
+	}
+	return s.load(ctx, now)
+}
+
+func (s *TokenService) load(ctx context.Context, now time.Time) error {
+	records, err := s.repository.FindActive(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]signingKey, 0, len(records))
+	for _, record := range records {
+		// 読み込めない鍵は検証に使わない。署名に使える鍵がなくなった場合はrotateで新しく作成する
+		key, err := parseSigningKey(record)
+		if err != nil {
+			log.Printf("skip signing key %s: %v\n", record.KeyID, err)
+			continue
+		}
+		keys = append(keys, *key)
+	}
+
+	s.keys = keys
+	s.loadedAt = now
+	return nil
+}
+
+// 古い鍵はそれで署名されたトークンが失効するまで検証に使えるよう残しておく
+func (s *TokenService) rotate(ctx context.Context, now time.Time) (*signingKey, error) {
+	algorithm := signingAlgorithm()
+	privateKey, err := generateSigningKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+
+	encrypted, err := encryptSigningKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.repository.Create(ctx, model.SigningKey{
+		KeyID:      now.UTC().Format("20060102") + "-" + hex.EncodeToString(random),
+		Algorithm:  algorithm,
+		PrivateKey: encrypted,
+		ExpiresAt:  now.Add(signingKeyRotationPeriod() + tokenLifetime),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := parseSigningKey(*record)
+	if err != nil {
+		return nil, err
+	}
+	s.keys = append([]signingKey{*key}, s.keys...)
+	return key, nil
+}
+
+func parseSigningKey(record model.SigningKey) (*signingKey, error) {
+	decrypted, err := decryptSigningKey(record.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key %s: %w", record.KeyID, err)
+	}
+	block, _ := pem.Decode(decrypted)
+	if block == nil {
+		return nil, fmt.Errorf("invalid signing key %s", record.KeyID)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("invalid signing key %s", record.KeyID)
+	}
+	return &signingKey{
+		keyID:      record.KeyID,
+		algorithm:  record.Algorithm,
+		privateKey: privateKey,
+		createdAt:  record.CreatedAt,
+	}, nil
+}
+
+func generateSigningKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case model.SigningAlgorithmRS256:
+		return rsa.GenerateKey(rand.Reader, rsaSigningKeyBits)
+	case model.SigningAlgorithmEdDSA:
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		return privateKey, err
+	}
+	return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+}
+
+// JWT_SIGNING_ALGORITHMはRS256またはEdDSA。未設定の場合はRS256
+func signingAlgorithm() string {
+	algorithm := os.Getenv("JWT_SIGNING_ALGORITHM")
+	if algorithm == "" {
+		return defaultSigningAlgorithm
+	}
+	return algorithm
+}
+
+// JWT_KEY_ROTATION_DAYSが未設定または0以下の場合は30日
+func signingKeyRotationPeriod() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("JWT_KEY_ROTATION_DAYS"))
+	if err != nil || days <= 0 {
+		days = defaultSigningKeyRotationDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func tokenIssuer() string {
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return defaultTokenIssuer
+}
+
+func tokenAudience() string {
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		return audience
+	}
+	return defaultTokenAudience
+}
+
+func challengeAudience() string {
+	return tokenAudience() + "/2fa"
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"bbs/internal/totp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTOTPIssuer = "bbs"
+	recoveryCodeCount = 10
+	// ログインの2段階目でこの回数続けて失敗すると、twoFactorLockDurationの間ログインできない
+	twoFactorMaxAttempts  = 5
+	twoFactorLockDuration = 15 * time.Minute
+)
+
+var (
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	ErrInvalidChallenge     = errors.New("invalid challenge token")
+	ErrTwoFactorLocked      = errors.New("too many failed two-factor attempts")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type TwoFactorService struct {
+	repository         repository.ITwoFactorRepository
+	authRepository     repository.IAuthRepository
+	sanctionRepository repository.ISanctionRepository
+	tokenService       ITokenService
+}
+
+func NewTwoFactorService(repository repository.ITwoFactorRepository, authRepository repository.IAuthRepository, sanctionRepository repository.ISanctionRepository, tokenService ITokenService) ITwoFactorService {
+	return &TwoFactorService{
+		repository:         repository,
+		authRepository:     authRepository,
+		sanctionRepository: sanctionRepository,
+		tokenService:       tokenService,
+	}
+}
+
+// 秘密鍵を発行する。有効化するまでログインには影響しない
+func (s *TwoFactorService) Enroll(ctx context.Context, userId uint) (*dto.TwoFactorEnrollOutput, error) {
+	user, err := s.authRepository.FindUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repository.UpdateSecret(ctx, user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &dto.TwoFactorEnrollOutput{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(totpIssuer(), user.Email, secret),
+	}, nil
+}
+
+// 認証アプリに正しく登録できたことをコードで確認してから有効にする
+func (s *TwoFactorService) Enable(ctx context.Context, codeInput dto.TwoFactorCodeInput, userId uint) (*dto.RecoveryCodesOutput, error) {
+	user, err := s.authRepository.FindUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("two-factor authentication is not enrolled")
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, codeInput.Code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, codeHashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repository.Enable(ctx, user.ID, step, codeHashes); err != nil {
+		return nil, err
+	}
+
+	return &dto.RecoveryCodesOutput{RecoveryCodes: codes}, nil
+}
+
+// 無効にする場合もコードの確認が必要。端末を失くした場合はリカバリーコードを使う
+func (s *TwoFactorService) Disable(ctx context.Context, codeInput dto.TwoFactorCodeInput, userId uint) error {
+	user, err := s.authRepository.FindUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := s.verifyCode(ctx, user, codeInput.Code); err != nil {
+		return err
+	}
+
+	return s.repository.Disable(ctx, user.ID)
+}
+
+func (s *TwoFactorService) FindStatus(ctx context.Context, userId uint) (*dto.TwoFactorStatusOutput, error) {
+	user, err := s.authRepository.FindUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return &dto.TwoFactorStatusOutput{}, nil
+	}
+
+	remaining, err := s.repository.CountUnusedRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.TwoFactorStatusOutput{Enabled: true, RemainingRecoveryCodes: remaining}, nil
+}
+
+// ログインの2段階目。チャレンジトークンとコードを検証して通常のトークンを発行する
+func (s *TwoFactorService) Login(ctx context.Context, loginInput dto.TwoFactorLoginInput) (*dto.LoginOutput, error) {
+	claims, err := s.tokenService.ParseChallenge(ctx, loginInput.ChallengeToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidChallenge, err)
+	}
+
+	userId, err := claims.UserID()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidChallenge, err)
+	}
+
+	user, err := s.authRepository.FindUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	// チャレンジの発行後に無効にされた場合
+	if !user.TOTPEnabled {
+		return nil, ErrInvalidChallenge
+	}
+
+	if err := checkSanction(ctx, s.sanctionRepository, user.ID); err != nil && !errors.Is(err, ErrUserSuspended) {
+		return nil, err
+	}
+
+	// 6桁のコードは総当たりできるため、続けて失敗した場合はしばらく受け付けない
+	now := time.Now()
+	if user.TOTPLockedUntil != nil && now.Before(*user.TOTPLockedUntil) {
+		return nil, ErrTwoFactorLocked
+	}
+
+	if err := s.verifyCode(ctx, user, loginInput.Code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if err := s.repository.RecordFailure(ctx, user.ID, twoFactorMaxAttempts, now.Add(twoFactorLockDuration)); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+	if user.TOTPFailedAttempts > 0 || user.TOTPLockedUntil != nil {
+		if err := s.repository.ResetFailures(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := s.tokenService.Issue(ctx, user.ID, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.LoginOutput{Token: token}, nil
+}
+
+// 認証アプリのコードとして検証し、一致しなければ未使用のリカバリーコードとして検証する
+func (s *TwoFactorService) verifyCode(ctx context.Context, user *model.User, code string) error {
+	now := time.Now()
+	if step, ok := totp.Validate(user.TOTPSecret, code, now); ok {
+		if err := s.repository.UseStep(ctx, user.ID, step); err != nil {
+			if err.Error() == "code already used" {
+				return ErrInvalidTwoFactorCode
+			}
+			return err
+		}
+		return nil
+	}
+
+	if err := s.repository.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(code), now); err != nil {
+		if err.Error() == "recovery code not found" {
+			return ErrInvalidTwoFactorCode
+		}
+		return err
+	}
+	return nil
+}
+
+// リカバリーコードはxxxxxxxx-xxxxxxxxの形式。十分に長いランダムな値のためハッシュにはSHA-256を使う
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	codeHashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		random := make([]byte, 10)
+		if _, err := rand.Read(random); err != nil {
+			return nil, nil, err
+		}
+		encoded := strings.ToLower(recoveryCodeEncoding.EncodeToString(random))
+		codes[i] = encoded[:8] + "-" + encoded[8:]
+		codeHashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, codeHashes, nil
+}
+
+// 入力の揺れを吸収するため、区切りと大文字小文字を無視してからハッシュ化する
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return defaultTOTPIssuer
+}
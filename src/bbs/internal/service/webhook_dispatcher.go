@@ -0,0 +1,142 @@
+package service
+
+import (
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	webhookDispatchBatchSize = 100
+	// 送信中の配信を他の配信処理が取得しないようにする時間。HTTPクライアントのタイムアウトより長くする
+	webhookDeliveryLease  = time.Minute
+	webhookMaxAttempts    = 8
+	webhookRetryBaseDelay = 30 * time.Second
+	// 配信記録に残すレスポンスボディの最大長
+	webhookResponseBodyLimit = 1024
+)
+
+type WebhookDispatcher struct {
+	repository repository.IWebhookRepository
+	client     *http.Client
+}
+
+func NewWebhookDispatcher(repository repository.IWebhookRepository, client *http.Client) IWebhookDispatcher {
+	return &WebhookDispatcher{repository: repository, client: client}
+}
+
+// 送信予定時刻を過ぎた配信を送信し、送信した件数を返す
+func (d *WebhookDispatcher) DeliverPending(ctx context.Context) (int, error) {
+	deliveries, err := d.repository.FindDueDeliveries(ctx, time.Now(), webhookDispatchBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, delivery := range *deliveries {
+		// 前の配信の送信に時間がかかってもリースが切れないよう、配信ごとに取得する
+		now := time.Now()
+		claimed, err := d.repository.ClaimDelivery(ctx, delivery.ID, now, now.Add(webhookDeliveryLease))
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		d.deliver(ctx, &delivery)
+		// 停止中に送信が中断された場合も、再送できるよう結果は記録する
+		if err := d.repository.UpdateDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// 2xxが返れば成功とし、それ以外は指数的に間隔を空けて再送する。上限に達した場合は失敗とする
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *model.WebhookDelivery) {
+	delivery.Attempts++
+	delivery.ResponseStatus = nil
+	delivery.ResponseBody = ""
+	delivery.Error = ""
+
+	statusCode, body, err := d.send(ctx, delivery)
+	if err != nil {
+		delivery.Error = err.Error()
+	} else {
+		delivery.ResponseStatus = &statusCode
+		delivery.ResponseBody = body
+	}
+
+	now := time.Now()
+	if err == nil && statusCode >= 200 && statusCode < 300 {
+		delivery.Status = model.WebhookDeliveryStatusSucceeded
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		return
+	}
+
+	if delivery.Attempts >= webhookMaxAttempts {
+		delivery.Status = model.WebhookDeliveryStatusFailed
+		delivery.NextAttemptAt = nil
+		return
+	}
+
+	nextAttemptAt := now.Add(webhookRetryDelay(delivery.Attempts))
+	delivery.NextAttemptAt = &nextAttemptAt
+}
+
+func (d *WebhookDispatcher) send(ctx context.Context, delivery *model.WebhookDelivery) (int, string, error) {
+	if delivery.Webhook == nil {
+		return 0, "", fmt.Errorf("webhook %d not found", delivery.WebhookID)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bbs-webhook")
+	req.Header.Set("X-BBS-Event", delivery.Event)
+	req.Header.Set("X-BBS-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-BBS-Timestamp", timestamp)
+	req.Header.Set("X-BBS-Signature", SignWebhookPayload(delivery.Webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, webhookResponseBodyLimit))
+	if err != nil {
+		return res.StatusCode, "", nil
+	}
+	return res.StatusCode, string(body), nil
+}
+
+// 受信側はX-BBS-Timestampとボディを"."でつないだ文字列のHMAC-SHA256で署名を検証する。
+// タイムスタンプを含めることで古いリクエストの再送を検出できる
+func SignWebhookPayload(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// 1回目の失敗で30秒後、以降は倍々に間隔を空ける
+func webhookRetryDelay(attempts int) time.Duration {
+	return webhookRetryBaseDelay << (attempts - 1)
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"bbs/internal/dto"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+type WebhookService struct {
+	repository repository.IWebhookRepository
+	jobService IJobService
+}
+
+func NewWebhookService(repository repository.IWebhookRepository, jobService IJobService) IWebhookService {
+	return &WebhookService{repository: repository, jobService: jobService}
+}
+
+func (s *WebhookService) Create(ctx context.Context, createWebhookInput dto.CreateWebhookInput, adminId uint) (*dto.CreateWebhookOutput, error) {
+	secret := createWebhookInput.Secret
+	if secret == "" {
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(random)
+	}
+
+	webhook, err := s.repository.Create(ctx, model.Webhook{
+		URL:       createWebhookInput.URL,
+		Secret:    secret,
+		Events:    createWebhookInput.Events,
+		ThreadID:  createWebhookInput.ThreadID,
+		Active:    true,
+		CreatedBy: adminId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dto.CreateWebhookOutput{Webhook: webhook, Secret: secret}, nil
+}
+
+func (s *WebhookService) FindAll(ctx context.Context) (*[]model.Webhook, error) {
+	return s.repository.FindAll(ctx)
+}
+
+func (s *WebhookService) Delete(ctx context.Context, id uint) error {
+	return s.repository.Delete(ctx, id)
+}
+
+func (s *WebhookService) FindDeliveries(ctx context.Context, webhookId uint, limit int, offset int) (*dto.WebhookDeliveryListOutput, error) {
+	if _, err := s.repository.FindById(ctx, webhookId); err != nil {
+		return nil, err
+	}
+	return s.repository.FindDeliveries(ctx, webhookId, limit, offset)
+}
+
+// 元の配信記録は残し、同じ内容の配信を新しく作成する
+func (s *WebhookService) Redeliver(ctx context.Context, webhookId uint, deliveryId uint) (*model.WebhookDelivery, error) {
+	delivery, err := s.repository.FindDelivery(ctx, deliveryId, webhookId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	redelivery := model.WebhookDelivery{
+		WebhookID:     delivery.WebhookID,
+		Event:         delivery.Event,
+		Payload:       delivery.Payload,
+		Status:        model.WebhookDeliveryStatusPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  &delivery.ID,
+	}
+	created, err := s.repository.CreateDelivery(ctx, redelivery)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.jobService.Enqueue(ctx, model.JobTypeDeliverWebhooks, nil); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// 配信を登録するだけで、送信はワーカーがWebhookDispatcherで非同期に行う。
+// 公開範囲を限定したスレッドの内容は外部に送らない
+func (s *WebhookService) Publish(ctx context.Context, event string, thread *model.Thread, data interface{}) error {
+	deliveries, err := webhookDeliveries(ctx, s.repository, event, thread, data)
+	if err != nil || len(deliveries) == 0 {
+		return err
+	}
+	if err := s.repository.CreateDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+
+	_, err = s.jobService.Enqueue(ctx, model.JobTypeDeliverWebhooks, nil)
+	return err
+}
+
+// Publishと同じ配信を、投稿と同じトランザクションで登録する
+func publishWebhook(ctx context.Context, repos repository.Repositories, event string, thread *model.Thread, data interface{}) error {
+	deliveries, err := webhookDeliveries(ctx, repos.Webhooks, event, thread, data)
+	if err != nil || len(deliveries) == 0 {
+		return err
+	}
+	if err := repos.Webhooks.CreateDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+
+	_, err = enqueueJob(ctx, repos.Jobs, model.JobTypeDeliverWebhooks, nil, time.Now())
+	return err
+}
+
+func webhookDeliveries(ctx context.Context, webhookRepository repository.IWebhookRepository, event string, thread *model.Thread, data interface{}) ([]model.WebhookDelivery, error) {
+	if thread.Visibility != model.VisibilityPublic {
+		return nil, nil
+	}
+
+	webhooks, err := webhookRepository.FindActiveByThreadId(ctx, thread.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	payload, err := json.Marshal(dto.WebhookPayload{Event: event, CreatedAt: now, Data: data})
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []model.WebhookDelivery
+	for _, webhook := range *webhooks {
+		if !webhook.Subscribes(event) {
+			continue
+		}
+		deliveries = append(deliveries, model.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        model.WebhookDeliveryStatusPending,
+			NextAttemptAt: &now,
+		})
+	}
+	return deliveries, nil
+}
+
+// 匿名の投稿は未ログインのユーザーと同じく投稿者を伏せて送る
+func webhookThread(thread *model.Thread) model.Thread {
+	copied := *thread
+	copied.Attachments = append([]model.Attachment(nil), thread.Attachments...)
+	copied.Comments = nil
+	AnonymizeThread(&copied, nil)
+	return copied
+}
+
+func webhookComment(comment *model.Comment) model.Comment {
+	copied := *comment
+	copied.Attachments = append([]model.Attachment(nil), comment.Attachments...)
+	AnonymizeComment(&copied, nil)
+	return copied
+}
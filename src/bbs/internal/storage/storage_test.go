@@ -0,0 +1,179 @@
+package storage_test
+
+import (
+	"bbs/internal/storage"
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestStorage(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Storage Suite")
+}
+
+var _ = Describe("Storage", func() {
+	storages := map[string]func() storage.Storage{
+		"LocalStorage": func() storage.Storage {
+			dir, err := os.MkdirTemp("", "bbs-storage")
+			Expect(err).To(BeNil())
+			DeferCleanup(os.RemoveAll, dir)
+
+			return storage.NewLocalStorage(dir)
+		},
+		"S3Storage": func() storage.Storage {
+			server := httptest.NewServer(newFakeS3())
+			DeferCleanup(server.Close)
+
+			st, err := storage.NewS3Storage(storage.S3Config{
+				Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+				Region:    "us-east-1",
+				Bucket:    "bbs",
+				AccessKey: "access",
+				SecretKey: "secret",
+			})
+			Expect(err).To(BeNil())
+
+			return st
+		},
+	}
+
+	for name, newStorage := range storages {
+		newStorage := newStorage
+
+		Describe(name, func() {
+			var st storage.Storage
+
+			BeforeEach(func() {
+				st = newStorage()
+			})
+
+			It("保存したファイルを取得できる", func() {
+				content := []byte("build succeeded")
+				Expect(st.Put(context.Background(), "attachments/1/log.txt", bytes.NewReader(content), int64(len(content)), "text/plain")).To(Succeed())
+
+				file, err := st.Get(context.Background(), "attachments/1/log.txt")
+				Expect(err).To(BeNil())
+				defer file.Close()
+
+				Expect(io.ReadAll(file)).To(Equal(content))
+			})
+
+			It("削除したファイルは取得できない", func() {
+				content := []byte("build succeeded")
+				Expect(st.Put(context.Background(), "attachments/1/log.txt", bytes.NewReader(content), int64(len(content)), "text/plain")).To(Succeed())
+				Expect(st.Delete(context.Background(), "attachments/1/log.txt")).To(Succeed())
+
+				_, err := st.Get(context.Background(), "attachments/1/log.txt")
+				Expect(err).To(MatchError(storage.ErrNotFound))
+			})
+
+			It("存在しないファイルの削除はエラーにならない", func() {
+				Expect(st.Delete(context.Background(), "attachments/1/missing.txt")).To(Succeed())
+			})
+
+			It("キャンセルされたcontextでは保存しない", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				content := []byte("build succeeded")
+				Expect(st.Put(ctx, "attachments/1/log.txt", bytes.NewReader(content), int64(len(content)), "text/plain")).To(MatchError(context.Canceled))
+
+				_, err := st.Get(context.Background(), "attachments/1/log.txt")
+				Expect(err).To(MatchError(storage.ErrNotFound))
+			})
+		})
+	}
+})
+
+// オブジェクトの保存・取得・削除のみを扱うS3互換サーバーの代用品
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string][]byte{}}
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := req.URL.Path
+	switch req.Method {
+	case http.MethodPut:
+		body, err := readS3Body(req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.objects[key] = body
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodHead, http.MethodGet:
+		body, ok := s.objects[key]
+		if !ok {
+			writeS3NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", "Mon, 01 Jan 2024 00:00:00 GMT")
+		if req.Method == http.MethodGet {
+			w.Write(body)
+		}
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func writeS3NotFound(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusNotFound)
+	if req.Method == http.MethodGet {
+		io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>not found</Message></Error>`)
+	}
+}
+
+// 暗号化なしの接続ではaws-chunked形式で送られてくるので、チャンクを結合して返す
+func readS3Body(req *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(req.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(req.Body)
+	}
+
+	var body bytes.Buffer
+	reader := bufio.NewReader(req.Body)
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.ParseInt(strings.SplitN(strings.TrimSpace(header), ";", 2)[0], 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return body.Bytes(), nil
+		}
+		if _, err := io.CopyN(&body, reader, size); err != nil {
+			return nil, err
+		}
+		if _, err := reader.Discard(2); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package totp_test
+
+import (
+	"bbs/internal/totp"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTOTP(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "TOTP Suite")
+}
+
+// RFC 6238 Appendix BのSHA-1の共有鍵"12345678901234567890"をBase32にしたもの
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+var _ = Describe("TOTP", func() {
+	Describe("Code", func() {
+		// RFC 6238 Appendix Bの8桁の値の下6桁
+		DescribeTable("RFC 6238のテストベクタと一致する",
+			func(unix int64, expected string) {
+				code, err := totp.Code(rfcSecret, totp.Step(time.Unix(unix, 0)))
+				Expect(err).To(BeNil())
+				Expect(code).To(Equal(expected))
+			},
+			Entry("59", int64(59), "287082"),
+			Entry("1111111109", int64(1111111109), "081804"),
+			Entry("1111111111", int64(1111111111), "050471"),
+			Entry("1234567890", int64(1234567890), "005924"),
+			Entry("2000000000", int64(2000000000), "279037"),
+			Entry("20000000000", int64(20000000000), "353130"),
+		)
+
+		It("小文字の共有鍵も受け付ける", func() {
+			code, err := totp.Code(strings.ToLower(rfcSecret), totp.Step(time.Unix(59, 0)))
+			Expect(err).To(BeNil())
+			Expect(code).To(Equal("287082"))
+		})
+
+		It("Base32として不正な共有鍵の場合はエラーを返す", func() {
+			_, err := totp.Code("not base32!", 1)
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	Describe("Validate", func() {
+		now := time.Unix(1111111111, 0)
+
+		DescribeTable("前後1ステップまでのコードを受け付ける",
+			func(offset int64, valid bool) {
+				step := totp.Step(now) + offset
+				code, err := totp.Code(rfcSecret, step)
+				Expect(err).To(BeNil())
+
+				matched, ok := totp.Validate(rfcSecret, code, now)
+				Expect(ok).To(Equal(valid))
+				if valid {
+					Expect(matched).To(Equal(step))
+				}
+			},
+			Entry("2ステップ前", int64(-2), false),
+			Entry("1ステップ前", int64(-1), true),
+			Entry("現在", int64(0), true),
+			Entry("1ステップ後", int64(1), true),
+			Entry("2ステップ後", int64(2), false),
+		)
+
+		It("前後の空白は無視する", func() {
+			_, ok := totp.Validate(rfcSecret, " 050471 ", now)
+			Expect(ok).To(BeTrue())
+		})
+
+		It("桁数が異なるコードは受け付けない", func() {
+			_, ok := totp.Validate(rfcSecret, "14050471", now)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("URI", func() {
+		It("桁数と周期を含める", func() {
+			uri := totp.URI("bbs", "user@example.com", rfcSecret)
+
+			Expect(uri).To(HavePrefix("otpauth://totp/bbs:user@example.com?"))
+			Expect(uri).To(ContainSubstring("digits=6"))
+			Expect(uri).To(ContainSubstring("period=30"))
+			Expect(uri).To(ContainSubstring("secret=" + rfcSecret))
+		})
+	})
+})
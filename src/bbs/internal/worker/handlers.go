@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"bbs/internal/service"
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 成功したジョブを残しておく期間
+const succeededJobRetention = 7 * 24 * time.Hour
+
+// ジョブの処理と定期実行を登録する。サービスはAPIサーバーと共有できるよう呼び出し元で作成する
+func SetUp(ctx context.Context, w *Worker, db *gorm.DB, threadService service.IThreadService, tokenService service.ITokenService) error {
+	webhookDispatcher := service.NewWebhookDispatcher(repository.NewWebhookRepository(db), &http.Client{Timeout: 10 * time.Second})
+	jobRepository := repository.NewJobRepository(db)
+
+	// 一定期間書き込みのないスレッドをアーカイブする
+	w.Handle(model.JobTypeArchiveThreads, func(ctx context.Context, job *model.Job) error {
+		archived, err := threadService.ArchiveInactive(ctx)
+		if err != nil {
+			return err
+		}
+		if archived > 0 {
+			log.Printf("archived %d inactive threads\n", archived)
+		}
+		return nil
+	})
+
+	// 失敗した配信の再送もここで行う
+	w.Handle(model.JobTypeDeliverWebhooks, func(ctx context.Context, job *model.Job) error {
+		_, err := webhookDispatcher.DeliverPending(ctx)
+		return err
+	})
+
+	w.Handle(model.JobTypePurgeJobs, func(ctx context.Context, job *model.Job) error {
+		_, err := jobRepository.DeleteSucceeded(ctx, time.Now().Add(-succeededJobRetention))
+		return err
+	})
+
+	// ログインがない間も署名鍵をローテーションし、期限が切れた鍵を削除する
+	w.Handle(model.JobTypeRotateSigningKeys, func(ctx context.Context, job *model.Job) error {
+		_, err := tokenService.RotateKeys(ctx)
+		return err
+	})
+
+	if err := w.Schedule(ctx, "archive-inactive-threads", "@hourly", model.JobTypeArchiveThreads); err != nil {
+		return err
+	}
+	if err := w.Schedule(ctx, "retry-webhook-deliveries", "@every 30s", model.JobTypeDeliverWebhooks); err != nil {
+		return err
+	}
+	if err := w.Schedule(ctx, "purge-jobs", "0 4 * * *", model.JobTypePurgeJobs); err != nil {
+		return err
+	}
+	return w.Schedule(ctx, "rotate-signing-keys", "@hourly", model.JobTypeRotateSigningKeys)
+}
+
+// WORKER_CONCURRENCYが未設定または0以下の場合は2
+func Concurrency() int {
+	concurrency, err := strconv.Atoi(os.Getenv("WORKER_CONCURRENCY"))
+	if err != nil || concurrency <= 0 {
+		return defaultConcurrency
+	}
+	return concurrency
+}
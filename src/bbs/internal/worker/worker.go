@@ -0,0 +1,233 @@
+// DBのジョブを取得して実行するワーカー。ジョブは少なくとも1回実行され、失敗した場合は間隔を空けて再実行する
+package worker
+
+import (
+	"bbs/internal/cron"
+	"bbs/internal/model"
+	"bbs/internal/repository"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConcurrency  = 2
+	defaultPollInterval = time.Second
+	// ジョブ1件の実行時間の上限
+	jobTimeout = 5 * time.Minute
+	// この時間を過ぎても実行中のジョブはワーカーが停止したとみなして再実行する
+	staleJobTimeout = 2 * jobTimeout
+	retryBaseDelay  = 10 * time.Second
+)
+
+// ジョブを処理する関数。エラーを返すと再実行する
+type Handler func(ctx context.Context, job *model.Job) error
+
+type schedule struct {
+	name     string
+	schedule cron.Schedule
+	jobType  string
+}
+
+type Worker struct {
+	repository   repository.IJobRepository
+	id           string
+	concurrency  int
+	pollInterval time.Duration
+	mu           sync.Mutex
+	handlers     map[string]Handler
+	schedules    []schedule
+}
+
+// concurrencyが0以下の場合はWORKER_CONCURRENCY、未設定の場合は2
+func New(repository repository.IJobRepository, concurrency int) *Worker {
+	if concurrency <= 0 {
+		concurrency = Concurrency()
+	}
+	return &Worker{
+		repository:   repository,
+		id:           workerId(),
+		concurrency:  concurrency,
+		pollInterval: defaultPollInterval,
+		handlers:     map[string]Handler{},
+	}
+}
+
+func (w *Worker) Handle(jobType string, handler Handler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers[jobType] = handler
+}
+
+// specはcron形式(例: "0 4 * * *"、"@hourly"、"@every 30s")。予定時刻になるとjobTypeのジョブを登録する。
+// 初めて登録する定期実行の場合は次回の予定をここで作成する
+func (w *Worker) Schedule(ctx context.Context, name string, spec string, jobType string) error {
+	parsed, err := cron.Parse(spec)
+	if err != nil {
+		return fmt.Errorf("schedule %s: %w", name, err)
+	}
+	if err := w.repository.EnsureSchedule(ctx, name, parsed.Next(time.Now())); err != nil {
+		return fmt.Errorf("schedule %s: %w", name, err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.schedules = append(w.schedules, schedule{name: name, schedule: parsed, jobType: jobType})
+	return nil
+}
+
+// ctxが終了するまでジョブを実行する。実行中のジョブが終わるのを待ってから戻る
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.poll(ctx, func() (bool, error) { return w.RunOnce(ctx) })
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.poll(ctx, func() (bool, error) { return false, w.Tick(ctx, time.Now()) })
+	}()
+
+	wg.Wait()
+}
+
+// fnが処理するものがなかった場合だけ待ってから繰り返す
+func (w *Worker) poll(ctx context.Context, fn func() (bool, error)) {
+	for {
+		processed, err := fn()
+		if err != nil {
+			log.Println("worker:", err)
+		}
+		if processed && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.pollInterval):
+		}
+	}
+}
+
+// ジョブを1件実行する。実行するジョブがなかった場合はfalseを返す
+func (w *Worker) RunOnce(ctx context.Context) (bool, error) {
+	job, err := w.repository.Claim(ctx, w.jobTypes(), w.id, time.Now())
+	if err != nil {
+		return false, err
+	}
+	if job == nil {
+		return false, nil
+	}
+
+	// 停止中でも実行を終えたジョブの結果は記録する
+	recordCtx := context.WithoutCancel(ctx)
+	if err := w.execute(ctx, job); err != nil {
+		return true, w.fail(recordCtx, job, err)
+	}
+	return true, w.repository.Complete(recordCtx, *job, time.Now())
+}
+
+// 予定時刻を過ぎた定期実行のジョブを登録し、実行中のまま残ったジョブを再実行できるようにする
+func (w *Worker) Tick(ctx context.Context, now time.Time) error {
+	w.mu.Lock()
+	schedules := append([]schedule(nil), w.schedules...)
+	w.mu.Unlock()
+
+	for _, s := range schedules {
+		claimed, err := w.repository.ClaimSchedule(ctx, s.name, now, s.schedule.Next(now))
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+		// 失敗しても次の予定で実行されるため再実行はしない
+		if _, err := w.repository.Enqueue(ctx, model.Job{
+			Type:        s.jobType,
+			Payload:     "null",
+			Status:      model.JobStatusPending,
+			RunAt:       now,
+			MaxAttempts: 1,
+		}); err != nil {
+			return err
+		}
+	}
+
+	requeued, err := w.repository.RequeueStale(ctx, now.Add(-staleJobTimeout))
+	if err != nil {
+		return err
+	}
+	if requeued > 0 {
+		log.Printf("worker: requeued %d stale jobs\n", requeued)
+	}
+	return nil
+}
+
+func (w *Worker) execute(ctx context.Context, job *model.Job) (err error) {
+	w.mu.Lock()
+	handler := w.handlers[job.Type]
+	w.mu.Unlock()
+
+	if handler == nil {
+		return fmt.Errorf("no handler for job type %s", job.Type)
+	}
+
+	// ハンドラーのpanicでワーカーが止まらないようにする
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+	return handler(ctx, job)
+}
+
+// 上限まで失敗した場合は再実行せず、管理者が確認できるようにする
+func (w *Worker) fail(ctx context.Context, job *model.Job, jobErr error) error {
+	now := time.Now()
+	if job.Attempts >= job.MaxAttempts {
+		log.Printf("worker: job %d (%s) is dead: %v\n", job.ID, job.Type, jobErr)
+		return w.repository.Kill(ctx, *job, jobErr.Error(), now)
+	}
+	return w.repository.Retry(ctx, *job, jobErr.Error(), now.Add(retryDelay(job.Attempts)))
+}
+
+func (w *Worker) jobTypes() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	types := make([]string, 0, len(w.handlers))
+	for jobType := range w.handlers {
+		types = append(types, jobType)
+	}
+	return types
+}
+
+// 1回目の失敗で10秒後、以降は倍々に間隔を空ける
+func retryDelay(attempts int) time.Duration {
+	return retryBaseDelay << (attempts - 1)
+}
+
+// ログやロックの持ち主として記録するワーカーの識別子
+func workerId() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "worker"
+	}
+	random := make([]byte, 4)
+	rand.Read(random)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(random))
+}